@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+	kryptonsqlxprometheus "github.com/olireadcopper/sqlxprototype/pkg/sqlx/instrumenting/prometheus"
+)
+
+// admin serves the endpoints used by the platform rather than by clients,
+// metrics, liveness and readiness
+type admin struct {
+	db            kryptonsqlx.DB
+	readyTimeout  time.Duration
+	maxSaturation float64
+	logger        *slog.Logger
+	shuttingDown  atomic.Bool
+}
+
+type readiness struct {
+	Status     string  `json:"status"`
+	Saturation float64 `json:"saturation"`
+}
+
+func newAdmin(db kryptonsqlx.DB, readyTimeout time.Duration, maxSaturation float64, logger *slog.Logger) *admin {
+	return &admin{
+		db:            db,
+		readyTimeout:  readyTimeout,
+		maxSaturation: maxSaturation,
+		logger:        logger,
+	}
+}
+
+func (a *admin) handler(g prometheus.Gatherer) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.Handle("GET /metrics", kryptonsqlxprometheus.Handler(g))
+	mux.HandleFunc("GET /healthz", a.healthz)
+	mux.HandleFunc("GET /readyz", a.readyz)
+
+	return mux
+}
+
+// shutdown marks the service as not ready, so load balancers stop sending
+// it requests while the in-flight ones drain
+func (a *admin) shutdown() {
+	a.shuttingDown.Store(true)
+}
+
+func (a *admin) healthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a *admin) readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), a.readyTimeout)
+	defer cancel()
+
+	res := readiness{
+		Status:     "ok",
+		Saturation: kryptonsqlxprometheus.Saturation(a.db.Stats()),
+	}
+
+	switch err := a.db.PingContext(ctx); {
+	case a.shuttingDown.Load():
+		res.Status = "shutting_down"
+	case err != nil:
+		// the error may name the driver or the DSN, it is logged rather than
+		// returned to the unauthenticated caller
+		a.logger.ErrorContext(r.Context(), err.Error(), "check", "readiness")
+		res.Status = "unavailable"
+	case res.Saturation >= a.maxSaturation:
+		res.Status = "saturated"
+	}
+
+	status := http.StatusOK
+	if res.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/nop"
+)
+
+// pingDB a database whose pings fail with err and whose pool is stats
+type pingDB struct {
+	*nop.Nop
+	err   error
+	stats sql.DBStats
+}
+
+func (db *pingDB) PingContext(context.Context) error {
+	return db.err
+}
+
+func (db *pingDB) Stats() sql.DBStats {
+	return db.stats
+}
+
+func TestAdmin(t *testing.T) {
+	secret := errors.New(`dial tcp: lookup db.internal: password "hunter2" rejected`)
+
+	tests := []struct {
+		name     string
+		db       *pingDB
+		shutdown bool
+		path     string
+		code     int
+		status   string
+	}{
+		{"healthy", &pingDB{Nop: nop.NewDB()}, false, "/healthz", http.StatusOK, ""},
+		{"healthy while shutting down", &pingDB{Nop: nop.NewDB()}, true, "/healthz", http.StatusOK, ""},
+		{"ready", &pingDB{Nop: nop.NewDB()}, false, "/readyz", http.StatusOK, "ok"},
+		{"unavailable", &pingDB{Nop: nop.NewDB(), err: secret}, false, "/readyz", http.StatusServiceUnavailable, "unavailable"},
+		{"saturated", &pingDB{Nop: nop.NewDB(), stats: sql.DBStats{MaxOpenConnections: 4, InUse: 4}}, false, "/readyz", http.StatusServiceUnavailable, "saturated"},
+		{"shutting down", &pingDB{Nop: nop.NewDB()}, true, "/readyz", http.StatusServiceUnavailable, "shutting_down"},
+	}
+
+	for _, tt := range tests {
+		logs := &bytes.Buffer{}
+
+		a := newAdmin(tt.db, time.Second, 0.9, slog.New(slog.NewTextHandler(logs, nil)))
+		if tt.shutdown {
+			a.shutdown()
+		}
+
+		rec := httptest.NewRecorder()
+		a.handler(prometheus.NewRegistry()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if rec.Code != tt.code {
+			t.Errorf("%s: code = %d, want %d", tt.name, rec.Code, tt.code)
+		}
+
+		if tt.status == "" {
+			continue
+		}
+
+		body := rec.Body.String()
+
+		res := readiness{}
+		if err := json.Unmarshal([]byte(body), &res); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		if res.Status != tt.status {
+			t.Errorf("%s: status = %q, want %q", tt.name, res.Status, tt.status)
+		}
+
+		if strings.Contains(body, "hunter2") {
+			t.Errorf("%s: body %s leaks the ping error", tt.name, body)
+		}
+
+		if tt.db.err != nil && !strings.Contains(logs.String(), "hunter2") {
+			t.Errorf("%s: the ping error was not logged", tt.name)
+		}
+	}
+}
+
+func TestAdminMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "admin_test_total"}))
+
+	rec := httptest.NewRecorder()
+	newAdmin(&pingDB{Nop: nop.NewDB()}, time.Second, 0.9, slog.Default()).
+		handler(registry).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "admin_test_total") {
+		t.Errorf("metrics = %d %s, want 200 with admin_test_total", rec.Code, rec.Body)
+	}
+}
@@ -2,31 +2,126 @@ package main
 
 import (
 	"context"
+	stdsql "database/sql"
+	"database/sql/driver"
+	"errors"
 	"flag"
+	"fmt"
+	"io/fs"
 	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
 
-	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/httpapi"
 	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy/cache"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy/feed"
+	taxonomyprometheus "github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy/instrumenting/prometheus"
+	taxonomylogging "github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy/logging"
 	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy/sql"
 	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/dialect"
 	kryptonsqlxprometheus "github.com/olireadcopper/sqlxprototype/pkg/sqlx/instrumenting/prometheus"
 	krtpronsqlxlogging "github.com/olireadcopper/sqlxprototype/pkg/sqlx/logging"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/migrate"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/outbox"
 )
 
 func main() {
 	logging := flag.Bool("logging", false, "enable logging in the application")
 	instrumenting := flag.Bool("instrumenting", false, "enable instrumenting in the application")
+	repositoryLogging := flag.Bool("repository-logging", false, "enable logging of the operations of the taxonomy repository")
+	repositoryInstrumenting := flag.Bool("repository-instrumenting", false, "enable instrumenting of the operations of the taxonomy repository")
+	logLevel := flag.String("log-level", "info", "minimum level of the sqlx logging decorator")
+	driverName := flag.String("driver", "sqlite3", "database driver, sqlite3 or postgres")
+	dsn := flag.String("dsn", "file:kryptonsdk?_foreign_keys=on", "data source name of the database")
+	addr := flag.String("addr", ":8080", "address of the API listener")
+	adminAddr := flag.String("admin-addr", ":9090", "address of the admin listener serving metrics and health checks")
+	readyTimeout := flag.Duration("ready-timeout", 2*time.Second, "timeout of the database ping made by the readiness check")
+	maxSaturation := flag.Float64("max-saturation", 0.9, "pool saturation above which the service reports it is not ready")
+	cursorKey := flag.String("cursor-key", os.Getenv("CURSOR_KEY"), "key signing pagination cursors, random when empty so cursors do not survive a restart")
+	cacheSize := flag.Int("cache-size", 0, "most taxonomy reads cached, the cache is disabled when 0")
+	cacheTTL := flag.Duration("cache-ttl", time.Minute, "time taxonomy reads are cached")
+	outboxFile := flag.String("outbox-file", "", "file the outbox relay appends taxonomy change messages to as JSON lines, the outbox is disabled when empty")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "time allowed for in-flight requests to drain on shutdown")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [migrate [outbox] up|down|status|to N | purge DURATION]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
-	pool, err := sqlx.Connect("sqllite3", "kryptonsdk")
+	level := new(slog.LevelVar)
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+
+	d, err := dialect.ForDriver(*driverName)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+
+	// the outbox runs on either driver, but only the SQLite repository keeps
+	// the change log the messages are enqueued from
+	if *outboxFile != "" && d.DriverName() != "sqlite3" {
+		slog.Error("the outbox is only written by the taxonomy repository of the sqlite3 driver")
+		os.Exit(1)
+	}
+
+	pool, err := connect(d.DriverName(), *dsn, *instrumenting)
 	if err != nil {
 		slog.Error(err.Error())
 		os.Exit(1)
 	}
 
+	// without FTS5 the search index can not be created, the service runs
+	// without search rather than failing to migrate
+	searchable := d.DriverName() == "sqlite3"
+	migrateOptions := []migrate.MigratorOption{}
+
+	if searchable {
+		searchable, err = sql.SQLiteSupportsSearch(context.Background(), pool)
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+
+		if !searchable {
+			slog.Warn("SQLite was built without FTS5, taxonomy search is disabled, build with -tags sqlite_fts5 to enable it")
+
+			migrateOptions = append(migrateOptions, migrate.MigratorWithSkip(sql.SQLiteSearchMigration))
+		}
+	}
+
+	if flag.Arg(0) == "migrate" {
+		fsys, args := migrations(d), flag.Args()[1:]
+
+		// the outbox records its migrations apart from those of the taxonomy
+		if len(args) > 0 && args[0] == "outbox" {
+			fsys, args = outboxMigrations(d), args[1:]
+			migrateOptions = []migrate.MigratorOption{migrate.MigratorWithTable(outbox.MigrationsTable)}
+		}
+
+		err := runMigrate(context.Background(), pool, fsys, os.Stdout, args, migrateOptions...)
+		pool.Close()
+
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	var repositoryDB kryptonsqlx.DB
 
 	repositoryDB = pool
@@ -35,23 +130,267 @@ func main() {
 		repositoryDB = krtpronsqlxlogging.NewDB(
 			krtpronsqlxlogging.DBWithLogger(slog.Default()),
 			krtpronsqlxlogging.DBWithInnerDB(repositoryDB),
+			krtpronsqlxlogging.DBWithLevel(level),
 		)
 	}
 
 	if *instrumenting {
 		repositoryDB = kryptonsqlxprometheus.NewDB(
 			kryptonsqlxprometheus.DBWithInnerDB(repositoryDB),
+			kryptonsqlxprometheus.DBWithRegisterer(prometheus.DefaultRegisterer),
+			kryptonsqlxprometheus.DBWithConstLabels(prometheus.Labels{
+				"db": "kryptonsdk",
+			}),
 		)
+
+		prometheus.MustRegister(kryptonsqlxprometheus.NewPoolCollector(
+			kryptonsqlxprometheus.PoolCollectorWithDB(pool),
+			kryptonsqlxprometheus.PoolCollectorWithName("kryptonsdk"),
+		))
+	}
+
+	handlerOptions := []httpapi.HandlerOption{
+		httpapi.HandlerWithLogger(slog.Default()),
 	}
 
-	taxonomyRepository := sql.NewSQLiteRepository(
-		sql.SQLiteWithDB(repositoryDB),
+	var (
+		taxonomyRepository   taxonomy.Repository
+		softDeleteRepository taxonomy.SoftDeleteRepository
+		relay                *outbox.Relay
+		cached               *cache.Repository
 	)
 
-	taxonomyRepository.Read(context.Background(), taxonomy.Query{
-		Taxonomy: model.Taxonomy{
-			ID:   "id",
-			Name: "taxonomy",
+	// the writes of the repositories bypassing the cache, moves and restores
+	// for example, invalidate it
+	invalidate := func(ids ...string) {
+		if cached != nil {
+			cached.Invalidate(ids...)
+		}
+	}
+
+	switch d.DriverName() {
+	case "postgres":
+		postgresRepository := sql.NewPostgresRepository(
+			sql.PostgresWithDB(repositoryDB),
+			sql.PostgresWithCursorKey([]byte(*cursorKey)),
+			sql.PostgresWithInvalidator(invalidate),
+		)
+
+		taxonomyRepository = postgresRepository
+		softDeleteRepository = postgresRepository
+
+		handlerOptions = append(handlerOptions,
+			httpapi.HandlerWithTaxonomyHierarchyRepository(postgresRepository),
+			httpapi.HandlerWithTaxonomySoftDeleteRepository(postgresRepository),
+		)
+	default:
+		repositoryOptions := []sql.SQLiteRepositoryOption{
+			sql.SQLiteWithDB(repositoryDB),
+			sql.SQLiteWithCursorKey([]byte(*cursorKey)),
+			sql.SQLiteWithInvalidator(invalidate),
+		}
+
+		if *outboxFile != "" {
+			publisher, err := outbox.NewFile(*outboxFile)
+			if err != nil {
+				slog.Error(err.Error())
+				os.Exit(1)
+			}
+			defer publisher.Close()
+
+			taxonomyOutbox := outbox.NewOutbox(
+				outbox.OutboxWithDB(repositoryDB),
+			)
+
+			relay = outbox.NewRelay(
+				outbox.RelayWithOutbox(taxonomyOutbox),
+				outbox.RelayWithPublisher(publisher),
+				outbox.RelayWithLogger(slog.Default()),
+			)
+
+			repositoryOptions = append(repositoryOptions, sql.SQLiteWithOutbox(taxonomyOutbox))
+
+			if *instrumenting {
+				prometheus.MustRegister(kryptonsqlxprometheus.NewOutboxCollector(
+					kryptonsqlxprometheus.OutboxCollectorWithRelay(relay),
+					kryptonsqlxprometheus.OutboxCollectorWithName("taxonomy"),
+				))
+			}
+		}
+
+		sqliteRepository := sql.NewSQLiteRepository(repositoryOptions...)
+
+		taxonomyRepository = sqliteRepository
+		softDeleteRepository = sqliteRepository
+
+		taxonomyFeed := feed.NewFeed(
+			feed.FeedWithRepository(sqliteRepository),
+			feed.FeedWithLogger(slog.Default()),
+		)
+
+		handlerOptions = append(handlerOptions,
+			httpapi.HandlerWithTaxonomyHierarchyRepository(sqliteRepository),
+			httpapi.HandlerWithTaxonomySoftDeleteRepository(sqliteRepository),
+			httpapi.HandlerWithTaxonomyHistoryRepository(sqliteRepository),
+			httpapi.HandlerWithTaxonomyFeed(taxonomyFeed),
+		)
+
+		if searchable {
+			handlerOptions = append(handlerOptions, httpapi.HandlerWithTaxonomySearchRepository(sqliteRepository))
+		}
+	}
+
+	if *cacheSize > 0 {
+		cached = cache.NewRepository(
+			cache.RepositoryWithInnerRepository(taxonomyRepository),
+			cache.RepositoryWithSize(*cacheSize),
+			cache.RepositoryWithTTL(*cacheTTL),
+		)
+
+		taxonomyRepository = cached
+	}
+
+	if *repositoryLogging {
+		taxonomyRepository = taxonomylogging.NewRepository(
+			taxonomylogging.RepositoryWithLogger(slog.Default()),
+			taxonomylogging.RepositoryWithInnerRepository(taxonomyRepository),
+		)
+	}
+
+	if *repositoryInstrumenting {
+		taxonomyRepository = taxonomyprometheus.NewRepository(
+			taxonomyprometheus.RepositoryWithInnerRepository(taxonomyRepository),
+			taxonomyprometheus.RepositoryWithRegisterer(prometheus.DefaultRegisterer),
+		)
+	}
+
+	handlerOptions = append(handlerOptions, httpapi.HandlerWithTaxonomyRepository(taxonomyRepository))
+
+	if flag.Arg(0) == "purge" {
+		err := runPurge(context.Background(), softDeleteRepository, os.Stdout, flag.Args()[1:])
+		pool.Close()
+
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	api := httpapi.NewHandler(handlerOptions...)
+
+	admin := newAdmin(repositoryDB, *readyTimeout, *maxSaturation, slog.Default())
+
+	servers := []*http.Server{
+		{
+			Addr:              *addr,
+			Handler:           api,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		{
+			Addr:              *adminAddr,
+			Handler:           admin.handler(prometheus.DefaultGatherer),
+			ReadHeaderTimeout: 5 * time.Second,
 		},
-	})
+	}
+
+	servers[0].RegisterOnShutdown(api.Shutdown)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	errs := make(chan error, len(servers))
+
+	// the relay stops once the servers are drained, so the messages of the
+	// last requests are still relayed
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	relayDone := make(chan struct{})
+
+	go func() {
+		defer close(relayDone)
+
+		if relay != nil {
+			relay.Run(relayCtx)
+		}
+	}()
+
+	for _, server := range servers {
+		go func(server *http.Server) {
+			slog.Info("listening", "addr", server.Addr)
+
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errs <- err
+			}
+		}(server)
+	}
+
+	select {
+	case <-ctx.Done():
+		slog.Info("shutting down")
+	case err := <-errs:
+		slog.Error(err.Error())
+	}
+
+	admin.shutdown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+
+	for _, server := range servers {
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error(err.Error(), "addr", server.Addr)
+		}
+	}
+
+	stopRelay()
+	<-relayDone
+
+	// closing the pool waits for the queries still running to finish
+	if err := pool.Close(); err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+// connect opens a pool to the database, wrapping the driver so the rows of
+// queries can be observed when instrumenting is enabled
+func connect(driverName, dsn string, instrumenting bool) (*sqlx.DB, error) {
+	if !instrumenting {
+		return sqlx.Connect(driverName, dsn)
+	}
+
+	drivers := map[string]driver.Driver{
+		"sqlite3":  &sqlite3.SQLiteDriver{},
+		"postgres": &pq.Driver{},
+	}
+
+	pool := sqlx.NewDb(
+		stdsql.OpenDB(kryptonsqlxprometheus.NewConnector(drivers[driverName], dsn)),
+		driverName,
+	)
+
+	if err := pool.Ping(); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+// migrations the schema migrations of the taxonomy repository of the dialect
+func migrations(d dialect.Dialect) fs.FS {
+	if d.DriverName() == "postgres" {
+		return sql.PostgresMigrations()
+	}
+
+	return sql.SQLiteMigrations()
+}
+
+// outboxMigrations the schema migrations of the outbox of the dialect
+func outboxMigrations(d dialect.Dialect) fs.FS {
+	if d.DriverName() == "postgres" {
+		return outbox.PostgresMigrations()
+	}
+
+	return outbox.SQLiteMigrations()
 }
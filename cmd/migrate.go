@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/migrate"
+)
+
+const migrateUsage = "usage: migrate [outbox] up|down|status|to N"
+
+// runMigrate runs the migrate subcommand with the migrations of fsys, args
+// are the arguments following migrate on the command line
+func runMigrate(ctx context.Context, db kryptonsqlx.DB, fsys fs.FS, out io.Writer, args []string, opts ...migrate.MigratorOption) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	m := migrate.NewMigrator(append([]migrate.MigratorOption{
+		migrate.MigratorWithDB(db),
+		migrate.MigratorWithFS(fsys),
+	}, opts...)...)
+
+	switch args[0] {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		return m.Down(ctx)
+	case "to":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+
+		version, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q: %w", args[1], err)
+		}
+
+		return m.To(ctx, version)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		return writeStatus(out, statuses)
+	}
+
+	return errors.New(migrateUsage)
+}
+
+func writeStatus(out io.Writer, statuses []migrate.Status) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT\tSTATE")
+
+	for _, s := range statuses {
+		appliedAt := "-"
+		if s.Applied {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+
+		state := "pending"
+		switch {
+		case s.Missing:
+			state = "missing"
+		case s.Modified:
+			state = "modified"
+		case s.Applied:
+			state = "applied"
+		case s.Skipped:
+			state = "skipped"
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, appliedAt, state)
+	}
+
+	return w.Flush()
+}
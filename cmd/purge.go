@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+)
+
+const purgeUsage = "usage: purge DURATION"
+
+// runPurge runs the purge subcommand, removing the taxonomies deleted longer
+// than the duration following purge on the command line ago
+func runPurge(ctx context.Context, r taxonomy.SoftDeleteRepository, out io.Writer, args []string) error {
+	if len(args) != 1 {
+		return errors.New(purgeUsage)
+	}
+
+	olderThan, err := time.ParseDuration(args[0])
+	if err != nil || olderThan < 0 {
+		return fmt.Errorf("invalid duration %q: %s", args[0], purgeUsage)
+	}
+
+	purged, err := r.Purge(ctx, olderThan)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(out, "purged %d taxonomies\n", purged)
+
+	return err
+}
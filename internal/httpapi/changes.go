@@ -0,0 +1,83 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// heartbeatInterval how often an idle stream of changes writes a comment, so
+// proxies do not close it
+const heartbeatInterval = 15 * time.Second
+
+// streamChanges streams the changes of the taxonomies as server-sent events,
+// following the after query parameter, or the Last-Event-ID header when a
+// client reconnects, each event is identified by the sequence number of its
+// change
+func (h *Handler) streamChanges(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query().Get("after")
+	field := "after"
+
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		v, field = id, "Last-Event-ID"
+	}
+
+	after := uint64(0)
+
+	if v != "" {
+		var err error
+
+		if after, err = strconv.ParseUint(v, 10, 64); err != nil {
+			h.writeProblem(w, r, http.StatusBadRequest, "invalid query parameters", FieldError{
+				Field:  field,
+				Detail: "must be a non-negative integer",
+			})
+			return
+		}
+	}
+
+	ctx := r.Context()
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if err := rc.Flush(); err != nil {
+		h.logger.ErrorContext(ctx, err.Error())
+		return
+	}
+
+	events := h.feed.Subscribe(ctx, after)
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(e)
+			if err != nil {
+				h.logger.ErrorContext(ctx, err.Error())
+				return
+			}
+
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Operation, data)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case <-h.done:
+			return
+		}
+
+		// the client is gone when the stream can not be flushed
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
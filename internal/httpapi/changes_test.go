@@ -0,0 +1,124 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy/feed"
+)
+
+// stubFeedRepository a log of the changes of three taxonomies
+type stubFeedRepository struct {
+	taxonomy.FeedRepository
+}
+
+func (stubFeedRepository) Changes(_ context.Context, after uint64, limit uint) ([]taxonomy.Event, error) {
+	events := []taxonomy.Event{}
+
+	for seq := after + 1; seq <= 3 && uint(len(events)) < limit; seq++ {
+		events = append(events, taxonomy.Event{Seq: seq, Operation: taxonomy.OperationCreate})
+	}
+
+	return events, nil
+}
+
+func TestStreamChanges(t *testing.T) {
+	h := NewHandler(HandlerWithTaxonomyFeed(feed.NewFeed(
+		feed.FeedWithRepository(stubFeedRepository{}),
+		feed.FeedWithPollInterval(time.Millisecond),
+	)))
+
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer h.Shutdown()
+
+	tests := []struct {
+		name        string
+		query       string
+		lastEventID string
+		want        []string
+	}{
+		{"every change", "", "", []string{"1", "2", "3"}},
+		{"after", "?after=1", "", []string{"2", "3"}},
+		// a reconnecting client resumes after the last event it received
+		{"reconnect", "?after=1", "2", []string{"3"}},
+	}
+
+	for _, tt := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/taxonomies/changes"+tt.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tt.lastEventID != "" {
+			req.Header.Set("Last-Event-ID", tt.lastEventID)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Errorf("%s: Content-Type = %q, want text/event-stream", tt.name, ct)
+		}
+
+		ids := []string{}
+		scanner := bufio.NewScanner(res.Body)
+
+		for len(ids) < len(tt.want) && scanner.Scan() {
+			line := scanner.Text()
+
+			if id, ok := strings.CutPrefix(line, "id: "); ok {
+				ids = append(ids, id)
+			}
+
+			if event, ok := strings.CutPrefix(line, "event: "); ok && event != taxonomy.OperationCreate {
+				t.Errorf("%s: event = %q, want %q", tt.name, event, taxonomy.OperationCreate)
+			}
+		}
+
+		cancel()
+		res.Body.Close()
+
+		if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: ids = %v, want %v", tt.name, ids, tt.want)
+		}
+	}
+}
+
+func TestStreamChangesInvalid(t *testing.T) {
+	h := NewHandler(HandlerWithTaxonomyFeed(feed.NewFeed(
+		feed.FeedWithRepository(stubFeedRepository{}),
+	)))
+
+	for _, header := range []string{"", "Last-Event-ID"} {
+		req := httptest.NewRequest(http.MethodGet, "/taxonomies/changes?after=-1", nil)
+		field := "after"
+
+		if header != "" {
+			req = httptest.NewRequest(http.MethodGet, "/taxonomies/changes", nil)
+			req.Header.Set(header, "x")
+			field = header
+		}
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: code = %d, want %d", field, rec.Code, http.StatusBadRequest)
+		}
+
+		if p := decodeProblem(t, rec); len(p.Errors) != 1 || p.Errors[0].Field != field {
+			t.Errorf("errors = %v, want one of %s", p.Errors, field)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Package httpapi exposes the repositories over HTTP as a JSON API, errors
+// are reported as RFC 9457 problem details
+package httpapi
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy/feed"
+	"github.com/olireadcopper/sqlxprototype/pkg/telemetry/logging"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+	maxBodyBytes = 1 << 20
+)
+
+type Handler struct {
+	taxonomies taxonomy.Repository
+	hierarchy  taxonomy.HierarchyRepository
+	search     taxonomy.SearchRepository
+	softDelete taxonomy.SoftDeleteRepository
+	history    taxonomy.HistoryRepository
+	feed       *feed.Feed
+	logger     *slog.Logger
+	mux        *http.ServeMux
+	// done is closed on shutdown to end the streams of changes
+	done      chan struct{}
+	closeDone sync.Once
+}
+
+type HandlerOption func(*Handler)
+
+// NewHandler constructor for the HTTP API, the repositories the endpoints are
+// served from must be provided as options
+func NewHandler(opts ...HandlerOption) *Handler {
+	h := &Handler{
+		logger: logging.NopLogger,
+		mux:    http.NewServeMux(),
+		done:   make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.mux.HandleFunc("POST /taxonomies", h.createTaxonomy)
+	h.mux.HandleFunc("GET /taxonomies", h.listTaxonomies)
+	h.mux.HandleFunc("GET /taxonomies/{id}", h.readTaxonomy)
+	h.mux.HandleFunc("PUT /taxonomies/{id}", h.replaceTaxonomy)
+	h.mux.HandleFunc("PATCH /taxonomies/{id}", h.patchTaxonomy)
+	h.mux.HandleFunc("DELETE /taxonomies/{id}", h.deleteTaxonomy)
+
+	if h.hierarchy != nil {
+		h.mux.HandleFunc("GET /taxonomies/tree", h.readForest)
+		h.mux.HandleFunc("GET /taxonomies/{id}/tree", h.readSubtree)
+		h.mux.HandleFunc("GET /taxonomies/{id}/children", h.listChildren)
+		h.mux.HandleFunc("GET /taxonomies/{id}/ancestors", h.listAncestors)
+		h.mux.HandleFunc("PUT /taxonomies/{id}/parent", h.moveTaxonomy)
+	}
+
+	if h.search != nil {
+		h.mux.HandleFunc("GET /taxonomies/search", h.searchTaxonomies)
+	}
+
+	if h.softDelete != nil {
+		h.mux.HandleFunc("POST /taxonomies/{id}/restore", h.restoreTaxonomy)
+	}
+
+	if h.history != nil {
+		h.mux.HandleFunc("GET /taxonomies/{id}/history", h.readHistory)
+	}
+
+	if h.feed != nil {
+		h.mux.HandleFunc("GET /taxonomies/changes", h.streamChanges)
+	}
+
+	return h
+}
+
+// ServeHTTP implementation of http.Handler, each request is given a trace ID
+// carried by its context
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	withTrace(h.mux).ServeHTTP(w, r)
+}
+
+// Shutdown ends the streams of changes, which would otherwise keep the server
+// from draining, it is meant to be registered with
+// http.Server.RegisterOnShutdown
+func (h *Handler) Shutdown() {
+	h.closeDone.Do(func() {
+		close(h.done)
+	})
+}
+
+func HandlerWithTaxonomyRepository(r taxonomy.Repository) HandlerOption {
+	return func(h *Handler) {
+		h.taxonomies = r
+	}
+}
+
+// HandlerWithTaxonomyHierarchyRepository enables the endpoints navigating and
+// restructuring the taxonomy tree
+func HandlerWithTaxonomyHierarchyRepository(r taxonomy.HierarchyRepository) HandlerOption {
+	return func(h *Handler) {
+		h.hierarchy = r
+	}
+}
+
+// HandlerWithTaxonomySearchRepository enables the endpoint searching
+// taxonomies by name
+func HandlerWithTaxonomySearchRepository(r taxonomy.SearchRepository) HandlerOption {
+	return func(h *Handler) {
+		h.search = r
+	}
+}
+
+// HandlerWithTaxonomySoftDeleteRepository enables the endpoint restoring
+// deleted taxonomies
+func HandlerWithTaxonomySoftDeleteRepository(r taxonomy.SoftDeleteRepository) HandlerOption {
+	return func(h *Handler) {
+		h.softDelete = r
+	}
+}
+
+// HandlerWithTaxonomyHistoryRepository enables the endpoint reading the
+// revisions of a taxonomy
+func HandlerWithTaxonomyHistoryRepository(r taxonomy.HistoryRepository) HandlerOption {
+	return func(h *Handler) {
+		h.history = r
+	}
+}
+
+// HandlerWithTaxonomyFeed enables the endpoint streaming the changes of the
+// taxonomies as server-sent events
+func HandlerWithTaxonomyFeed(f *feed.Feed) HandlerOption {
+	return func(h *Handler) {
+		h.feed = f
+	}
+}
+
+func HandlerWithLogger(l *slog.Logger) HandlerOption {
+	return func(h *Handler) {
+		h.logger = l.With(
+			logging.FieldComponent, "httpapi",
+		)
+	}
+}
@@ -0,0 +1,150 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+)
+
+// moveRequest the body of a request moving a taxonomy, a null or absent
+// parent makes the taxonomy a root
+type moveRequest struct {
+	ParentID *string `json:"parent_id"`
+}
+
+func (h *Handler) readForest(w http.ResponseWriter, r *http.Request) {
+	h.readTree(w, r, "")
+}
+
+func (h *Handler) readSubtree(w http.ResponseWriter, r *http.Request) {
+	h.readTree(w, r, r.PathValue("id"))
+}
+
+// readTree writes the subtree of the taxonomy with the ID, or every tree when
+// the ID is empty, to the depth given by the query string
+func (h *Handler) readTree(w http.ResponseWriter, r *http.Request, id string) {
+	values := r.URL.Query()
+
+	depth, errs := parseDepth(values)
+
+	includeDeleted, fieldErrs := parseIncludeDeleted(values)
+	errs = append(errs, fieldErrs...)
+
+	if len(errs) > 0 {
+		h.writeProblem(w, r, http.StatusBadRequest, "invalid query parameters", errs...)
+		return
+	}
+
+	res, err := h.hierarchy.Subtree(r.Context(), taxonomy.NewQuery(
+		taxonomy.QueryWithTaxonomy(model.Taxonomy{
+			ID: id,
+		}),
+		taxonomy.QueryWithDepth(depth),
+		taxonomy.QueryWithIncludeDeleted(includeDeleted),
+	))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if id != "" {
+		h.writeJSON(w, http.StatusOK, res.Results[0])
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) listChildren(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+
+	pagination, errs := parsePagination(values)
+
+	includeDeleted, fieldErrs := parseIncludeDeleted(values)
+	errs = append(errs, fieldErrs...)
+
+	if len(errs) > 0 {
+		h.writeProblem(w, r, http.StatusBadRequest, "invalid query parameters", errs...)
+		return
+	}
+
+	res, err := h.hierarchy.Children(r.Context(), taxonomy.NewQuery(
+		taxonomy.QueryWithTaxonomy(model.Taxonomy{
+			ID: r.PathValue("id"),
+		}),
+		taxonomy.QueryWithPagination(pagination),
+		taxonomy.QueryWithIncludeDeleted(includeDeleted),
+	))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) listAncestors(w http.ResponseWriter, r *http.Request) {
+	includeDeleted, errs := parseIncludeDeleted(r.URL.Query())
+	if len(errs) > 0 {
+		h.writeProblem(w, r, http.StatusBadRequest, "invalid query parameters", errs...)
+		return
+	}
+
+	res, err := h.hierarchy.Ancestors(r.Context(), taxonomy.NewQuery(
+		taxonomy.QueryWithTaxonomy(model.Taxonomy{
+			ID: r.PathValue("id"),
+		}),
+		taxonomy.QueryWithIncludeDeleted(includeDeleted),
+	))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) moveTaxonomy(w http.ResponseWriter, r *http.Request) {
+	req := moveRequest{}
+
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.hierarchy.Move(r.Context(), taxonomy.NewQuery(
+		taxonomy.QueryWithTaxonomy(model.Taxonomy{
+			ID:       r.PathValue("id"),
+			ParentID: req.ParentID,
+		}),
+		withChange(r),
+	)); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	t, ok := h.findTaxonomy(w, r)
+	if !ok {
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, t)
+}
+
+// parseDepth parses the depth of a tree to read, an absent depth reads the
+// whole tree
+func parseDepth(values url.Values) (uint, []FieldError) {
+	v := values.Get("depth")
+	if v == "" {
+		return 0, nil
+	}
+
+	depth, err := strconv.ParseUint(v, 10, 32)
+	if err != nil || depth == 0 {
+		return 0, []FieldError{{Field: "depth", Detail: "must be a positive integer"}}
+	}
+
+	return uint(depth), nil
+}
@@ -0,0 +1,60 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+)
+
+// the headers describing who made a change and why, they are recorded in the
+// history as given, authenticating the actor is left to a proxy in front of
+// the API
+const (
+	headerActor  = "X-Actor"
+	headerReason = "X-Change-Reason"
+)
+
+func (h *Handler) readHistory(w http.ResponseWriter, r *http.Request) {
+	res, err := h.history.History(r.Context(), taxonomy.NewQuery(
+		taxonomy.QueryWithTaxonomy(model.Taxonomy{
+			ID: r.PathValue("id"),
+		}),
+	))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, res)
+}
+
+// withChange records the actor and reason of the change made by the request
+func withChange(r *http.Request) taxonomy.QueryOption {
+	return func(q *taxonomy.Query) {
+		taxonomy.QueryWithActor(r.Header.Get(headerActor))(q)
+		taxonomy.QueryWithReason(r.Header.Get(headerReason))(q)
+	}
+}
+
+// parseAsOf parses the time taxonomies are read as of, an RFC 3339 timestamp
+// or a date, an absent time reads them as they are now
+func parseAsOf(values url.Values) (time.Time, []FieldError) {
+	v := values.Get("as_of")
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return time.Time{}, []FieldError{{Field: "as_of", Detail: "must be an RFC 3339 timestamp or a date"}}
+	}
+
+	return t, nil
+}
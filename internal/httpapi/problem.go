@@ -0,0 +1,91 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/filter"
+
+	"github.com/olireadcopper/sqlxprototype/pkg/telemetry"
+	"github.com/olireadcopper/sqlxprototype/pkg/telemetry/logging"
+)
+
+const contentTypeProblem = "application/problem+json"
+
+// Problem RFC 9457 problem details returned for every error
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Trace    string       `json:"trace,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError a validation error of a single field of the request
+type FieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+func (h *Handler) writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string, errs ...FieldError) {
+	p := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Errors:   errs,
+	}
+
+	if trace, ok := telemetry.TraceFromContext(r.Context()); ok {
+		p.Trace = trace
+	}
+
+	w.Header().Set("Content-Type", contentTypeProblem)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// writeError maps an error returned by a repository to a problem, errors
+// which are not understood are logged and reported without detail
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		h.writeProblem(w, r, http.StatusNotFound, "the requested resource does not exist")
+		return
+	case errors.Is(err, repository.ErrInvalidCursor):
+		h.writeProblem(w, r, http.StatusBadRequest, "the pagination cursor is invalid", FieldError{
+			Field:  "cursor",
+			Detail: "must be a cursor returned by a previous page",
+		})
+		return
+	case errors.Is(err, filter.ErrInvalid), errors.Is(err, repository.ErrUnsupported):
+		h.writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, repository.ErrConflict):
+		h.writeProblem(w, r, http.StatusConflict, "the request conflicts with an existing resource")
+		return
+	}
+
+	args := []any{
+		logging.FieldMethod, r.Method + " " + r.URL.Path,
+	}
+
+	if trace, ok := telemetry.TraceFromContext(r.Context()); ok {
+		args = append(args, logging.FieldTrace, trace)
+	}
+
+	h.logger.ErrorContext(r.Context(), err.Error(), args...)
+
+	h.writeProblem(w, r, http.StatusInternalServerError, "")
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,35 @@
+package httpapi
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+)
+
+func (h *Handler) searchTaxonomies(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+
+	pagination, errs := parsePagination(values)
+
+	search := values.Get("q")
+	if strings.TrimSpace(search) == "" {
+		errs = append(errs, FieldError{Field: "q", Detail: "is required"})
+	}
+
+	if len(errs) > 0 {
+		h.writeProblem(w, r, http.StatusBadRequest, "invalid query parameters", errs...)
+		return
+	}
+
+	res, err := h.search.Search(r.Context(), taxonomy.NewQuery(
+		taxonomy.QueryWithSearch(search),
+		taxonomy.QueryWithPagination(pagination),
+	))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,27 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+)
+
+func (h *Handler) restoreTaxonomy(w http.ResponseWriter, r *http.Request) {
+	if err := h.softDelete.Restore(r.Context(), taxonomy.NewQuery(
+		taxonomy.QueryWithTaxonomy(model.Taxonomy{
+			ID: r.PathValue("id"),
+		}),
+		withChange(r),
+	)); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	t, ok := h.findTaxonomy(w, r)
+	if !ok {
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, t)
+}
@@ -0,0 +1,345 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/filter"
+)
+
+const maxNameLength = 255
+
+// taxonomyColumns the columns taxonomies can be filtered and sorted by
+var taxonomyColumns = filter.ColumnsOf(model.Taxonomy{})
+
+// taxonomyRequest the body of requests creating or updating a taxonomy,
+// fields are pointers so a PATCH can tell absent fields from empty ones
+type taxonomyRequest struct {
+	Name     *string `json:"name"`
+	ParentID *string `json:"parent_id"`
+}
+
+func (h *Handler) createTaxonomy(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeTaxonomy(w, r, false)
+	if !ok {
+		return
+	}
+
+	res, err := h.taxonomies.Create(r.Context(), taxonomy.NewQuery(
+		taxonomy.QueryWithTaxonomy(model.Taxonomy{
+			Name:     *req.Name,
+			ParentID: req.ParentID,
+		}),
+		withChange(r),
+	))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	t := res.Results[0]
+
+	w.Header().Set("Location", "/taxonomies/"+url.PathEscape(t.ID))
+
+	h.writeJSON(w, http.StatusCreated, t)
+}
+
+// listTaxonomies lists a page of taxonomies, filtered and sorted with the
+// syntax of the filter package, filter=name like 'Mam%'&sort=-created_at
+func (h *Handler) listTaxonomies(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+
+	pagination, errs := parsePagination(values)
+
+	e, err := filter.Parse(values.Get("filter"), taxonomyColumns)
+	if err != nil {
+		errs = append(errs, FieldError{Field: "filter", Detail: err.Error()})
+	}
+
+	sorts, err := filter.ParseSort(values.Get("sort"), taxonomyColumns)
+	if err != nil {
+		errs = append(errs, FieldError{Field: "sort", Detail: err.Error()})
+	}
+
+	includeDeleted, fieldErrs := parseIncludeDeleted(values)
+	errs = append(errs, fieldErrs...)
+
+	asOf, fieldErrs := parseAsOf(values)
+	errs = append(errs, fieldErrs...)
+
+	if len(errs) > 0 {
+		h.writeProblem(w, r, http.StatusBadRequest, "invalid query parameters", errs...)
+		return
+	}
+
+	res, err := h.taxonomies.Read(r.Context(), taxonomy.NewQuery(
+		taxonomy.QueryWithPagination(pagination),
+		taxonomy.QueryWithFilter(e),
+		taxonomy.QueryWithSort(sorts...),
+		taxonomy.QueryWithIncludeDeleted(includeDeleted),
+		taxonomy.QueryWithAsOf(asOf),
+	))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) readTaxonomy(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+
+	includeDeleted, errs := parseIncludeDeleted(values)
+
+	asOf, fieldErrs := parseAsOf(values)
+	errs = append(errs, fieldErrs...)
+
+	if len(errs) > 0 {
+		h.writeProblem(w, r, http.StatusBadRequest, "invalid query parameters", errs...)
+		return
+	}
+
+	t, ok := h.findTaxonomy(w, r,
+		taxonomy.QueryWithIncludeDeleted(includeDeleted),
+		taxonomy.QueryWithAsOf(asOf),
+	)
+	if !ok {
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, t)
+}
+
+func (h *Handler) replaceTaxonomy(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeTaxonomy(w, r, false)
+	if !ok || !h.refuseParent(w, r, req) {
+		return
+	}
+
+	h.updateTaxonomy(w, r, model.Taxonomy{
+		ID:   r.PathValue("id"),
+		Name: *req.Name,
+	})
+}
+
+func (h *Handler) patchTaxonomy(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeTaxonomy(w, r, true)
+	if !ok || !h.refuseParent(w, r, req) {
+		return
+	}
+
+	t, ok := h.findTaxonomy(w, r)
+	if !ok {
+		return
+	}
+
+	if req.Name != nil {
+		t.Name = *req.Name
+	}
+
+	h.updateTaxonomy(w, r, t)
+}
+
+func (h *Handler) updateTaxonomy(w http.ResponseWriter, r *http.Request, t model.Taxonomy) {
+	if err := h.taxonomies.Update(r.Context(), taxonomy.NewQuery(
+		taxonomy.QueryWithTaxonomy(t),
+		withChange(r),
+	)); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	// read back the taxonomy so the response carries its timestamps
+	t, ok := h.findTaxonomy(w, r)
+	if !ok {
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, t)
+}
+
+func (h *Handler) deleteTaxonomy(w http.ResponseWriter, r *http.Request) {
+	if err := h.taxonomies.Delete(r.Context(), taxonomy.NewQuery(
+		taxonomy.QueryWithTaxonomy(model.Taxonomy{
+			ID: r.PathValue("id"),
+		}),
+		withChange(r),
+	)); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findTaxonomy reads the taxonomy identified by the path of the request,
+// opts refine the query
+func (h *Handler) findTaxonomy(w http.ResponseWriter, r *http.Request, opts ...taxonomy.QueryOption) (model.Taxonomy, bool) {
+	opts = append([]taxonomy.QueryOption{
+		taxonomy.QueryWithTaxonomy(model.Taxonomy{
+			ID: r.PathValue("id"),
+		}),
+		taxonomy.QueryWithPagination(repository.Pagination{
+			Limit: 1,
+		}),
+	}, opts...)
+
+	res, err := h.taxonomies.Read(r.Context(), taxonomy.NewQuery(opts...))
+	if err != nil {
+		h.writeError(w, r, err)
+		return model.Taxonomy{}, false
+	}
+
+	return res.Results[0], true
+}
+
+// decodeTaxonomy decodes and validates the body of the request, writing a
+// problem when it is invalid, partial bodies may omit fields
+func (h *Handler) decodeTaxonomy(w http.ResponseWriter, r *http.Request, partial bool) (taxonomyRequest, bool) {
+	req := taxonomyRequest{}
+
+	if !h.decodeJSON(w, r, &req) {
+		return req, false
+	}
+
+	if errs := validateTaxonomy(req, partial); len(errs) > 0 {
+		h.writeProblem(w, r, http.StatusUnprocessableEntity, "the taxonomy is invalid", errs...)
+		return req, false
+	}
+
+	return req, true
+}
+
+// decodeJSON decodes the single JSON object of the body of the request into
+// v, writing a problem when the body can not be decoded
+func (h *Handler) decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		if mt, _, err := mime.ParseMediaType(ct); err != nil || mt != "application/json" {
+			h.writeProblem(w, r, http.StatusUnsupportedMediaType, "the request body must be application/json")
+			return false
+		}
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		h.writeProblem(w, r, http.StatusBadRequest, "the request body is not valid JSON: "+err.Error())
+		return false
+	}
+
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		h.writeProblem(w, r, http.StatusBadRequest, "the request body must contain a single JSON object")
+		return false
+	}
+
+	return true
+}
+
+// refuseParent writes a problem when an update attempts to change the
+// parent, which must go through a move so cycles are prevented
+func (h *Handler) refuseParent(w http.ResponseWriter, r *http.Request, req taxonomyRequest) bool {
+	if req.ParentID == nil {
+		return true
+	}
+
+	h.writeProblem(w, r, http.StatusUnprocessableEntity, "the taxonomy is invalid", FieldError{
+		Field:  "parent_id",
+		Detail: "can only be changed with PUT /taxonomies/{id}/parent",
+	})
+
+	return false
+}
+
+func validateTaxonomy(req taxonomyRequest, partial bool) []FieldError {
+	errs := []FieldError{}
+
+	switch {
+	case req.Name == nil && !partial:
+		errs = append(errs, FieldError{Field: "name", Detail: "is required"})
+	case req.Name == nil:
+	case strings.TrimSpace(*req.Name) == "":
+		errs = append(errs, FieldError{Field: "name", Detail: "must not be blank"})
+	case utf8.RuneCountInString(*req.Name) > maxNameLength:
+		errs = append(errs, FieldError{Field: "name", Detail: fmt.Sprintf("must be at most %d characters", maxNameLength)})
+	}
+
+	return errs
+}
+
+func parsePagination(values url.Values) (repository.Pagination, []FieldError) {
+	p := repository.Pagination{
+		Limit: defaultLimit,
+	}
+	errs := []FieldError{}
+
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.ParseUint(v, 10, 32)
+
+		switch {
+		case err != nil:
+			errs = append(errs, FieldError{Field: "limit", Detail: "must be a non-negative integer"})
+		case limit == 0 || limit > maxLimit:
+			errs = append(errs, FieldError{Field: "limit", Detail: fmt.Sprintf("must be between 1 and %d", maxLimit)})
+		default:
+			p.Limit = uint(limit)
+		}
+	}
+
+	if v := values.Get("offset"); v != "" {
+		offset, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			errs = append(errs, FieldError{Field: "offset", Detail: "must be a non-negative integer"})
+		} else {
+			p.Offset = uint(offset)
+		}
+	}
+
+	if v := values.Get("cursor"); v != "" {
+		if values.Has("offset") {
+			errs = append(errs, FieldError{Field: "cursor", Detail: "can not be combined with offset"})
+		}
+
+		p.Cursor = v
+	}
+
+	switch v := values.Get("total"); v {
+	case "":
+	case "exact":
+		p.TotalMode = repository.TotalExact
+	case "estimated":
+		p.TotalMode = repository.TotalEstimated
+	default:
+		errs = append(errs, FieldError{Field: "total", Detail: "must be exact or estimated"})
+	}
+
+	return p, errs
+}
+
+// parseIncludeDeleted parses the include_deleted parameter, deleted
+// taxonomies are left out unless it is true
+func parseIncludeDeleted(values url.Values) (bool, []FieldError) {
+	v := values.Get("include_deleted")
+	if v == "" {
+		return false, nil
+	}
+
+	include, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, []FieldError{{Field: "include_deleted", Detail: "must be a boolean"}}
+	}
+
+	return include, nil
+}
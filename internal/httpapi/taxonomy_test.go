@@ -0,0 +1,328 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/filter"
+)
+
+// stubRepository an in memory taxonomy.Repository, every call fails with err
+// when it is set
+type stubRepository struct {
+	taxonomies map[string]model.Taxonomy
+	updates    []taxonomy.Query
+	err        error
+}
+
+func newStubRepository(taxonomies ...model.Taxonomy) *stubRepository {
+	r := &stubRepository{taxonomies: map[string]model.Taxonomy{}}
+
+	for _, t := range taxonomies {
+		r.taxonomies[t.ID] = t
+	}
+
+	return r
+}
+
+func (r *stubRepository) Create(_ context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	if r.err != nil {
+		return taxonomy.Response{}, r.err
+	}
+
+	t := query.Taxonomy
+	t.ID = fmt.Sprint(len(r.taxonomies) + 1)
+	r.taxonomies[t.ID] = t
+
+	return taxonomy.NewResponse(taxonomy.ResponseWithResult(t)), nil
+}
+
+func (r *stubRepository) Read(_ context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	if r.err != nil {
+		return taxonomy.Response{}, r.err
+	}
+
+	t, ok := r.taxonomies[query.Taxonomy.ID]
+	if !ok {
+		return taxonomy.Response{}, repository.ErrNotFound
+	}
+
+	return taxonomy.NewResponse(taxonomy.ResponseWithResult(t)), nil
+}
+
+func (r *stubRepository) Update(_ context.Context, query taxonomy.Query) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	if _, ok := r.taxonomies[query.Taxonomy.ID]; !ok {
+		return repository.ErrNotFound
+	}
+
+	r.updates = append(r.updates, query)
+	r.taxonomies[query.Taxonomy.ID] = query.Taxonomy
+
+	return nil
+}
+
+func (r *stubRepository) Delete(_ context.Context, query taxonomy.Query) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	if _, ok := r.taxonomies[query.Taxonomy.ID]; !ok {
+		return repository.ErrNotFound
+	}
+
+	delete(r.taxonomies, query.Taxonomy.ID)
+
+	return nil
+}
+
+// serve serves a request with the body and content type to h
+func serve(t *testing.T, h *Handler, method, target, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	return rec
+}
+
+// decodeProblem decodes the problem of the response, failing the test when
+// the response is not a problem
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) Problem {
+	t.Helper()
+
+	if ct := rec.Header().Get("Content-Type"); ct != contentTypeProblem {
+		t.Fatalf("Content-Type = %q, want %q", ct, contentTypeProblem)
+	}
+
+	p := Problem{}
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		field  string
+	}{
+		{repository.ErrNotFound, http.StatusNotFound, ""},
+		{fmt.Errorf("read: %w", repository.ErrConflict), http.StatusConflict, ""},
+		{repository.ErrInvalidCursor, http.StatusBadRequest, "cursor"},
+		{repository.ErrUnsupported, http.StatusBadRequest, ""},
+		{fmt.Errorf("%w: unknown column", filter.ErrInvalid), http.StatusBadRequest, ""},
+		{errors.New("disk I/O error"), http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range tests {
+		logs := &bytes.Buffer{}
+		repo := newStubRepository()
+		repo.err = tt.err
+
+		h := NewHandler(
+			HandlerWithTaxonomyRepository(repo),
+			HandlerWithLogger(slog.New(slog.NewTextHandler(logs, nil))),
+		)
+
+		rec := serve(t, h, http.MethodGet, "/taxonomies/1", "", "")
+		p := decodeProblem(t, rec)
+
+		if rec.Code != tt.status || p.Status != tt.status || p.Title != http.StatusText(tt.status) {
+			t.Errorf("%v: problem = %d %d %q, want %d", tt.err, rec.Code, p.Status, p.Title, tt.status)
+		}
+
+		if p.Instance != "/taxonomies/1" || p.Trace == "" {
+			t.Errorf("%v: instance = %q and trace = %q, want the path and a trace", tt.err, p.Instance, p.Trace)
+		}
+
+		if tt.field != "" && (len(p.Errors) != 1 || p.Errors[0].Field != tt.field) {
+			t.Errorf("%v: errors = %v, want one of %s", tt.err, p.Errors, tt.field)
+		}
+
+		// only the errors which are not understood are logged, without
+		// their detail reaching the client
+		internal := tt.status == http.StatusInternalServerError
+
+		if internal && (p.Detail != "" || !strings.Contains(logs.String(), tt.err.Error())) {
+			t.Errorf("%v: detail = %q, logs = %q, want the error logged only", tt.err, p.Detail, logs)
+		}
+
+		if !internal && logs.Len() > 0 {
+			t.Errorf("%v: logs = %q, want none", tt.err, logs)
+		}
+	}
+}
+
+func TestDecodeTaxonomy(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		status      int
+		field       string
+	}{
+		{"created", http.MethodPost, "application/json", `{"name": "Mammals"}`, http.StatusCreated, ""},
+		{"charset", http.MethodPost, "application/json; charset=utf-8", `{"name": "Mammals"}`, http.StatusCreated, ""},
+		{"no content type", http.MethodPost, "", `{"name": "Mammals"}`, http.StatusCreated, ""},
+		{"not json", http.MethodPost, "text/plain", `{"name": "Mammals"}`, http.StatusUnsupportedMediaType, ""},
+		{"malformed", http.MethodPost, "application/json", `{"name": `, http.StatusBadRequest, ""},
+		{"unknown field", http.MethodPost, "application/json", `{"name": "Mammals", "rank": 1}`, http.StatusBadRequest, ""},
+		{"two objects", http.MethodPost, "application/json", `{"name": "a"} {"name": "b"}`, http.StatusBadRequest, ""},
+		{"missing name", http.MethodPost, "application/json", `{}`, http.StatusUnprocessableEntity, "name"},
+		{"blank name", http.MethodPost, "application/json", `{"name": " "}`, http.StatusUnprocessableEntity, "name"},
+		{"long name", http.MethodPost, "application/json", `{"name": "` + strings.Repeat("é", maxNameLength+1) + `"}`, http.StatusUnprocessableEntity, "name"},
+		{"longest name", http.MethodPost, "application/json", `{"name": "` + strings.Repeat("é", maxNameLength) + `"}`, http.StatusCreated, ""},
+		{"replace missing name", http.MethodPut, "application/json", `{}`, http.StatusUnprocessableEntity, "name"},
+		{"replace parent", http.MethodPut, "application/json", `{"name": "a", "parent_id": "2"}`, http.StatusUnprocessableEntity, "parent_id"},
+		{"patch blank name", http.MethodPatch, "application/json", `{"name": ""}`, http.StatusUnprocessableEntity, "name"},
+	}
+
+	for _, tt := range tests {
+		h := NewHandler(HandlerWithTaxonomyRepository(newStubRepository(model.Taxonomy{ID: "1", Name: "a"})))
+
+		target := "/taxonomies"
+		if tt.method != http.MethodPost {
+			target += "/1"
+		}
+
+		rec := serve(t, h, tt.method, target, tt.contentType, tt.body)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: code = %d, want %d: %s", tt.name, rec.Code, tt.status, rec.Body)
+			continue
+		}
+
+		if tt.status == http.StatusCreated {
+			if loc := rec.Header().Get("Location"); loc != "/taxonomies/2" {
+				t.Errorf("%s: Location = %q, want /taxonomies/2", tt.name, loc)
+			}
+
+			continue
+		}
+
+		p := decodeProblem(t, rec)
+
+		if tt.field != "" && (len(p.Errors) != 1 || p.Errors[0].Field != tt.field) {
+			t.Errorf("%s: errors = %v, want one of %s", tt.name, p.Errors, tt.field)
+		}
+	}
+}
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		query  string
+		want   repository.Pagination
+		fields []string
+	}{
+		{"", repository.Pagination{Limit: defaultLimit}, nil},
+		{"limit=1&offset=40", repository.Pagination{Limit: 1, Offset: 40}, nil},
+		{fmt.Sprintf("limit=%d", maxLimit), repository.Pagination{Limit: maxLimit}, nil},
+		{fmt.Sprintf("limit=%d", maxLimit+1), repository.Pagination{Limit: defaultLimit}, []string{"limit"}},
+		{"limit=0", repository.Pagination{Limit: defaultLimit}, []string{"limit"}},
+		{"limit=-1&offset=x", repository.Pagination{Limit: defaultLimit}, []string{"limit", "offset"}},
+		{"cursor=abc", repository.Pagination{Limit: defaultLimit, Cursor: "abc"}, nil},
+		{"cursor=abc&offset=1", repository.Pagination{Limit: defaultLimit, Offset: 1, Cursor: "abc"}, []string{"cursor"}},
+		{"total=exact", repository.Pagination{Limit: defaultLimit, TotalMode: repository.TotalExact}, nil},
+		{"total=estimated", repository.Pagination{Limit: defaultLimit, TotalMode: repository.TotalEstimated}, nil},
+		{"total=some", repository.Pagination{Limit: defaultLimit}, []string{"total"}},
+	}
+
+	for _, tt := range tests {
+		values, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, errs := parsePagination(values)
+
+		fields := []string{}
+		for _, e := range errs {
+			fields = append(fields, e.Field)
+		}
+
+		if got != tt.want || strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+			t.Errorf("parsePagination(%q) = %+v %v, want %+v %v", tt.query, got, fields, tt.want, tt.fields)
+		}
+	}
+}
+
+func TestPatchTaxonomy(t *testing.T) {
+	parentID := "2"
+
+	tests := []struct {
+		name string
+		id   string
+		body string
+		code int
+		want string
+	}{
+		{"name", "1", `{"name": "Felines"}`, http.StatusOK, "Felines"},
+		{"empty", "1", `{}`, http.StatusOK, "Cats"},
+		{"missing", "9", `{"name": "Felines"}`, http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		repo := newStubRepository(model.Taxonomy{ID: "1", Name: "Cats", ParentID: &parentID})
+		h := NewHandler(HandlerWithTaxonomyRepository(repo))
+
+		rec := serve(t, h, http.MethodPatch, "/taxonomies/"+tt.id, "application/json", tt.body)
+
+		if rec.Code != tt.code {
+			t.Errorf("%s: code = %d, want %d: %s", tt.name, rec.Code, tt.code, rec.Body)
+			continue
+		}
+
+		if tt.code != http.StatusOK {
+			if len(repo.updates) != 0 {
+				t.Errorf("%s: %d updates, want none", tt.name, len(repo.updates))
+			}
+
+			continue
+		}
+
+		// the fields absent from the body are read back from the repository
+		// rather than cleared
+		if len(repo.updates) != 1 {
+			t.Fatalf("%s: %d updates, want 1", tt.name, len(repo.updates))
+		}
+
+		updated := repo.updates[0].Taxonomy
+
+		if updated.Name != tt.want || updated.ParentID == nil || *updated.ParentID != parentID {
+			t.Errorf("%s: updated %+v, want name %s and parent %s", tt.name, updated, tt.want, parentID)
+		}
+
+		got := model.Taxonomy{}
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Name != tt.want {
+			t.Errorf("%s: response name = %q, want %q", tt.name, got.Name, tt.want)
+		}
+	}
+}
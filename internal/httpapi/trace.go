@@ -0,0 +1,56 @@
+package httpapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/olireadcopper/sqlxprototype/pkg/telemetry"
+)
+
+const (
+	headerTraceParent = "Traceparent"
+	headerRequestID   = "X-Request-Id"
+)
+
+var (
+	// traceParentID matches the trace ID of a traceparent header
+	traceParentID = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	// requestID matches the request IDs accepted from clients, they end up in
+	// logs, response headers and metric exemplars so they are kept short and
+	// plain
+	requestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+)
+
+// withTrace carries the trace ID of the request into its context, taken from
+// a W3C traceparent header, an X-Request-Id header or generated when neither
+// is present, and echoes it back in the X-Request-Id response header
+func withTrace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		trace := traceID(r)
+
+		w.Header().Set(headerRequestID, trace)
+
+		next.ServeHTTP(w, r.WithContext(
+			telemetry.ContextWithTrace(r.Context(), trace),
+		))
+	})
+}
+
+func traceID(r *http.Request) string {
+	// traceparent is version-trace_id-parent_id-flags
+	if parts := strings.Split(r.Header.Get(headerTraceParent), "-"); len(parts) == 4 && traceParentID.MatchString(parts[1]) {
+		return parts[1]
+	}
+
+	if id := r.Header.Get(headerRequestID); requestID.MatchString(id) {
+		return id
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,41 @@
+package httpapi
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTraceID(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceParent string
+		requestID   string
+		want        string
+	}{
+		{"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "", "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"traceparent not hex", "00-4bf92f3577b34da6a3ce929d0e0e473\xff-00f067aa0ba902b7-01", "", ""},
+		{"request id", "", "req-42_a.b", "req-42_a.b"},
+		{"request id too long", "", strings.Repeat("a", 125), ""},
+		{"request id invalid UTF-8", "", "req\xff", ""},
+		{"request id with spaces", "", "req 42", ""},
+		{"none", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set(headerTraceParent, tt.traceParent)
+		r.Header.Set(headerRequestID, tt.requestID)
+
+		got := traceID(r)
+
+		if tt.want != "" && got != tt.want {
+			t.Errorf("%s: traceID() = %q, want %q", tt.name, got, tt.want)
+		}
+
+		// rejected IDs are replaced with generated ones
+		if tt.want == "" && !traceParentID.MatchString(got) {
+			t.Errorf("%s: traceID() = %q, want a generated ID", tt.name, got)
+		}
+	}
+}
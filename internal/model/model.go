@@ -1,6 +1,16 @@
 package model
 
+import "time"
+
+// Taxonomy a node of a taxonomy tree, roots have no ParentID, DeletedAt is set
+// once the taxonomy is deleted, Children is only populated by the queries
+// reading a subtree
 type Taxonomy struct {
-	ID   string `db:"id" json:"id"`
-	Name string `db:"name" json:"name"`
+	ID        string     `db:"id" json:"id"`
+	Name      string     `db:"name" json:"name"`
+	ParentID  *string    `db:"parent_id" json:"parent_id"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+	Children  []Taxonomy `db:"-" json:"children,omitempty"`
 }
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Cursor a position in a sorted set of entities, the page it selects holds
+// the entities after Key, or before it when Backward is set, Query
+// fingerprints the query the cursor was taken from, the cursor selecting
+// nothing meaningful in the results of another
+type Cursor struct {
+	Key      string `json:"k"`
+	Backward bool   `json:"b,omitempty"`
+	Query    string `json:"q,omitempty"`
+}
+
+// CursorCodec encodes cursors into strings signed with a key, making them
+// tamper-evident, the payload is only encoded so clients can read it
+type CursorCodec struct {
+	key []byte
+}
+
+// NewCursorCodec constructor for a cursor codec signing with key, a random key
+// is generated when it is empty, invalidating cursors when the process
+// restarts
+func NewCursorCodec(key []byte) *CursorCodec {
+	if len(key) == 0 {
+		key = make([]byte, sha256.Size)
+
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+	}
+
+	return &CursorCodec{
+		key: key,
+	}
+}
+
+// Encode returns the signed representation of c
+func (c *CursorCodec) Encode(cursor Cursor) string {
+	payload, err := json.Marshal(cursor)
+	if err != nil {
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(c.sign(payload))
+}
+
+// Decode returns the cursor represented by s, ErrInvalidCursor is returned
+// when s is malformed or was not signed with the key of the codec
+func (c *CursorCodec) Decode(s string) (Cursor, error) {
+	cursor := Cursor{}
+
+	encodedPayload, encodedSignature, ok := strings.Cut(s, ".")
+	if !ok {
+		return cursor, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+
+	if !hmac.Equal(signature, c.sign(payload)) {
+		return cursor, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return cursor, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	return cursor, nil
+}
+
+// Fingerprint returns a digest of v encoded as JSON, for the Query of cursors
+func Fingerprint(v any) string {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+
+	digest := sha256.Sum256(payload)
+
+	return base64.RawURLEncoding.EncodeToString(digest[:12])
+}
+
+func (c *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(payload)
+
+	return mac.Sum(nil)
+}
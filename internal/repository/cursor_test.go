@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestCursorCodec(t *testing.T) {
+	c := NewCursorCodec([]byte("key"))
+
+	for _, want := range []Cursor{
+		{Key: "42"},
+		{Key: "42", Backward: true, Query: Fingerprint("filter")},
+		{},
+	} {
+		got, err := c.Decode(c.Encode(want))
+		if err != nil {
+			t.Errorf("Decode(Encode(%+v)) error = %v", want, err)
+			continue
+		}
+
+		if got != want {
+			t.Errorf("Decode(Encode(%+v)) = %+v", want, got)
+		}
+	}
+
+	// codecs sharing a key accept each other's cursors
+	if _, err := NewCursorCodec([]byte("key")).Decode(c.Encode(Cursor{Key: "42"})); err != nil {
+		t.Errorf("Decode() with the same key error = %v", err)
+	}
+}
+
+func TestCursorCodecInvalid(t *testing.T) {
+	c := NewCursorCodec([]byte("key"))
+	valid := c.Encode(Cursor{Key: "42"})
+
+	payload, signature := base64.RawURLEncoding.EncodeToString([]byte(`{"k":"43"}`)), valid[len(valid)-43:]
+	garbage := []byte(`{"k":`)
+
+	// the last character of a signature carries its last 4 bits
+	flipped := "A"
+	if valid[len(valid)-1] == 'A' {
+		flipped = "E"
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"empty", ""},
+		{"no signature", valid[:len(valid)-44]},
+		{"truncated signature", valid[:len(valid)-1]},
+		{"truncated payload", valid[1:]},
+		{"tampered payload", payload + "." + signature},
+		{"tampered signature", valid[:len(valid)-1] + flipped},
+		{"not base64", "!!." + signature},
+		{"other key", NewCursorCodec([]byte("other")).Encode(Cursor{Key: "42"})},
+		{"random key", NewCursorCodec(nil).Encode(Cursor{Key: "42"})},
+		{
+			"signed garbage",
+			base64.RawURLEncoding.EncodeToString(garbage) + "." + base64.RawURLEncoding.EncodeToString(c.sign(garbage)),
+		},
+	}
+
+	for _, tt := range tests {
+		if _, err := c.Decode(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: Decode(%q) error = %v, want %v", tt.name, tt.cursor, err, ErrInvalidCursor)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	type query struct {
+		Filter  string
+		Deleted bool
+	}
+
+	if Fingerprint(query{"a", false}) != Fingerprint(query{"a", false}) {
+		t.Error("Fingerprint() differs for equal values")
+	}
+
+	if Fingerprint(query{"a", false}) == Fingerprint(query{"a", true}) {
+		t.Error("Fingerprint() equal for different values")
+	}
+}
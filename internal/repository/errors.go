@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/filter"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/sqlerr"
+)
+
+var (
+	// ErrNotFound returned when the entity a query refers to does not exist
+	ErrNotFound = errors.New("repository: not found")
+	// ErrConflict returned when a write conflicts with an existing entity,
+	// such as a duplicate name or a reference to a missing entity
+	ErrConflict = errors.New("repository: conflict")
+	// ErrInvalidCursor returned when a pagination cursor is malformed or was
+	// tampered with
+	ErrInvalidCursor = errors.New("repository: invalid cursor")
+	// ErrUnsupported returned when a query asks for what the repository does
+	// not implement, such as reading the past from a store without history
+	ErrUnsupported = errors.New("repository: unsupported query")
+)
+
+// Code returns the kind of err as a string suitable for log fields and
+// metric labels, errors of the repository are named after their variable and
+// others are classified with sqlerr, an empty string when err is nil
+func Code(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	case errors.Is(err, ErrConflict):
+		return "conflict"
+	case errors.Is(err, ErrInvalidCursor):
+		return "invalid_cursor"
+	case errors.Is(err, ErrUnsupported):
+		return "unsupported"
+	case errors.Is(err, filter.ErrInvalid):
+		return "invalid_filter"
+	}
+
+	return sqlerr.Code(err)
+}
+
+// IsClientError reports whether err was caused by the query rather than by
+// the repository failing, such as a missing entity or an invalid filter
+func IsClientError(err error) bool {
+	switch Code(err) {
+	case "not_found", "conflict", "invalid_cursor", "unsupported", "invalid_filter", string(sqlerr.CategoryCanceled):
+		return true
+	}
+
+	return false
+}
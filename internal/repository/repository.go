@@ -1,7 +1,29 @@
 package repository
 
+// TotalMode selects whether a page reports the total number of entities and
+// how it is counted
+type TotalMode uint8
+
+const (
+	// TotalNone the total is not reported
+	TotalNone TotalMode = iota
+	// TotalExact the total is counted, which scans the table
+	TotalExact
+	// TotalEstimated the total is estimated cheaply and may be inaccurate
+	TotalEstimated
+)
+
+// Pagination selects a page of entities and describes the page returned, a
+// page is selected either by offset or by a cursor returned with a previous
+// page, cursors keep their position under concurrent inserts and deletes
 type Pagination struct {
-	Limit  uint `json:"limit"`
-	Offset uint `json:"offset"`
-	Count  uint `json:"count"`
+	Limit          uint      `json:"limit"`
+	Offset         uint      `json:"offset"`
+	Count          uint      `json:"count"`
+	Cursor         string    `json:"-"`
+	TotalMode      TotalMode `json:"-"`
+	NextCursor     string    `json:"next_cursor,omitempty"`
+	PrevCursor     string    `json:"prev_cursor,omitempty"`
+	Total          *uint     `json:"total,omitempty"`
+	TotalEstimated bool      `json:"total_estimated,omitempty"`
 }
@@ -0,0 +1,312 @@
+// Package cache decorates a taxonomy.Repository with a size bounded LRU
+// cache of its reads, of taxonomies by ID and of pages of taxonomies, which
+// expire after a TTL. Taxonomies not found are cached too, for a TTL of their
+// own. Writes through the decorator invalidate the taxonomy written and every
+// page, writes made by other means, moves and restores for example, must call
+// Invalidate, otherwise they are only seen once the entries they affect expire
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/filter"
+)
+
+const (
+	defaultSize        = 1024
+	defaultTTL         = time.Minute
+	defaultNegativeTTL = 5 * time.Second
+)
+
+type RepositoryOption func(*Repository)
+
+// Repository a taxonomy.Repository caching the reads of its inner
+// repository, safe for concurrent use
+type Repository struct {
+	inner       taxonomy.Repository
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	// generation counts the writes, a read loaded across a write may miss it
+	// so it is not cached
+	generation uint64
+
+	loads singleflight.Group
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// Stats the statistics of a cache, Hits include the taxonomies found not to
+// exist, Evictions count the entries dropped to make room for others
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+}
+
+// entry a cached read, ID is empty for pages
+type entry struct {
+	key     string
+	id      string
+	res     taxonomy.Response
+	err     error
+	expires time.Time
+}
+
+// NewRepository constructor for a caching decorator, the inner repository
+// must be provided as an option
+func NewRepository(opts ...RepositoryOption) *Repository {
+	r := &Repository{
+		size:        defaultSize,
+		ttl:         defaultTTL,
+		negativeTTL: defaultNegativeTTL,
+		entries:     map[string]*list.Element{},
+		lru:         list.New(),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.size <= 0 {
+		r.size = defaultSize
+	}
+
+	return r
+}
+
+// Create caching implementation for a taxonomy repository, the pages cached
+// are invalidated along with the taxonomy found not to exist under the ID
+// created
+func (r *Repository) Create(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	res, err := r.inner.Create(ctx, query)
+
+	ids := []string{}
+	for _, t := range res.Results {
+		ids = append(ids, t.ID)
+	}
+
+	r.invalidate(ids...)
+
+	return res, err
+}
+
+// Read caching implementation for a taxonomy repository, concurrent reads
+// missing the cache share a single read of the inner repository
+func (r *Repository) Read(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	key, err := keyOf(query)
+	if err != nil {
+		return r.inner.Read(ctx, query)
+	}
+
+	if e, ok := r.get(key); ok {
+		r.hits.Add(1)
+
+		return clone(e.res), e.err
+	}
+
+	r.misses.Add(1)
+
+	r.mu.Lock()
+	generation := r.generation
+	r.mu.Unlock()
+
+	// a read is only shared with the reads of the same generation, which can
+	// not miss a write the others saw
+	ch := r.loads.DoChan(key+"@"+strconv.FormatUint(generation, 10), func() (any, error) {
+		// the read is shared so it outlives the cancellation of any reader
+		res, err := r.inner.Read(context.WithoutCancel(ctx), query)
+
+		r.put(key, query.Taxonomy.ID, generation, res, err)
+
+		return res, err
+	})
+
+	select {
+	case <-ctx.Done():
+		return taxonomy.Response{}, ctx.Err()
+	case result := <-ch:
+		return clone(result.Val.(taxonomy.Response)), result.Err
+	}
+}
+
+// Update caching implementation for a taxonomy repository, the taxonomy and
+// the pages cached are invalidated
+func (r *Repository) Update(ctx context.Context, query taxonomy.Query) error {
+	err := r.inner.Update(ctx, query)
+
+	r.invalidate(query.Taxonomy.ID)
+
+	return err
+}
+
+// Delete caching implementation for a taxonomy repository, the taxonomy and
+// the pages cached are invalidated
+func (r *Repository) Delete(ctx context.Context, query taxonomy.Query) error {
+	err := r.inner.Delete(ctx, query)
+
+	r.invalidate(query.Taxonomy.ID)
+
+	return err
+}
+
+// Stats returns the statistics of the cache
+func (r *Repository) Stats() Stats {
+	r.mu.Lock()
+	entries := r.lru.Len()
+	r.mu.Unlock()
+
+	return Stats{
+		Hits:      r.hits.Load(),
+		Misses:    r.misses.Load(),
+		Evictions: r.evictions.Load(),
+		Entries:   entries,
+	}
+}
+
+// Invalidate drops the entries of the taxonomies with the IDs and every page,
+// for the writers of the taxonomies bypassing the cache
+func (r *Repository) Invalidate(ids ...string) {
+	r.invalidate(ids...)
+}
+
+// get returns the entry of the key unless it expired
+func (r *Repository) get(key string) (entry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	el, ok := r.entries[key]
+	if !ok {
+		return entry{}, false
+	}
+
+	e := el.Value.(*entry)
+
+	if time.Now().After(e.expires) {
+		r.remove(el)
+		return entry{}, false
+	}
+
+	r.lru.MoveToFront(el)
+
+	return *e, true
+}
+
+// put caches the result of a read of the generation, unless a write
+// followed, errors other than a taxonomy not found are not cached
+func (r *Repository) put(key, id string, generation uint64, res taxonomy.Response, err error) {
+	ttl := r.ttl
+
+	if err != nil {
+		if id == "" || !errors.Is(err, repository.ErrNotFound) {
+			return
+		}
+
+		ttl = r.negativeTTL
+	}
+
+	if ttl <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if generation != r.generation {
+		return
+	}
+
+	if el, ok := r.entries[key]; ok {
+		r.remove(el)
+	}
+
+	r.entries[key] = r.lru.PushFront(&entry{
+		key:     key,
+		id:      id,
+		res:     res,
+		err:     err,
+		expires: time.Now().Add(ttl),
+	})
+
+	for r.lru.Len() > r.size {
+		r.remove(r.lru.Back())
+		r.evictions.Add(1)
+	}
+}
+
+// invalidate drops the entries of the taxonomies with the IDs and every page
+func (r *Repository) invalidate(ids ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+
+	for el := r.lru.Front(); el != nil; {
+		next := el.Next()
+
+		if e := el.Value.(*entry); e.id == "" || slices.Contains(ids, e.id) {
+			r.remove(el)
+		}
+
+		el = next
+	}
+}
+
+func (r *Repository) remove(el *list.Element) {
+	r.lru.Remove(el)
+	delete(r.entries, el.Value.(*entry).key)
+}
+
+// keyOf the key of the entry of the read of the query, built from the fields
+// a read depends on
+func keyOf(query taxonomy.Query) (string, error) {
+	key, err := json.Marshal(struct {
+		ID             string
+		IncludeDeleted bool
+		AsOf           time.Time
+		Limit          uint
+		Offset         uint
+		Cursor         string
+		TotalMode      repository.TotalMode
+		Filter         filter.Expr
+		Sort           []filter.Sort
+	}{
+		ID:             query.Taxonomy.ID,
+		IncludeDeleted: query.IncludeDeleted,
+		AsOf:           query.AsOf,
+		Limit:          query.Pagination.Limit,
+		Offset:         query.Pagination.Offset,
+		Cursor:         query.Pagination.Cursor,
+		TotalMode:      query.Pagination.TotalMode,
+		Filter:         query.Filter,
+		Sort:           query.Sort,
+	})
+
+	return string(key), err
+}
+
+// clone copies the results of res, so callers can not alter the cached ones
+func clone(res taxonomy.Response) taxonomy.Response {
+	res.Results = slices.Clone(res.Results)
+
+	return res
+}
@@ -0,0 +1,446 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+)
+
+// fakeRepository an inner repository counting its reads, reads wait for
+// release when it is set and signal started first
+type fakeRepository struct {
+	mu         sync.Mutex
+	reads      int
+	taxonomies map[string]model.Taxonomy
+	err        error
+
+	started chan struct{}
+	release chan struct{}
+}
+
+func newFakeRepository(taxonomies ...model.Taxonomy) *fakeRepository {
+	f := &fakeRepository{
+		taxonomies: map[string]model.Taxonomy{},
+	}
+
+	for _, t := range taxonomies {
+		f.taxonomies[t.ID] = t
+	}
+
+	return f
+}
+
+func (f *fakeRepository) Create(_ context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	f.set(query.Taxonomy)
+
+	return taxonomy.NewResponse(taxonomy.ResponseWithResult(query.Taxonomy)), nil
+}
+
+func (f *fakeRepository) Read(_ context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	f.mu.Lock()
+	f.reads++
+	started, release := f.started, f.release
+	f.mu.Unlock()
+
+	if started != nil {
+		started <- struct{}{}
+	}
+
+	if release != nil {
+		<-release
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return taxonomy.Response{}, f.err
+	}
+
+	if query.Taxonomy.ID == "" {
+		res := taxonomy.NewResponse()
+		for _, t := range f.taxonomies {
+			res.Results = append(res.Results, t)
+		}
+
+		return res, nil
+	}
+
+	t, ok := f.taxonomies[query.Taxonomy.ID]
+	if !ok {
+		return taxonomy.Response{}, repository.ErrNotFound
+	}
+
+	return taxonomy.NewResponse(taxonomy.ResponseWithResult(t)), nil
+}
+
+func (f *fakeRepository) Update(_ context.Context, query taxonomy.Query) error {
+	f.set(query.Taxonomy)
+
+	return nil
+}
+
+func (f *fakeRepository) Delete(_ context.Context, query taxonomy.Query) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.taxonomies, query.Taxonomy.ID)
+
+	return nil
+}
+
+func (f *fakeRepository) set(t model.Taxonomy) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.taxonomies[t.ID] = t
+}
+
+func (f *fakeRepository) readCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.reads
+}
+
+func byID(id string) taxonomy.Query {
+	return taxonomy.Query{Taxonomy: model.Taxonomy{ID: id}}
+}
+
+func name(t *testing.T, r *Repository, id string) string {
+	t.Helper()
+
+	res, err := r.Read(context.Background(), byID(id))
+	if err != nil {
+		t.Fatalf("Read(%s) error = %v", id, err)
+	}
+
+	return res.Results[0].Name
+}
+
+func TestRead(t *testing.T) {
+	inner := newFakeRepository(model.Taxonomy{ID: "1", Name: "a"})
+	r := NewRepository(RepositoryWithInnerRepository(inner))
+
+	name(t, r, "1")
+	name(t, r, "1")
+
+	if got := inner.readCount(); got != 1 {
+		t.Errorf("inner reads = %d, want 1", got)
+	}
+
+	want := Stats{Hits: 1, Misses: 1, Entries: 1}
+	if got := r.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadClone(t *testing.T) {
+	inner := newFakeRepository(model.Taxonomy{ID: "1", Name: "a"})
+	r := NewRepository(RepositoryWithInnerRepository(inner))
+
+	res, err := r.Read(context.Background(), byID("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Results[0].Name = "altered"
+
+	if got := name(t, r, "1"); got != "a" {
+		t.Errorf("cached name = %q, want a", got)
+	}
+}
+
+func TestEviction(t *testing.T) {
+	inner := newFakeRepository(
+		model.Taxonomy{ID: "1", Name: "a"},
+		model.Taxonomy{ID: "2", Name: "b"},
+		model.Taxonomy{ID: "3", Name: "c"},
+	)
+	r := NewRepository(
+		RepositoryWithInnerRepository(inner),
+		RepositoryWithSize(2),
+	)
+
+	name(t, r, "1")
+	name(t, r, "2")
+	// 1 is used more recently than 2, which is evicted for 3
+	name(t, r, "1")
+	name(t, r, "3")
+
+	if got := r.Stats(); got.Evictions != 1 || got.Entries != 2 {
+		t.Errorf("Stats() = %+v, want 1 eviction and 2 entries", got)
+	}
+
+	name(t, r, "1")
+
+	if got := inner.readCount(); got != 3 {
+		t.Errorf("inner reads = %d, want 3", got)
+	}
+
+	name(t, r, "2")
+
+	if got := inner.readCount(); got != 4 {
+		t.Errorf("inner reads = %d, want 4", got)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	inner := newFakeRepository(model.Taxonomy{ID: "1", Name: "a"})
+	r := NewRepository(
+		RepositoryWithInnerRepository(inner),
+		RepositoryWithTTL(10*time.Millisecond),
+	)
+
+	name(t, r, "1")
+	time.Sleep(20 * time.Millisecond)
+	name(t, r, "1")
+
+	if got := inner.readCount(); got != 2 {
+		t.Errorf("inner reads = %d, want 2", got)
+	}
+
+	if got := r.Stats().Entries; got != 1 {
+		t.Errorf("entries = %d, want 1", got)
+	}
+}
+
+func TestNegativeTTL(t *testing.T) {
+	tests := []struct {
+		name        string
+		negativeTTL time.Duration
+		err         error
+		want        int
+	}{
+		{"not found", time.Minute, nil, 1},
+		{"negative caching disabled", 0, nil, 2},
+		{"other errors", time.Minute, errors.New("connection reset"), 2},
+	}
+
+	for _, tt := range tests {
+		inner := newFakeRepository()
+		inner.err = tt.err
+
+		r := NewRepository(
+			RepositoryWithInnerRepository(inner),
+			RepositoryWithNegativeTTL(tt.negativeTTL),
+		)
+
+		for range 2 {
+			if _, err := r.Read(context.Background(), byID("1")); err == nil {
+				t.Fatalf("%s: Read() error = nil", tt.name)
+			}
+		}
+
+		if got := inner.readCount(); got != tt.want {
+			t.Errorf("%s: inner reads = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNegativeTTLExpiry(t *testing.T) {
+	inner := newFakeRepository()
+	r := NewRepository(
+		RepositoryWithInnerRepository(inner),
+		RepositoryWithNegativeTTL(10*time.Millisecond),
+	)
+
+	if _, err := r.Read(context.Background(), byID("1")); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Read() error = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	inner.set(model.Taxonomy{ID: "1", Name: "a"})
+	time.Sleep(20 * time.Millisecond)
+
+	if got := name(t, r, "1"); got != "a" {
+		t.Errorf("name = %q, want a", got)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	ctx := context.Background()
+
+	inner := newFakeRepository(
+		model.Taxonomy{ID: "1", Name: "a"},
+		model.Taxonomy{ID: "2", Name: "b"},
+	)
+	r := NewRepository(RepositoryWithInnerRepository(inner))
+
+	name(t, r, "1")
+	name(t, r, "2")
+
+	if _, err := r.Read(ctx, taxonomy.Query{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Update(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: "1", Name: "c"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the taxonomy written and the page are dropped, 2 is kept
+	if got := r.Stats().Entries; got != 1 {
+		t.Errorf("entries = %d, want 1", got)
+	}
+
+	if got := name(t, r, "1"); got != "c" {
+		t.Errorf("name = %q, want c", got)
+	}
+
+	// a write bypassing the cache is seen once invalidated
+	inner.set(model.Taxonomy{ID: "2", Name: "d"})
+
+	if got := name(t, r, "2"); got != "b" {
+		t.Errorf("name = %q, want the cached b", got)
+	}
+
+	r.Invalidate("2")
+
+	if got := name(t, r, "2"); got != "d" {
+		t.Errorf("name = %q, want d", got)
+	}
+}
+
+func TestReadShared(t *testing.T) {
+	const readers = 8
+
+	inner := newFakeRepository(model.Taxonomy{ID: "1", Name: "a"})
+	inner.started = make(chan struct{}, readers)
+	inner.release = make(chan struct{})
+
+	r := NewRepository(RepositoryWithInnerRepository(inner))
+
+	wg := sync.WaitGroup{}
+
+	for range readers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if _, err := r.Read(context.Background(), byID("1")); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	<-inner.started
+
+	// the readers joining the read of the inner repository are counted as
+	// misses before they join it
+	for r.Stats().Misses < readers {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	close(inner.release)
+	wg.Wait()
+
+	if got := inner.readCount(); got != 1 {
+		t.Errorf("inner reads = %d, want 1", got)
+	}
+}
+
+func TestReadAcrossWrite(t *testing.T) {
+	ctx := context.Background()
+
+	inner := newFakeRepository(model.Taxonomy{ID: "1", Name: "a"})
+	inner.started = make(chan struct{})
+	inner.release = make(chan struct{})
+
+	r := NewRepository(RepositoryWithInnerRepository(inner))
+
+	read := func(names chan<- string) {
+		res, err := r.Read(ctx, byID("1"))
+		if err != nil {
+			names <- err.Error()
+			return
+		}
+
+		names <- res.Results[0].Name
+	}
+
+	stale := make(chan string)
+
+	go read(stale)
+
+	<-inner.started
+
+	if err := r.Update(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: "1", Name: "b"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a read following the write does not join the read preceding it
+	fresh := make(chan string)
+
+	go read(fresh)
+
+	<-inner.started
+
+	close(inner.release)
+
+	<-stale
+
+	if got := <-fresh; got != "b" {
+		t.Errorf("name = %q, want b", got)
+	}
+
+	inner.mu.Lock()
+	inner.started, inner.release = nil, nil
+	inner.mu.Unlock()
+
+	// the read preceding the write was not cached
+	if got := name(t, r, "1"); got != "b" {
+		t.Errorf("cached name = %q, want b", got)
+	}
+
+	if got := inner.readCount(); got != 2 {
+		t.Errorf("inner reads = %d, want 2", got)
+	}
+}
+
+func TestReadCancelled(t *testing.T) {
+	inner := newFakeRepository(model.Taxonomy{ID: "1", Name: "a"})
+	inner.started = make(chan struct{}, 1)
+	inner.release = make(chan struct{})
+
+	r := NewRepository(RepositoryWithInnerRepository(inner))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+
+	go func() {
+		_, err := r.Read(ctx, byID("1"))
+		done <- err
+	}()
+
+	<-inner.started
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Read() error = %v, want %v", err, context.Canceled)
+	}
+
+	close(inner.release)
+
+	// the shared read outlives the reader and is cached
+	for r.Stats().Entries == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	inner.mu.Lock()
+	inner.started, inner.release = nil, nil
+	inner.mu.Unlock()
+
+	name(t, r, "1")
+
+	if got := inner.readCount(); got != 1 {
+		t.Errorf("inner reads = %d, want 1", got)
+	}
+}
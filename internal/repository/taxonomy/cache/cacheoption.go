@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+)
+
+func RepositoryWithInnerRepository(inner taxonomy.Repository) RepositoryOption {
+	return func(r *Repository) {
+		r.inner = inner
+	}
+}
+
+// RepositoryWithSize sets the most reads cached, the least recently used are
+// evicted first, defaults to 1024
+func RepositoryWithSize(n int) RepositoryOption {
+	return func(r *Repository) {
+		r.size = n
+	}
+}
+
+// RepositoryWithTTL sets how long reads are cached, defaults to a minute
+func RepositoryWithTTL(d time.Duration) RepositoryOption {
+	return func(r *Repository) {
+		r.ttl = d
+	}
+}
+
+// RepositoryWithNegativeTTL sets how long taxonomies found not to exist are
+// cached, zero disables negative caching, defaults to 5 seconds
+func RepositoryWithNegativeTTL(d time.Duration) RepositoryOption {
+	return func(r *Repository) {
+		r.negativeTTL = d
+	}
+}
@@ -0,0 +1,139 @@
+// Package feed delivers the changes of the taxonomies to consumers, polling
+// the change log of a taxonomy.FeedRepository, a change is delivered at least
+// once, consumers saving checkpoints resume after the last change they
+// processed
+package feed
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	"github.com/olireadcopper/sqlxprototype/pkg/telemetry/logging"
+)
+
+const (
+	defaultPollInterval = time.Second
+	defaultBatchSize    = 100
+	// maxBackoff the longest wait between the reads of a failing log
+	maxBackoff = 30 * time.Second
+)
+
+type Feed struct {
+	changes  taxonomy.FeedRepository
+	interval time.Duration
+	batch    uint
+	logger   *slog.Logger
+}
+
+type FeedOption func(*Feed)
+
+// NewFeed constructor for a feed of the changes of the taxonomies, the
+// repository the changes are read from must be provided as an option
+func NewFeed(opts ...FeedOption) *Feed {
+	f := &Feed{
+		interval: defaultPollInterval,
+		batch:    defaultBatchSize,
+		logger:   logging.NopLogger,
+	}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	// subscriptions would spin without a batch or an interval
+	if f.batch == 0 {
+		f.batch = defaultBatchSize
+	}
+
+	if f.interval <= 0 {
+		f.interval = defaultPollInterval
+	}
+
+	return f
+}
+
+// Subscribe delivers the changes following the sequence number after, 0
+// delivers every change, the channel is closed once ctx is done, failures to
+// read the log are logged and retried
+func (f *Feed) Subscribe(ctx context.Context, after uint64) <-chan taxonomy.Event {
+	events := make(chan taxonomy.Event, f.batch)
+
+	go func() {
+		defer close(events)
+
+		wait := f.interval
+
+		for {
+			changes, err := f.changes.Changes(ctx, after, f.batch)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				f.logger.ErrorContext(ctx, err.Error(), "after", after)
+
+				wait = min(2*wait, maxBackoff)
+
+				if !sleep(ctx, wait) {
+					return
+				}
+
+				continue
+			}
+
+			wait = f.interval
+
+			for _, e := range changes {
+				select {
+				case events <- e:
+					after = e.Seq
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			// a full batch is followed by more changes
+			if uint(len(changes)) == f.batch {
+				continue
+			}
+
+			if !sleep(ctx, f.interval) {
+				return
+			}
+		}
+	}()
+
+	return events
+}
+
+// Resume delivers the changes following the checkpoint of the consumer, the
+// changes delivered since its last checkpoint are delivered again
+func (f *Feed) Resume(ctx context.Context, consumer string) (<-chan taxonomy.Event, error) {
+	after, err := f.changes.Checkpoint(ctx, consumer)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.Subscribe(ctx, after), nil
+}
+
+// Commit saves the sequence number of the last change the consumer processed,
+// consumers commit after processing a change so it is never skipped
+func (f *Feed) Commit(ctx context.Context, consumer string, seq uint64) error {
+	return f.changes.SaveCheckpoint(ctx, consumer, seq)
+}
+
+// sleep waits for d, reporting false when ctx is done first
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
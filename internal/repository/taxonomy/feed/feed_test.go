@@ -0,0 +1,237 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	taxonomysql "github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy/sql"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/migrate"
+)
+
+// newRepository returns an SQLite repository over a migrated database in a
+// temporary file, without the search index when SQLite lacks FTS5
+func newRepository(t *testing.T) *taxonomysql.SQLiteRepository {
+	t.Helper()
+
+	ctx := context.Background()
+
+	db, err := sqlx.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "taxonomy.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	searchable, err := taxonomysql.SQLiteSupportsSearch(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := []migrate.MigratorOption{
+		migrate.MigratorWithDB(db),
+		migrate.MigratorWithFS(taxonomysql.SQLiteMigrations()),
+	}
+
+	if !searchable {
+		opts = append(opts, migrate.MigratorWithSkip(taxonomysql.SQLiteSearchMigration))
+	}
+
+	if err := migrate.NewMigrator(opts...).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	return taxonomysql.NewSQLiteRepository(taxonomysql.SQLiteWithDB(db))
+}
+
+// create creates root taxonomies with the names
+func create(t *testing.T, r taxonomy.Repository, names ...string) {
+	t.Helper()
+
+	for _, name := range names {
+		if _, err := r.Create(context.Background(), taxonomy.Query{Taxonomy: model.Taxonomy{Name: name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// receive receives n events, failing the test when they take too long
+func receive(t *testing.T, events <-chan taxonomy.Event, n int) []taxonomy.Event {
+	t.Helper()
+
+	received := []taxonomy.Event{}
+	timeout := time.After(5 * time.Second)
+
+	for len(received) < n {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatalf("events closed after %d events, want %d", len(received), n)
+			}
+
+			received = append(received, e)
+		case <-timeout:
+			t.Fatalf("received %d events, want %d", len(received), n)
+		}
+	}
+
+	return received
+}
+
+// seqs returns the sequence numbers of the events
+func seqs(events []taxonomy.Event) []uint64 {
+	s := make([]uint64, len(events))
+	for i, e := range events {
+		s[i] = e.Seq
+	}
+
+	return s
+}
+
+func TestResume(t *testing.T) {
+	ctx := context.Background()
+	r := newRepository(t)
+
+	f := NewFeed(
+		FeedWithRepository(r),
+		FeedWithPollInterval(time.Millisecond),
+	)
+
+	create(t, r, "a", "b", "c")
+
+	subscription, cancel := context.WithCancel(ctx)
+
+	events, err := f.Resume(subscription, "consumer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := receive(t, events, 3)
+
+	if got, want := seqs(received), []uint64{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("first subscription = %v, want %v", got, want)
+	}
+
+	if e := received[0]; e.Operation != taxonomy.OperationCreate || e.Taxonomy == nil || e.Taxonomy.Name != "a" {
+		t.Errorf("first event = %+v, want the creation of a", e)
+	}
+
+	// changes made while subscribed are delivered once committed
+	create(t, r, "d")
+
+	if got := seqs(receive(t, events, 1)); !slices.Equal(got, []uint64{4}) {
+		t.Errorf("change made while subscribed = %v, want [4]", got)
+	}
+
+	// only the first change was processed when the consumer stopped
+	if err := f.Commit(ctx, "consumer", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	for range events {
+	}
+
+	events, err = f.Resume(ctx, "consumer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := seqs(receive(t, events, 3)), []uint64{2, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("resumed subscription = %v, want %v redelivered", got, want)
+	}
+
+	// checkpoints never move backward
+	if err := f.Commit(ctx, "consumer", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Commit(ctx, "consumer", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if seq, err := r.Checkpoint(ctx, "consumer"); err != nil || seq != 3 {
+		t.Errorf("Checkpoint() = %d %v, want 3", seq, err)
+	}
+}
+
+func TestSubscribeFullBatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := newRepository(t)
+
+	// the interval is never waited as long as the batches are full
+	f := NewFeed(
+		FeedWithRepository(r),
+		FeedWithBatchSize(2),
+		FeedWithPollInterval(time.Hour),
+	)
+
+	create(t, r, "a", "b", "c", "d", "e")
+
+	if got, want := seqs(receive(t, f.Subscribe(ctx, 0), 5)), []uint64{1, 2, 3, 4, 5}; !slices.Equal(got, want) {
+		t.Errorf("Subscribe() = %v, want %v", got, want)
+	}
+}
+
+// failingRepository a feed repository failing its first reads
+type failingRepository struct {
+	taxonomy.FeedRepository
+	mu       sync.Mutex
+	failures int
+	reads    []time.Time
+}
+
+func (r *failingRepository) Changes(ctx context.Context, after uint64, limit uint) ([]taxonomy.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reads = append(r.reads, time.Now())
+
+	if len(r.reads) <= r.failures {
+		return nil, errors.New("database is locked")
+	}
+
+	if after > 0 {
+		return nil, nil
+	}
+
+	return []taxonomy.Event{{Seq: 1}}, nil
+}
+
+func TestSubscribeBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := &failingRepository{failures: 3}
+	interval := 10 * time.Millisecond
+
+	f := NewFeed(
+		FeedWithRepository(r),
+		FeedWithPollInterval(interval),
+	)
+
+	if got := seqs(receive(t, f.Subscribe(ctx, 0), 1)); !slices.Equal(got, []uint64{1}) {
+		t.Errorf("Subscribe() = %v, want [1] once the reads succeed", got)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// each failure doubles the wait before the next read
+	for i, want := range []time.Duration{2 * interval, 4 * interval, 8 * interval} {
+		if got := r.reads[i+1].Sub(r.reads[i]); got < want {
+			t.Errorf("wait after failure %d = %v, want at least %v", i+1, got, want)
+		}
+	}
+}
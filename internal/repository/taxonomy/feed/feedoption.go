@@ -0,0 +1,39 @@
+package feed
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	"github.com/olireadcopper/sqlxprototype/pkg/telemetry/logging"
+)
+
+func FeedWithRepository(r taxonomy.FeedRepository) FeedOption {
+	return func(f *Feed) {
+		f.changes = r
+	}
+}
+
+// FeedWithPollInterval sets how long subscriptions wait for new changes once
+// they delivered every change of the log
+func FeedWithPollInterval(d time.Duration) FeedOption {
+	return func(f *Feed) {
+		f.interval = d
+	}
+}
+
+// FeedWithBatchSize sets the most changes read from the log at once, also the
+// capacity of the channels of subscriptions
+func FeedWithBatchSize(n uint) FeedOption {
+	return func(f *Feed) {
+		f.batch = n
+	}
+}
+
+func FeedWithLogger(l *slog.Logger) FeedOption {
+	return func(f *Feed) {
+		f.logger = l.With(
+			logging.FieldComponent, "feed",
+		)
+	}
+}
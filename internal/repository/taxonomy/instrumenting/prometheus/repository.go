@@ -0,0 +1,184 @@
+// Package prometheus decorates a taxonomy.Repository with metrics of its
+// operations, counting the errors of each by their repository.Code
+package prometheus
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	kryptonsqlxprometheus "github.com/olireadcopper/sqlxprototype/pkg/sqlx/instrumenting/prometheus"
+)
+
+const defaultNamespace = "copper"
+
+// resultBuckets the buckets of the result count histogram, from a single
+// taxonomy up to the largest pages
+var resultBuckets = prometheus.ExponentialBuckets(1, 4, 7)
+
+// Outcomes recorded in the outcome label of the metrics, errors caused by the
+// query, such as a missing taxonomy, are client errors
+const (
+	OutcomeOK          = "ok"
+	OutcomeClientError = "client_error"
+	OutcomeError       = "error"
+)
+
+type Repository struct {
+	inner       taxonomy.Repository
+	registerer  prometheus.Registerer
+	namespace   string
+	subsystem   string
+	constLabels prometheus.Labels
+	buckets     []float64
+
+	count    *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	results  *prometheus.HistogramVec
+}
+
+type RepositoryOption func(*Repository)
+
+func NewRepository(opts ...RepositoryOption) taxonomy.Repository {
+	r := &Repository{
+		registerer: prometheus.DefaultRegisterer,
+		namespace:  defaultNamespace,
+		buckets:    prometheus.DefBuckets,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	r.count = kryptonsqlxprometheus.Register(r.registerer, prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   r.namespace,
+			Subsystem:   r.subsystem,
+			Name:        "taxonomy_operations_total",
+			Help:        "Number of operations on taxonomies",
+			ConstLabels: r.constLabels,
+		},
+		[]string{"operation", "outcome"},
+	))
+	r.errors = kryptonsqlxprometheus.Register(r.registerer, prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   r.namespace,
+			Subsystem:   r.subsystem,
+			Name:        "taxonomy_operation_errors_total",
+			Help:        "Number of errors from operations on taxonomies",
+			ConstLabels: r.constLabels,
+		},
+		[]string{"operation", "code"},
+	))
+	r.duration = kryptonsqlxprometheus.Register(r.registerer, prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   r.namespace,
+			Subsystem:   r.subsystem,
+			Name:        "taxonomy_operation_duration_seconds",
+			Help:        "Duration of operations on taxonomies, measured in seconds",
+			ConstLabels: r.constLabels,
+			Buckets:     r.buckets,
+		},
+		[]string{"operation", "outcome"},
+	))
+	r.results = kryptonsqlxprometheus.Register(r.registerer, prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   r.namespace,
+			Subsystem:   r.subsystem,
+			Name:        "taxonomy_operation_results",
+			Help:        "Number of taxonomies returned by successful operations",
+			ConstLabels: r.constLabels,
+			Buckets:     resultBuckets,
+		},
+		[]string{"operation"},
+	))
+
+	return r
+}
+
+// Create prometheus instrumentation implementation for a taxonomy repository
+func (r *Repository) Create(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	begin := time.Now()
+
+	res, err := r.inner.Create(ctx, query)
+
+	r.observe(ctx, "create", begin, err)
+	r.observeResults(ctx, "create", res, err)
+
+	return res, err
+}
+
+// Read prometheus instrumentation implementation for a taxonomy repository
+func (r *Repository) Read(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	begin := time.Now()
+
+	res, err := r.inner.Read(ctx, query)
+
+	r.observe(ctx, "read", begin, err)
+	r.observeResults(ctx, "read", res, err)
+
+	return res, err
+}
+
+// Update prometheus instrumentation implementation for a taxonomy repository
+func (r *Repository) Update(ctx context.Context, query taxonomy.Query) error {
+	begin := time.Now()
+
+	err := r.inner.Update(ctx, query)
+
+	r.observe(ctx, "update", begin, err)
+
+	return err
+}
+
+// Delete prometheus instrumentation implementation for a taxonomy repository
+func (r *Repository) Delete(ctx context.Context, query taxonomy.Query) error {
+	begin := time.Now()
+
+	err := r.inner.Delete(ctx, query)
+
+	r.observe(ctx, "delete", begin, err)
+
+	return err
+}
+
+func (r *Repository) observe(ctx context.Context, operation string, begin time.Time, err error) {
+	outcome := OutcomeOK
+
+	switch {
+	case err == nil:
+	case repository.IsClientError(err):
+		outcome = OutcomeClientError
+	default:
+		outcome = OutcomeError
+	}
+
+	labels := prometheus.Labels{
+		"operation": operation,
+		"outcome":   outcome,
+	}
+
+	kryptonsqlxprometheus.Inc(ctx, r.count.With(labels))
+	kryptonsqlxprometheus.Observe(ctx, r.duration.With(labels), time.Since(begin).Seconds())
+
+	if err != nil {
+		kryptonsqlxprometheus.Inc(ctx, r.errors.With(prometheus.Labels{
+			"operation": operation,
+			"code":      repository.Code(err),
+		}))
+	}
+}
+
+func (r *Repository) observeResults(ctx context.Context, operation string, res taxonomy.Response, err error) {
+	if err != nil {
+		return
+	}
+
+	kryptonsqlxprometheus.Observe(ctx, r.results.With(prometheus.Labels{
+		"operation": operation,
+	}), float64(len(res.Results)))
+}
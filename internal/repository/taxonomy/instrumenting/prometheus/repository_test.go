@@ -0,0 +1,134 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	"github.com/olireadcopper/sqlxprototype/pkg/telemetry"
+)
+
+// stubRepository a repository whose operations all return res and err
+type stubRepository struct {
+	res taxonomy.Response
+	err error
+}
+
+func (r stubRepository) Create(context.Context, taxonomy.Query) (taxonomy.Response, error) {
+	return r.res, r.err
+}
+
+func (r stubRepository) Read(context.Context, taxonomy.Query) (taxonomy.Response, error) {
+	return r.res, r.err
+}
+
+func (r stubRepository) Update(context.Context, taxonomy.Query) error {
+	return r.err
+}
+
+func (r stubRepository) Delete(context.Context, taxonomy.Query) error {
+	return r.err
+}
+
+// histogram returns the sample of the histogram with the labels
+func histogram(t *testing.T, h *prometheus.HistogramVec, labels prometheus.Labels) *dto.Histogram {
+	t.Helper()
+
+	m := &dto.Metric{}
+	if err := h.With(labels).(prometheus.Metric).Write(m); err != nil {
+		t.Fatal(err)
+	}
+
+	return m.GetHistogram()
+}
+
+func TestObserve(t *testing.T) {
+	ctx := telemetry.ContextWithTrace(context.Background(), "abc")
+	serverErr := errors.New("disk I/O error")
+
+	tests := []struct {
+		name    string
+		err     error
+		outcome string
+	}{
+		{"ok", nil, OutcomeOK},
+		{"client error", repository.ErrNotFound, OutcomeClientError},
+		{"server error", serverErr, OutcomeError},
+	}
+
+	for _, tt := range tests {
+		r := NewRepository(
+			RepositoryWithInnerRepository(stubRepository{
+				res: taxonomy.Response{Results: []model.Taxonomy{{ID: "1"}, {ID: "2"}}},
+				err: tt.err,
+			}),
+			RepositoryWithRegisterer(prometheus.NewRegistry()),
+		).(*Repository)
+
+		_, _ = r.Read(ctx, taxonomy.Query{})
+
+		labels := prometheus.Labels{"operation": "read", "outcome": tt.outcome}
+
+		if got := testutil.ToFloat64(r.count.With(labels)); got != 1 {
+			t.Errorf("%s: taxonomy_operations_total = %v, want 1", tt.name, got)
+		}
+
+		if got := testutil.CollectAndCount(r.count); got != 1 {
+			t.Errorf("%s: %d series of taxonomy_operations_total, want only the %s one", tt.name, got, tt.outcome)
+		}
+
+		if got := histogram(t, r.duration, labels).GetSampleCount(); got != 1 {
+			t.Errorf("%s: taxonomy_operation_duration_seconds count = %v, want 1", tt.name, got)
+		}
+
+		// the errors are counted by code and the results of successes only
+		wantErrors := 0
+		if tt.err != nil {
+			wantErrors = 1
+
+			code := prometheus.Labels{"operation": "read", "code": repository.Code(tt.err)}
+			if got := testutil.ToFloat64(r.errors.With(code)); got != 1 {
+				t.Errorf("%s: taxonomy_operation_errors_total%v = %v, want 1", tt.name, code, got)
+			}
+		}
+
+		if got := testutil.CollectAndCount(r.errors); got != wantErrors {
+			t.Errorf("%s: %d series of taxonomy_operation_errors_total, want %d", tt.name, got, wantErrors)
+		}
+
+		results := histogram(t, r.results, prometheus.Labels{"operation": "read"})
+
+		if wantCount, wantSum := uint64(1-wantErrors), float64(2*(1-wantErrors)); results.GetSampleCount() != wantCount || results.GetSampleSum() != wantSum {
+			t.Errorf("%s: taxonomy_operation_results = %d samples summing %v, want %d summing %v",
+				tt.name, results.GetSampleCount(), results.GetSampleSum(), wantCount, wantSum)
+		}
+	}
+}
+
+func TestObserveExemplar(t *testing.T) {
+	ctx := telemetry.ContextWithTrace(context.Background(), "abc")
+
+	r := NewRepository(
+		RepositoryWithInnerRepository(stubRepository{}),
+		RepositoryWithRegisterer(prometheus.NewRegistry()),
+	).(*Repository)
+
+	_ = r.Update(ctx, taxonomy.Query{})
+
+	m := &dto.Metric{}
+	if err := r.count.With(prometheus.Labels{"operation": "update", "outcome": OutcomeOK}).Write(m); err != nil {
+		t.Fatal(err)
+	}
+
+	labels := m.GetCounter().GetExemplar().GetLabel()
+	if len(labels) != 1 || labels[0].GetValue() != "abc" {
+		t.Errorf("exemplar = %v, want the trace abc", labels)
+	}
+}
@@ -0,0 +1,51 @@
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+)
+
+func RepositoryWithInnerRepository(inner taxonomy.Repository) RepositoryOption {
+	return func(r *Repository) {
+		r.inner = inner
+	}
+}
+
+// RepositoryWithRegisterer sets the registerer the metrics are registered
+// with, defaults to prometheus.DefaultRegisterer
+func RepositoryWithRegisterer(reg prometheus.Registerer) RepositoryOption {
+	return func(r *Repository) {
+		r.registerer = reg
+	}
+}
+
+// RepositoryWithNamespace sets the namespace of the metrics, defaults to
+// "copper"
+func RepositoryWithNamespace(namespace string) RepositoryOption {
+	return func(r *Repository) {
+		r.namespace = namespace
+	}
+}
+
+// RepositoryWithSubsystem sets the subsystem of the metrics
+func RepositoryWithSubsystem(subsystem string) RepositoryOption {
+	return func(r *Repository) {
+		r.subsystem = subsystem
+	}
+}
+
+// RepositoryWithConstLabels sets labels attached to every metric
+func RepositoryWithConstLabels(labels prometheus.Labels) RepositoryOption {
+	return func(r *Repository) {
+		r.constLabels = labels
+	}
+}
+
+// RepositoryWithBuckets sets the buckets of the duration histogram, measured
+// in seconds, defaults to prometheus.DefBuckets
+func RepositoryWithBuckets(buckets ...float64) RepositoryOption {
+	return func(r *Repository) {
+		r.buckets = buckets
+	}
+}
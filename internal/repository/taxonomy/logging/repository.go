@@ -0,0 +1,160 @@
+// Package logging decorates a taxonomy.Repository with a log record of every
+// operation, unlike the sqlx logging decorator which logs each query the
+// operation runs
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	"github.com/olireadcopper/sqlxprototype/pkg/telemetry"
+	"github.com/olireadcopper/sqlxprototype/pkg/telemetry/logging"
+)
+
+type Repository struct {
+	inner  taxonomy.Repository
+	logger *slog.Logger
+	level  slog.Leveler
+}
+
+type RepositoryOption func(*Repository)
+
+func NewRepository(opts ...RepositoryOption) taxonomy.Repository {
+	r := &Repository{
+		logger: logging.NopLogger,
+		level:  slog.LevelInfo,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Create logging implementation for a taxonomy repository
+func (r *Repository) Create(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	begin := time.Now()
+
+	res, err := r.inner.Create(ctx, query)
+
+	args := queryAttrs(query)
+	if len(res.Results) == 1 {
+		args = append(args, logging.FieldID, res.Results[0].ID)
+	}
+
+	r.log(ctx, "Create", err, append(args,
+		logging.FieldDuration, time.Since(begin),
+		logging.FieldCount, len(res.Results),
+	)...)
+
+	return res, err
+}
+
+// Read logging implementation for a taxonomy repository
+func (r *Repository) Read(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	begin := time.Now()
+
+	res, err := r.inner.Read(ctx, query)
+
+	args := append(queryAttrs(query),
+		logging.FieldDuration, time.Since(begin),
+		logging.FieldCount, len(res.Results),
+	)
+
+	if res.Pagination.Total != nil {
+		args = append(args, logging.FieldTotal, *res.Pagination.Total)
+	}
+
+	r.log(ctx, "Read", err, args...)
+
+	return res, err
+}
+
+// Update logging implementation for a taxonomy repository
+func (r *Repository) Update(ctx context.Context, query taxonomy.Query) error {
+	begin := time.Now()
+
+	err := r.inner.Update(ctx, query)
+
+	r.log(ctx, "Update", err, append(queryAttrs(query),
+		logging.FieldDuration, time.Since(begin),
+	)...)
+
+	return err
+}
+
+// Delete logging implementation for a taxonomy repository
+func (r *Repository) Delete(ctx context.Context, query taxonomy.Query) error {
+	begin := time.Now()
+
+	err := r.inner.Delete(ctx, query)
+
+	r.log(ctx, "Delete", err, append(queryAttrs(query),
+		logging.FieldDuration, time.Since(begin),
+	)...)
+
+	return err
+}
+
+// log writes the record of an operation, errors caused by the query, such as
+// a missing taxonomy, are logged as warnings and the others as errors
+func (r *Repository) log(ctx context.Context, method string, err error, args ...any) {
+	level := slog.LevelInfo
+
+	switch {
+	case err == nil:
+	case repository.IsClientError(err):
+		level = slog.LevelWarn
+	default:
+		level = slog.LevelError
+	}
+
+	if level < r.level.Level() || !r.logger.Enabled(ctx, level) {
+		return
+	}
+
+	args = append([]any{logging.FieldMethod, method}, args...)
+
+	if trace, ok := telemetry.TraceFromContext(ctx); ok {
+		args = append(args, logging.FieldTrace, trace)
+	}
+
+	msg := method
+
+	if err != nil {
+		msg = err.Error()
+		args = append(args, logging.FieldErrorCode, repository.Code(err))
+	}
+
+	r.logger.Log(ctx, level, msg, args...)
+}
+
+// queryAttrs the parameters of the query worth logging, the pagination is
+// only logged when set
+func queryAttrs(query taxonomy.Query) []any {
+	args := []any{}
+
+	if query.Taxonomy.ID != "" {
+		args = append(args, logging.FieldID, query.Taxonomy.ID)
+	}
+
+	p := query.Pagination
+
+	if p.Limit > 0 {
+		args = append(args, logging.FieldLimit, p.Limit)
+	}
+
+	if p.Offset > 0 {
+		args = append(args, logging.FieldOffset, p.Offset)
+	}
+
+	if p.Cursor != "" {
+		args = append(args, logging.FieldCursor, p.Cursor)
+	}
+
+	return args
+}
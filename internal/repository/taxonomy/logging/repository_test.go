@@ -0,0 +1,144 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	"github.com/olireadcopper/sqlxprototype/pkg/telemetry"
+	"github.com/olireadcopper/sqlxprototype/pkg/telemetry/logging"
+)
+
+// stubRepository a repository whose operations all return res and err
+type stubRepository struct {
+	res taxonomy.Response
+	err error
+}
+
+func (r stubRepository) Create(context.Context, taxonomy.Query) (taxonomy.Response, error) {
+	return r.res, r.err
+}
+
+func (r stubRepository) Read(context.Context, taxonomy.Query) (taxonomy.Response, error) {
+	return r.res, r.err
+}
+
+func (r stubRepository) Update(context.Context, taxonomy.Query) error {
+	return r.err
+}
+
+func (r stubRepository) Delete(context.Context, taxonomy.Query) error {
+	return r.err
+}
+
+// record runs op on a repository logging to a buffer and returns the records
+// written
+func record(t *testing.T, inner taxonomy.Repository, level slog.Level, op func(r taxonomy.Repository) error) []map[string]any {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+
+	r := NewRepository(
+		RepositoryWithInnerRepository(inner),
+		RepositoryWithLogger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+		RepositoryWithLevel(level),
+	)
+
+	_ = op(r)
+
+	records := []map[string]any{}
+
+	for dec := json.NewDecoder(buf); dec.More(); {
+		record := map[string]any{}
+		if err := dec.Decode(&record); err != nil {
+			t.Fatal(err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestLog(t *testing.T) {
+	ctx := telemetry.ContextWithTrace(context.Background(), "abc")
+	created := taxonomy.Response{Results: []model.Taxonomy{{ID: "1", Name: "a"}}}
+
+	tests := []struct {
+		name  string
+		err   error
+		level string
+		code  any
+	}{
+		{"ok", nil, "INFO", nil},
+		{"client error", repository.ErrNotFound, "WARN", "not_found"},
+		{"server error", errors.New("disk I/O error"), "ERROR", repository.Code(errors.New("disk I/O error"))},
+	}
+
+	for _, tt := range tests {
+		records := record(t, stubRepository{res: created, err: tt.err}, slog.LevelInfo, func(r taxonomy.Repository) error {
+			_, err := r.Create(ctx, taxonomy.Query{Pagination: repository.Pagination{Limit: 5}})
+			return err
+		})
+
+		if len(records) != 1 {
+			t.Fatalf("%s: %d records, want 1", tt.name, len(records))
+		}
+
+		got := records[0]
+
+		for field, want := range map[string]any{
+			"level":                tt.level,
+			logging.FieldMethod:    "Create",
+			logging.FieldComponent: "taxonomy",
+			logging.FieldTrace:     "abc",
+			logging.FieldErrorCode: tt.code,
+			logging.FieldID:        "1",
+			logging.FieldLimit:     float64(5),
+			logging.FieldCount:     float64(1),
+		} {
+			if got[field] != want {
+				t.Errorf("%s: %s = %v, want %v", tt.name, field, got[field], want)
+			}
+		}
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		err   error
+		level slog.Level
+		want  int
+	}{
+		{"ok below the level", nil, slog.LevelWarn, 0},
+		{"client error at the level", repository.ErrConflict, slog.LevelWarn, 1},
+		{"client error below the level", repository.ErrConflict, slog.LevelError, 0},
+		{"server error", errors.New("disk I/O error"), slog.LevelError, 1},
+	}
+
+	for _, tt := range tests {
+		records := record(t, stubRepository{err: tt.err}, tt.level, func(r taxonomy.Repository) error {
+			return r.Delete(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: "1"}})
+		})
+
+		if len(records) != tt.want {
+			t.Errorf("%s: %d records, want %d", tt.name, len(records), tt.want)
+		}
+
+		// the trace is only logged when the context carries one
+		for _, r := range records {
+			if _, ok := r[logging.FieldTrace]; ok {
+				t.Errorf("%s: trace = %v, want none", tt.name, r[logging.FieldTrace])
+			}
+		}
+	}
+}
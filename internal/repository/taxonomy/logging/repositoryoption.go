@@ -0,0 +1,30 @@
+package logging
+
+import (
+	"log/slog"
+
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	"github.com/olireadcopper/sqlxprototype/pkg/telemetry/logging"
+)
+
+func RepositoryWithInnerRepository(inner taxonomy.Repository) RepositoryOption {
+	return func(r *Repository) {
+		r.inner = inner
+	}
+}
+
+func RepositoryWithLogger(l *slog.Logger) RepositoryOption {
+	return func(r *Repository) {
+		r.logger = l.With(
+			logging.FieldComponent, "taxonomy",
+		)
+	}
+}
+
+// RepositoryWithLevel sets the minimum level an operation must be logged at
+// to be written, successful operations are logged at info
+func RepositoryWithLevel(l slog.Leveler) RepositoryOption {
+	return func(r *Repository) {
+		r.level = l
+	}
+}
@@ -0,0 +1,234 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/filter"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/nop"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the SQL statements")
+
+// recorder a database recording the statements run on it and in its
+// transactions, every statement succeeds affecting a row, reads of booleans
+// are true but for the cycle checks of moves and reads of the hierarchy
+// return a single node
+type recorder struct {
+	*nop.Nop
+	statements []string
+}
+
+func (r *recorder) record(query string, args []any) {
+	for i, arg := range args {
+		args[i] = redact(arg)
+	}
+
+	r.statements = append(r.statements, strings.Join(strings.Fields(query), " "), fmt.Sprintf("-- %v", args))
+}
+
+// redact replaces the times of the arguments, which vary between runs
+func redact(arg any) any {
+	switch arg := arg.(type) {
+	case time.Time:
+		return "<time>"
+	case string:
+		for _, layout := range []string{historyTimeFormat, time.DateTime} {
+			if _, err := time.Parse(layout, arg); err == nil {
+				return "<time>"
+			}
+		}
+	case *string:
+		if arg != nil {
+			return *arg
+		}
+	case *uint64:
+		if arg != nil {
+			return *arg
+		}
+	}
+
+	return arg
+}
+
+func (r *recorder) BeginTransaction(context.Context, *sql.TxOptions) (kryptonsqlx.Tx, error) {
+	return &recorderTx{recorder: r}, nil
+}
+
+func (r *recorder) ExecContext(_ context.Context, query string, args ...any) (sql.Result, error) {
+	r.record(query, args)
+
+	return driver.RowsAffected(1), nil
+}
+
+func (r *recorder) GetContext(_ context.Context, dest any, query string, args ...any) error {
+	r.record(query, args)
+
+	switch dest := dest.(type) {
+	case *bool:
+		*dest = !strings.Contains(query, "descendants")
+	case *model.Taxonomy:
+		dest.ID = "1"
+	}
+
+	return nil
+}
+
+func (r *recorder) SelectContext(_ context.Context, dest any, query string, args ...any) error {
+	r.record(query, args)
+
+	if dest, ok := dest.(*[]node); ok {
+		*dest = append(*dest, node{Taxonomy: model.Taxonomy{ID: "1"}})
+	}
+
+	return nil
+}
+
+type recorderTx struct {
+	nop.Tx
+	recorder *recorder
+}
+
+func (tx *recorderTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tx.recorder.ExecContext(ctx, query, args...)
+}
+
+func (tx *recorderTx) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	return tx.recorder.GetContext(ctx, dest, query, args...)
+}
+
+func (tx *recorderTx) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	return tx.recorder.SelectContext(ctx, dest, query, args...)
+}
+
+// golden compares the statements to the golden file of the name, or rewrites
+// it with -update
+func golden(t *testing.T, name string, statements []string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden.sql")
+	got := strings.Join(statements, "\n") + "\n"
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != string(want) {
+		t.Errorf("statements of %s differ from %s, run go test -update to accept them\ngot:\n%s\nwant:\n%s", name, path, got, want)
+	}
+}
+
+// goldenRepository the operations of both repositories whose statements are
+// compared to golden files
+type goldenRepository interface {
+	taxonomy.Repository
+	taxonomy.HierarchyRepository
+	taxonomy.SoftDeleteRepository
+}
+
+func TestGolden(t *testing.T) {
+	ctx := context.Background()
+	parentID := "2"
+
+	page := taxonomy.Query{
+		Pagination: repository.Pagination{Limit: 10, Offset: 20, TotalMode: repository.TotalExact},
+		Filter:     filter.And(filter.Like("name", "a%"), filter.In("id", "1", "2")),
+		Sort:       []filter.Sort{filter.Desc("name")},
+	}
+
+	operations := []struct {
+		name string
+		run  func(r goldenRepository) error
+	}{
+		{"create", func(r goldenRepository) error {
+			_, err := r.Create(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{Name: "a", ParentID: &parentID}})
+			return err
+		}},
+		{"read", func(r goldenRepository) error {
+			_, err := r.Read(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: "1"}})
+			return err
+		}},
+		{"update", func(r goldenRepository) error {
+			return r.Update(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: "1", Name: "b"}})
+		}},
+		{"delete", func(r goldenRepository) error {
+			return r.Delete(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: "1"}})
+		}},
+		{"page", func(r goldenRepository) error {
+			_, err := r.Read(ctx, page)
+			return err
+		}},
+		{"children", func(r goldenRepository) error {
+			_, err := r.Children(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: "1"}})
+			return err
+		}},
+		{"subtree", func(r goldenRepository) error {
+			_, err := r.Subtree(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: "1"}, Depth: 2})
+			return err
+		}},
+		{"ancestors", func(r goldenRepository) error {
+			_, err := r.Ancestors(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: "1"}})
+			return err
+		}},
+		{"move", func(r goldenRepository) error {
+			return r.Move(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: "1", ParentID: &parentID}})
+		}},
+		{"restore", func(r goldenRepository) error {
+			return r.Restore(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: "1"}})
+		}},
+		{"purge", func(r goldenRepository) error {
+			_, err := r.Purge(ctx, time.Hour)
+			return err
+		}},
+	}
+
+	repositories := []struct {
+		name string
+		new  func(db kryptonsqlx.DB) goldenRepository
+	}{
+		{"sqlite", func(db kryptonsqlx.DB) goldenRepository {
+			return NewSQLiteRepository(SQLiteWithDB(db))
+		}},
+		{"postgres", func(db kryptonsqlx.DB) goldenRepository {
+			return NewPostgresRepository(PostgresWithDB(db))
+		}},
+	}
+
+	for _, repository := range repositories {
+		for _, op := range operations {
+			db := &recorder{Nop: nop.NewDB()}
+
+			if err := op.run(repository.new(db)); err != nil {
+				t.Errorf("%s %s: error = %v", repository.name, op.name, err)
+				continue
+			}
+
+			golden(t, repository.name+"_"+op.name, db.statements)
+		}
+	}
+}
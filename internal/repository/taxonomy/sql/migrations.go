@@ -0,0 +1,54 @@
+package sql
+
+import (
+	"context"
+	"embed"
+	"io/fs"
+
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+)
+
+// SQLiteSearchMigration the version of the SQLite migration creating the
+// search index, which can only be applied when SQLite has FTS5
+const SQLiteSearchMigration = 3
+
+var (
+	//go:embed migrations/sqlite/*.sql
+	sqliteMigrations embed.FS
+	//go:embed migrations/postgres/*.sql
+	postgresMigrations embed.FS
+)
+
+// SQLiteMigrations the schema migrations of the SQLite taxonomy repository,
+// to be applied with the migrate package, the search index requires FTS5
+// which go-sqlite3 only enables when built with the sqlite_fts5 tag, see
+// SQLiteSupportsSearch
+func SQLiteMigrations() fs.FS {
+	return sub(sqliteMigrations, "migrations/sqlite")
+}
+
+// SQLiteSupportsSearch reports whether the SQLite of db was compiled with
+// FTS5, when it was not SQLiteSearchMigration must be skipped and the
+// repository not used for searches
+func SQLiteSupportsSearch(ctx context.Context, db sqlx.DB) (bool, error) {
+	supported := false
+
+	err := db.GetContext(ctx, &supported, `SELECT sqlite_compileoption_used('ENABLE_FTS5')`)
+
+	return supported, err
+}
+
+// PostgresMigrations the schema migrations of the PostgreSQL taxonomy
+// repository, to be applied with the migrate package
+func PostgresMigrations() fs.FS {
+	return sub(postgresMigrations, "migrations/postgres")
+}
+
+func sub(fsys embed.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+
+	return sub
+}
@@ -0,0 +1,7 @@
+DROP INDEX taxonomy_deleted_at_idx;
+
+DROP INDEX taxonomy_name_key;
+
+DROP INDEX taxonomy_parent_id_idx;
+
+DROP TABLE taxonomy;
@@ -0,0 +1,15 @@
+CREATE TABLE taxonomy (
+    id         BIGSERIAL PRIMARY KEY,
+    name       TEXT NOT NULL,
+    parent_id  BIGINT REFERENCES taxonomy (id) ON DELETE RESTRICT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX taxonomy_parent_id_idx ON taxonomy (parent_id);
+
+-- names only have to be unique among the taxonomies which are not deleted
+CREATE UNIQUE INDEX taxonomy_name_key ON taxonomy (name) WHERE deleted_at IS NULL;
+
+CREATE INDEX taxonomy_deleted_at_idx ON taxonomy (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX taxonomy_name_key;
+
+DROP TABLE taxonomy;
@@ -0,0 +1,22 @@
+-- SQLite can not drop a column referenced by a foreign key, the table is
+-- rebuilt without it instead
+DROP INDEX taxonomy_parent_id_idx;
+
+-- the references are cleared first so dropping the table does not trip them
+UPDATE taxonomy SET parent_id = NULL;
+
+CREATE TABLE taxonomy_without_parent (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO taxonomy_without_parent (id, name, created_at, updated_at)
+    SELECT id, name, created_at, updated_at FROM taxonomy;
+
+DROP TABLE taxonomy;
+
+ALTER TABLE taxonomy_without_parent RENAME TO taxonomy;
+
+CREATE UNIQUE INDEX taxonomy_name_key ON taxonomy (name);
@@ -0,0 +1,3 @@
+ALTER TABLE taxonomy ADD COLUMN parent_id INTEGER REFERENCES taxonomy (id) ON DELETE RESTRICT;
+
+CREATE INDEX taxonomy_parent_id_idx ON taxonomy (parent_id);
@@ -0,0 +1,6 @@
+DROP TRIGGER taxonomy_search_update;
+DROP TRIGGER taxonomy_search_delete;
+DROP TRIGGER taxonomy_search_insert;
+
+DROP TABLE taxonomy_search_trigram;
+DROP TABLE taxonomy_search;
@@ -0,0 +1,36 @@
+-- requires SQLite with FTS5, go-sqlite3 enables it with the sqlite_fts5 tag
+CREATE VIRTUAL TABLE taxonomy_search USING fts5 (
+    name,
+    content = 'taxonomy',
+    content_rowid = 'id',
+    tokenize = 'unicode61 remove_diacritics 2',
+    prefix = '2 3'
+);
+
+-- the trigram index matches names sharing fragments with a misspelt query
+CREATE VIRTUAL TABLE taxonomy_search_trigram USING fts5 (
+    name,
+    content = 'taxonomy',
+    content_rowid = 'id',
+    tokenize = 'trigram'
+);
+
+CREATE TRIGGER taxonomy_search_insert AFTER INSERT ON taxonomy BEGIN
+    INSERT INTO taxonomy_search (rowid, name) VALUES (new.id, new.name);
+    INSERT INTO taxonomy_search_trigram (rowid, name) VALUES (new.id, new.name);
+END;
+
+CREATE TRIGGER taxonomy_search_delete AFTER DELETE ON taxonomy BEGIN
+    INSERT INTO taxonomy_search (taxonomy_search, rowid, name) VALUES ('delete', old.id, old.name);
+    INSERT INTO taxonomy_search_trigram (taxonomy_search_trigram, rowid, name) VALUES ('delete', old.id, old.name);
+END;
+
+CREATE TRIGGER taxonomy_search_update AFTER UPDATE OF name ON taxonomy BEGIN
+    INSERT INTO taxonomy_search (taxonomy_search, rowid, name) VALUES ('delete', old.id, old.name);
+    INSERT INTO taxonomy_search (rowid, name) VALUES (new.id, new.name);
+    INSERT INTO taxonomy_search_trigram (taxonomy_search_trigram, rowid, name) VALUES ('delete', old.id, old.name);
+    INSERT INTO taxonomy_search_trigram (rowid, name) VALUES (new.id, new.name);
+END;
+
+INSERT INTO taxonomy_search (taxonomy_search) VALUES ('rebuild');
+INSERT INTO taxonomy_search_trigram (taxonomy_search_trigram) VALUES ('rebuild');
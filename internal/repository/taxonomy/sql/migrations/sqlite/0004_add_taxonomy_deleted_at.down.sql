@@ -0,0 +1,13 @@
+-- deleted taxonomies may share their names, they are purged so the names can
+-- be unique again
+UPDATE taxonomy SET parent_id = NULL WHERE parent_id IN (SELECT id FROM taxonomy WHERE deleted_at IS NOT NULL);
+
+DELETE FROM taxonomy WHERE deleted_at IS NOT NULL;
+
+DROP INDEX taxonomy_deleted_at_idx;
+
+DROP INDEX taxonomy_name_key;
+
+CREATE UNIQUE INDEX taxonomy_name_key ON taxonomy (name);
+
+ALTER TABLE taxonomy DROP COLUMN deleted_at;
@@ -0,0 +1,8 @@
+ALTER TABLE taxonomy ADD COLUMN deleted_at TIMESTAMP;
+
+-- names only have to be unique among the taxonomies which are not deleted
+DROP INDEX taxonomy_name_key;
+
+CREATE UNIQUE INDEX taxonomy_name_key ON taxonomy (name) WHERE deleted_at IS NULL;
+
+CREATE INDEX taxonomy_deleted_at_idx ON taxonomy (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1 @@
+DROP TABLE taxonomy_history;
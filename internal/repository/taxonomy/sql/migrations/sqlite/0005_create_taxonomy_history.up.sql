@@ -0,0 +1,26 @@
+-- every revision is a snapshot of a taxonomy, valid from valid_from until
+-- valid_to, the current revision has no valid_to, times are UTC with
+-- milliseconds so revisions written within a second stay ordered
+CREATE TABLE taxonomy_history (
+    revision    INTEGER PRIMARY KEY AUTOINCREMENT,
+    taxonomy_id INTEGER NOT NULL,
+    name        TEXT NOT NULL,
+    parent_id   INTEGER,
+    created_at  TIMESTAMP NOT NULL,
+    updated_at  TIMESTAMP NOT NULL,
+    deleted_at  TIMESTAMP,
+    operation   TEXT NOT NULL,
+    actor       TEXT NOT NULL DEFAULT '',
+    reason      TEXT NOT NULL DEFAULT '',
+    valid_from  TIMESTAMP NOT NULL,
+    valid_to    TIMESTAMP
+);
+
+CREATE INDEX taxonomy_history_taxonomy_id_idx ON taxonomy_history (taxonomy_id, valid_from);
+
+CREATE INDEX taxonomy_history_valid_idx ON taxonomy_history (valid_from, valid_to);
+
+-- the history of existing taxonomies starts with their current state
+INSERT INTO taxonomy_history (taxonomy_id, name, parent_id, created_at, updated_at, deleted_at, operation, valid_from)
+SELECT id, name, parent_id, created_at, updated_at, deleted_at, 'create', strftime('%Y-%m-%d %H:%M:%f', updated_at)
+FROM taxonomy ORDER BY id;
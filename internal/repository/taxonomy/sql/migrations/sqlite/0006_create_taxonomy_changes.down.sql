@@ -0,0 +1,3 @@
+DROP TABLE taxonomy_change_checkpoints;
+
+DROP TABLE taxonomy_changes;
@@ -0,0 +1,21 @@
+-- the log of every change of the taxonomies in the order they were committed,
+-- changes keeping a taxonomy read its state from their revision, purges have
+-- none
+CREATE TABLE taxonomy_changes (
+    seq         INTEGER PRIMARY KEY AUTOINCREMENT,
+    taxonomy_id INTEGER NOT NULL,
+    revision    INTEGER REFERENCES taxonomy_history (revision),
+    operation   TEXT NOT NULL,
+    created_at  TIMESTAMP NOT NULL
+);
+
+-- the position of each consumer of the feed, the last change it processed
+CREATE TABLE taxonomy_change_checkpoints (
+    consumer   TEXT PRIMARY KEY,
+    seq        INTEGER NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- the feed replays the history written so far
+INSERT INTO taxonomy_changes (taxonomy_id, revision, operation, created_at)
+SELECT taxonomy_id, revision, operation, valid_from FROM taxonomy_history ORDER BY revision;
@@ -0,0 +1,194 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/dialect"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/filter"
+)
+
+// pager reads filtered pages of taxonomies in the SQL of a dialect, shared by
+// the repositories of every database
+type pager struct {
+	db       kryptonsqlx.DB
+	dialect  dialect.Dialect
+	cursors  *repository.CursorCodec
+	filters  *filter.Compiler
+	estimate string
+}
+
+// read reads the page of the taxonomies of table matching the query, ordered
+// by the sort of the query then by ID, selected by the cursor of the
+// pagination when set or by its offset, cursors require the default order,
+// args are the arguments of table
+func (p *pager) read(ctx context.Context, query taxonomy.Query, table string, args []any) (taxonomy.Response, error) {
+	pagination := query.Pagination
+
+	cursor := repository.Cursor{}
+	keyset := pagination.Cursor != ""
+	sorted := len(query.Sort) > 0
+	fingerprint := fingerprint(query, table, args)
+
+	if keyset {
+		if sorted {
+			return taxonomy.Response{}, fmt.Errorf("%w: cursors only page taxonomies ordered by ID", repository.ErrInvalidCursor)
+		}
+
+		var err error
+
+		if cursor, err = p.cursors.Decode(pagination.Cursor); err != nil {
+			return taxonomy.Response{}, err
+		}
+
+		if cursor.Query != fingerprint {
+			return taxonomy.Response{}, fmt.Errorf("%w: cursor taken from another filter", repository.ErrInvalidCursor)
+		}
+	}
+
+	clause, err := p.filters.Compile(query.Filter, query.Sort, len(args)+1)
+	if err != nil {
+		return taxonomy.Response{}, err
+	}
+
+	conditions := []string{}
+	if clause.Where != "" {
+		conditions = append(conditions, clause.Where)
+	}
+
+	if !query.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+
+	args = append(args, clause.Args...)
+
+	// the total counts the taxonomies matching the filter, whatever the page
+	filtered, filteredArgs := slices.Clip(conditions), slices.Clip(args)
+
+	orderBy := "id"
+
+	switch {
+	case sorted:
+		orderBy = clause.OrderBy + ", id"
+	case keyset && cursor.Backward:
+		args = append(args, cursor.Key)
+		conditions = append(conditions, "id < "+p.dialect.Placeholder(len(args)))
+		orderBy = "id DESC"
+	case keyset:
+		args = append(args, cursor.Key)
+		conditions = append(conditions, "id > "+p.dialect.Placeholder(len(args)))
+	}
+
+	// one more taxonomy than the limit is read to tell whether a page follows
+	fetch := pagination.Limit
+	if fetch > 0 {
+		fetch++
+	}
+
+	offset := pagination.Offset
+	if keyset {
+		offset = 0
+	}
+
+	page, pageArgs := p.dialect.Paginate(fetch, offset, len(args)+1)
+
+	q := queryReadTaxonomies + table + where(conditions) + " ORDER BY " + orderBy + page
+	args = append(args, pageArgs...)
+
+	taxonomies := []model.Taxonomy{}
+
+	if err := p.db.SelectContext(ctx, &taxonomies, q, args...); err != nil {
+		return taxonomy.Response{}, mapError(err)
+	}
+
+	more := fetch > 0 && uint(len(taxonomies)) == fetch
+	if more {
+		taxonomies = taxonomies[:pagination.Limit]
+	}
+
+	if cursor.Backward {
+		slices.Reverse(taxonomies)
+	}
+
+	res := repository.Pagination{
+		Limit:  pagination.Limit,
+		Offset: pagination.Offset,
+		Count:  uint(len(taxonomies)),
+	}
+
+	// reading backward, the page the cursor was taken from follows this one
+	hasNext := more || cursor.Backward
+	hasPrev := more && cursor.Backward || !cursor.Backward && (keyset || pagination.Offset > 0)
+
+	if n := len(taxonomies); n > 0 && !sorted {
+		if hasNext {
+			res.NextCursor = p.cursors.Encode(repository.Cursor{
+				Key:   taxonomies[n-1].ID,
+				Query: fingerprint,
+			})
+		}
+
+		if hasPrev {
+			res.PrevCursor = p.cursors.Encode(repository.Cursor{
+				Key:      taxonomies[0].ID,
+				Backward: true,
+				Query:    fingerprint,
+			})
+		}
+	}
+
+	if res.Total, res.TotalEstimated, err = p.total(ctx, pagination.TotalMode, table, filtered, filteredArgs); err != nil {
+		return taxonomy.Response{}, err
+	}
+
+	return taxonomy.NewResponse(
+		taxonomy.ResponseWithPagination(res),
+		taxonomy.ResponseWithResult(taxonomies...),
+	), nil
+}
+
+// total counts the taxonomies matching the conditions as requested by mode,
+// the estimate is read by the estimate query of the pager, it accounts for
+// neither the conditions nor deleted taxonomies
+func (p *pager) total(ctx context.Context, mode repository.TotalMode, table string, conditions []string, args []any) (*uint, bool, error) {
+	query := ""
+
+	switch mode {
+	case repository.TotalExact:
+		query = queryCountTaxonomies + table + where(conditions)
+	case repository.TotalEstimated:
+		query, args = p.estimate, nil
+	default:
+		return nil, false, nil
+	}
+
+	var total uint
+
+	if err := p.db.GetContext(ctx, &total, query, args...); err != nil {
+		return nil, false, mapError(err)
+	}
+
+	return &total, mode == repository.TotalEstimated, nil
+}
+
+// fingerprint the fingerprint of the fields of the query selecting the
+// taxonomies paged, the pagination aside, table and args included as they
+// select the revisions read
+func fingerprint(query taxonomy.Query, table string, args []any) string {
+	return repository.Fingerprint(struct {
+		Filter         filter.Expr
+		IncludeDeleted bool
+		Table          string
+		Args           []any
+	}{
+		Filter:         query.Filter,
+		IncludeDeleted: query.IncludeDeleted,
+		Table:          table,
+		Args:           args,
+	})
+}
@@ -0,0 +1,123 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/filter"
+)
+
+func names(taxonomies []model.Taxonomy) []string {
+	names := []string{}
+	for _, t := range taxonomies {
+		names = append(names, t.Name)
+	}
+
+	return names
+}
+
+func TestReadCursor(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewSQLiteRepository(
+		SQLiteWithDB(newSQLiteDB(t)),
+		SQLiteWithCursorKey([]byte("key")),
+	)
+
+	create(t, r, nil, "a", "b", "c", "d", "e")
+
+	page := func(cursor string) taxonomy.Response {
+		t.Helper()
+
+		res, err := r.Read(ctx, taxonomy.Query{
+			Pagination: repository.Pagination{Limit: 2, Cursor: cursor},
+			Filter:     filter.Ne("name", "c"),
+		})
+		if err != nil {
+			t.Fatalf("Read(%q) error = %v", cursor, err)
+		}
+
+		return res
+	}
+
+	first := page("")
+	second := page(first.Pagination.NextCursor)
+
+	for i, tt := range []struct {
+		res  taxonomy.Response
+		want []string
+	}{
+		{first, []string{"a", "b"}},
+		{second, []string{"d", "e"}},
+		{page(second.Pagination.PrevCursor), []string{"a", "b"}},
+	} {
+		if got := names(tt.res.Results); !slices.Equal(got, tt.want) {
+			t.Errorf("page %d = %v, want %v", i, got, tt.want)
+		}
+	}
+
+	if second.Pagination.NextCursor != "" {
+		t.Errorf("NextCursor of the last page = %q, want none", second.Pagination.NextCursor)
+	}
+}
+
+func TestReadCursorInvalid(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewSQLiteRepository(
+		SQLiteWithDB(newSQLiteDB(t)),
+		SQLiteWithCursorKey([]byte("key")),
+	)
+
+	create(t, r, nil, "a", "b", "c")
+
+	query := taxonomy.Query{
+		Pagination: repository.Pagination{Limit: 1},
+		Filter:     filter.Like("name", "%"),
+	}
+
+	res, err := r.Read(ctx, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cursor := res.Pagination.NextCursor
+	other := NewSQLiteRepository(SQLiteWithCursorKey([]byte("other")))
+
+	tests := []struct {
+		name   string
+		modify func(q *taxonomy.Query)
+	}{
+		{"tampered", func(q *taxonomy.Query) { q.Pagination.Cursor = cursor[:len(cursor)-2] + "AA" }},
+		{"truncated", func(q *taxonomy.Query) { q.Pagination.Cursor = cursor[:len(cursor)/2] }},
+		{"other key", func(q *taxonomy.Query) {
+			q.Pagination.Cursor = other.cursors.Encode(repository.Cursor{Key: "1", Query: fingerprint(query, "taxonomy", []any{})})
+		}},
+		{"other filter", func(q *taxonomy.Query) { q.Filter = filter.Like("name", "a%") }},
+		{"no filter", func(q *taxonomy.Query) { q.Filter = filter.Expr{} }},
+		{"deleted included", func(q *taxonomy.Query) { q.IncludeDeleted = true }},
+		{"sorted", func(q *taxonomy.Query) { q.Sort = []filter.Sort{filter.Desc("name")} }},
+	}
+
+	for _, tt := range tests {
+		q := query
+		q.Pagination.Cursor = cursor
+		tt.modify(&q)
+
+		if _, err := r.Read(ctx, q); !errors.Is(err, repository.ErrInvalidCursor) {
+			t.Errorf("%s: Read() error = %v, want %v", tt.name, err, repository.ErrInvalidCursor)
+		}
+	}
+
+	// the cursor is accepted with its own query
+	query.Pagination.Cursor = cursor
+
+	if _, err := r.Read(ctx, query); err != nil {
+		t.Errorf("Read() error = %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/dialect"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/filter"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/nop"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/repo"
+)
+
+const (
+	// the statistics of the planner, -1 until the table is first analysed
+	queryEstimateTaxonomiesPostgres = `
+		SELECT GREATEST(reltuples, 0)::BIGINT FROM pg_class WHERE oid = 'taxonomy'::regclass`
+)
+
+type PostgresRepositoryOption func(*PostgresRepository)
+
+// PostgresRepository the taxonomy repository of PostgreSQL, it keeps no
+// history, change log or search index, which remain SQLite only
+type PostgresRepository struct {
+	db         kryptonsqlx.DB
+	dialect    dialect.Dialect
+	taxonomies *repo.Repository[model.Taxonomy]
+	pages      *pager
+	cursors    *repository.CursorCodec
+	filters    *filter.Compiler
+	invalidate func(ids ...string)
+}
+
+// NewPostgresRepository constructor for a new SQL repository utilising
+// PostgreSQL as a driver
+func NewPostgresRepository(opts ...PostgresRepositoryOption) *PostgresRepository {
+	d := dialect.NewPostgres()
+
+	r := PostgresRepository{
+		db:      nop.NewDB(),
+		dialect: d,
+		cursors: repository.NewCursorCodec(nil),
+		filters: filter.NewCompiler(
+			filter.CompilerWithColumns(filter.ColumnsOf(model.Taxonomy{})),
+			filter.CompilerWithValuer(d.Value),
+		),
+	}
+
+	for _, opt := range opts {
+		opt(&r)
+	}
+
+	r.taxonomies = repo.NewRepository[model.Taxonomy](
+		repo.RepositoryWithDB(r.db),
+		repo.RepositoryWithDialect(r.dialect),
+		repo.RepositoryWithGenerated("id", "created_at", "updated_at", "deleted_at"),
+		repo.RepositoryWithQuery(repo.OperationUpdate, queryUpdateTaxonomy),
+		repo.RepositoryWithQuery(repo.OperationDelete, queryDeleteTaxonomy),
+	)
+
+	r.pages = &pager{
+		db:       r.db,
+		dialect:  r.dialect,
+		cursors:  r.cursors,
+		filters:  r.filters,
+		estimate: queryEstimateTaxonomiesPostgres,
+	}
+
+	return &r
+}
+
+// Create PostgreSQL implementation for a taxonomy repository, the response
+// holds the created taxonomy with its generated ID
+func (r *PostgresRepository) Create(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	if parentID := query.Taxonomy.ParentID; parentID != nil && !isID(*parentID) {
+		return taxonomy.Response{}, fmt.Errorf("%w: parent taxonomy %s does not exist", repository.ErrConflict, *parentID)
+	}
+
+	t, err := r.taxonomies.Create(ctx, query.Taxonomy)
+	if err != nil {
+		return taxonomy.Response{}, mapError(err)
+	}
+
+	r.changed(t.ID)
+
+	return taxonomy.NewResponse(
+		taxonomy.ResponseWithPagination(repository.Pagination{
+			Count: 1,
+		}),
+		taxonomy.ResponseWithResult(t),
+	), nil
+}
+
+// Read PostgreSQL implementation for a taxonomy repository, reads the
+// taxonomy with the ID of the query when set, otherwise a filtered page of
+// taxonomies as the SQLite repository does, returns
+// repository.ErrUnsupported for queries with an AsOf as there is no history
+func (r *PostgresRepository) Read(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	if !query.AsOf.IsZero() {
+		return taxonomy.Response{}, fmt.Errorf("%w: taxonomies have no history in PostgreSQL", repository.ErrUnsupported)
+	}
+
+	if query.Taxonomy.ID == "" {
+		return r.pages.read(ctx, query, "taxonomy", nil)
+	}
+
+	if !isID(query.Taxonomy.ID) {
+		return taxonomy.Response{}, repository.ErrNotFound
+	}
+
+	t := model.Taxonomy{}
+
+	if err := r.db.GetContext(ctx, &t, queryReadTaxonomyByID, query.Taxonomy.ID, query.IncludeDeleted); err != nil {
+		return taxonomy.Response{}, mapError(err)
+	}
+
+	return taxonomy.NewResponse(
+		taxonomy.ResponseWithPagination(repository.Pagination{
+			Limit: query.Pagination.Limit,
+			Count: 1,
+		}),
+		taxonomy.ResponseWithResult(t),
+	), nil
+}
+
+// Update PostgreSQL implementation for a taxonomy repository, returns
+// repository.ErrNotFound when no taxonomy has the ID of the query or it is
+// deleted
+func (r *PostgresRepository) Update(ctx context.Context, query taxonomy.Query) error {
+	if !isID(query.Taxonomy.ID) {
+		return repository.ErrNotFound
+	}
+
+	if err := r.taxonomies.Update(ctx, query.Taxonomy); err != nil {
+		return mapError(err)
+	}
+
+	r.changed(query.Taxonomy.ID)
+
+	return nil
+}
+
+// Delete PostgreSQL implementation for a taxonomy repository, marks the
+// taxonomy as deleted, returns repository.ErrNotFound when no taxonomy has
+// the ID of the query and repository.ErrConflict while it has children
+func (r *PostgresRepository) Delete(ctx context.Context, query taxonomy.Query) error {
+	id := query.Taxonomy.ID
+
+	if !isID(id) {
+		return repository.ErrNotFound
+	}
+
+	return r.inTx(ctx, func(tx kryptonsqlx.Tx) error {
+		err := r.taxonomies.Tx(tx).Delete(ctx, id)
+		if err == nil || !errors.Is(err, repo.ErrNotFound) {
+			return mapError(err)
+		}
+
+		exists := false
+
+		if err := tx.GetContext(ctx, &exists, queryTaxonomyExists, id, false); err != nil {
+			return mapError(err)
+		}
+
+		if !exists {
+			return repository.ErrNotFound
+		}
+
+		return fmt.Errorf("%w: taxonomy %s has children", repository.ErrConflict, id)
+	}, id)
+}
+
+// inTx runs fn in a transaction, the taxonomies with the IDs, when given, are
+// passed to the invalidation hook once it is committed
+func (r *PostgresRepository) inTx(ctx context.Context, fn func(tx kryptonsqlx.Tx) error, ids ...string) error {
+	if err := inTx(ctx, r.db, fn); err != nil {
+		return err
+	}
+
+	if len(ids) > 0 {
+		r.changed(ids...)
+	}
+
+	return nil
+}
+
+// changed passes the IDs of the taxonomies written to the invalidation hook
+// when the repository has one
+func (r *PostgresRepository) changed(ids ...string) {
+	if r.invalidate != nil {
+		r.invalidate(ids...)
+	}
+}
+
+// isID reports whether id can be the ID of a taxonomy, PostgreSQL refuses to
+// compare the ID column with anything but an integer
+func isID(id string) bool {
+	_, err := strconv.ParseInt(id, 10, 64)
+
+	return err == nil
+}
@@ -0,0 +1,216 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+)
+
+// an empty ID refers to the roots, PostgreSQL deduces a single type for each
+// parameter so the ID is compared as text with the empty ID and converted to
+// an integer for the ID columns
+const (
+	queryReadChildrenPostgres = `
+		SELECT id, name, parent_id, created_at, updated_at, deleted_at FROM taxonomy
+		WHERE (($1::TEXT = '' AND parent_id IS NULL) OR parent_id = NULLIF($1::TEXT, '')::BIGINT)
+		AND ($2 OR deleted_at IS NULL)
+		ORDER BY id`
+	queryReadSubtreePostgres = `
+		WITH RECURSIVE subtree (id, name, parent_id, created_at, updated_at, deleted_at, depth) AS (
+			SELECT id, name, parent_id, created_at, updated_at, deleted_at, 0 FROM taxonomy
+			WHERE (($1::TEXT = '' AND parent_id IS NULL) OR id = NULLIF($1::TEXT, '')::BIGINT)
+			AND ($2 OR deleted_at IS NULL)
+			UNION ALL
+			SELECT t.id, t.name, t.parent_id, t.created_at, t.updated_at, t.deleted_at, s.depth + 1
+			FROM taxonomy t JOIN subtree s ON t.parent_id = s.id
+			WHERE ($3 = 0 OR s.depth < $3) AND ($2 OR t.deleted_at IS NULL)
+		)
+		SELECT id, name, parent_id, created_at, updated_at, deleted_at, depth FROM subtree ORDER BY depth, id`
+	// a move locks the taxonomy moved, its new parent and the ancestors of
+	// the parent, a concurrent move forming a cycle with it locks one of them
+	// too so it waits and then sees the move, the rows are locked in the
+	// order of their IDs
+	queryLockMovePostgres = `
+		SELECT id FROM taxonomy WHERE id = $1::BIGINT OR id IN (
+			WITH RECURSIVE ancestors (id, parent_id) AS (
+				SELECT id, parent_id FROM taxonomy WHERE id = $2::BIGINT
+				UNION ALL
+				SELECT t.id, t.parent_id FROM taxonomy t JOIN ancestors a ON t.id = a.parent_id
+			)
+			SELECT id FROM ancestors
+		)
+		ORDER BY id FOR UPDATE`
+)
+
+// Children PostgreSQL implementation for a taxonomy hierarchy repository,
+// returns repository.ErrNotFound when the parent does not exist
+func (r *PostgresRepository) Children(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	id, p := query.Taxonomy.ID, query.Pagination
+
+	if id != "" && !isID(id) {
+		return taxonomy.Response{}, repository.ErrNotFound
+	}
+
+	page, pageArgs := r.dialect.Paginate(p.Limit, p.Offset, 3)
+
+	children := []model.Taxonomy{}
+
+	if err := r.db.SelectContext(
+		ctx, &children, queryReadChildrenPostgres+page,
+		append([]any{id, query.IncludeDeleted}, pageArgs...)...,
+	); err != nil {
+		return taxonomy.Response{}, mapError(err)
+	}
+
+	if len(children) == 0 && id != "" {
+		if err := r.requireExists(ctx, id, query.IncludeDeleted); err != nil {
+			return taxonomy.Response{}, err
+		}
+	}
+
+	return taxonomy.NewResponse(
+		taxonomy.ResponseWithPagination(repository.Pagination{
+			Limit:  p.Limit,
+			Offset: p.Offset,
+			Count:  uint(len(children)),
+		}),
+		taxonomy.ResponseWithResult(children...),
+	), nil
+}
+
+// Ancestors PostgreSQL implementation for a taxonomy hierarchy repository,
+// the results start at the root and end with the taxonomy of the query
+func (r *PostgresRepository) Ancestors(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	if !isID(query.Taxonomy.ID) {
+		return taxonomy.Response{}, repository.ErrNotFound
+	}
+
+	nodes := []node{}
+
+	if err := r.db.SelectContext(ctx, &nodes, queryReadAncestors, query.Taxonomy.ID, query.IncludeDeleted); err != nil {
+		return taxonomy.Response{}, mapError(err)
+	}
+
+	if len(nodes) == 0 {
+		return taxonomy.Response{}, repository.ErrNotFound
+	}
+
+	ancestors := make([]model.Taxonomy, 0, len(nodes))
+	for _, n := range nodes {
+		ancestors = append(ancestors, n.Taxonomy)
+	}
+
+	return taxonomy.NewResponse(
+		taxonomy.ResponseWithPagination(repository.Pagination{
+			Count: uint(len(ancestors)),
+		}),
+		taxonomy.ResponseWithResult(ancestors...),
+	), nil
+}
+
+// Subtree PostgreSQL implementation for a taxonomy hierarchy repository, the
+// results hold the taxonomy of the query, or every root when its ID is
+// empty, with the descendants nested as children
+func (r *PostgresRepository) Subtree(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	id := query.Taxonomy.ID
+
+	if id != "" && !isID(id) {
+		return taxonomy.Response{}, repository.ErrNotFound
+	}
+
+	nodes := []node{}
+
+	if err := r.db.SelectContext(ctx, &nodes, queryReadSubtreePostgres, id, query.IncludeDeleted, query.Depth); err != nil {
+		return taxonomy.Response{}, mapError(err)
+	}
+
+	if len(nodes) == 0 && id != "" {
+		return taxonomy.Response{}, repository.ErrNotFound
+	}
+
+	roots := nest(nodes)
+
+	return taxonomy.NewResponse(
+		taxonomy.ResponseWithPagination(repository.Pagination{
+			Count: uint(len(roots)),
+		}),
+		taxonomy.ResponseWithResult(roots...),
+	), nil
+}
+
+// Move PostgreSQL implementation for a taxonomy hierarchy repository, a nil
+// ParentID makes the taxonomy a root, moves under a missing parent or under
+// the taxonomy's own subtree return repository.ErrConflict
+func (r *PostgresRepository) Move(ctx context.Context, query taxonomy.Query) error {
+	id, parentID := query.Taxonomy.ID, query.Taxonomy.ParentID
+
+	if !isID(id) {
+		return repository.ErrNotFound
+	}
+
+	if parentID != nil && !isID(*parentID) {
+		return fmt.Errorf("%w: parent taxonomy %s does not exist", repository.ErrConflict, *parentID)
+	}
+
+	return r.inTx(ctx, func(tx kryptonsqlx.Tx) error {
+		locked := []string{}
+
+		if err := tx.SelectContext(ctx, &locked, queryLockMovePostgres, id, parentID); err != nil {
+			return mapError(err)
+		}
+
+		exists := false
+
+		if err := tx.GetContext(ctx, &exists, queryTaxonomyExists, id, false); err != nil {
+			return mapError(err)
+		}
+
+		if !exists {
+			return repository.ErrNotFound
+		}
+
+		if parentID != nil {
+			if err := tx.GetContext(ctx, &exists, queryTaxonomyExists, *parentID, false); err != nil {
+				return mapError(err)
+			}
+
+			if !exists {
+				return fmt.Errorf("%w: parent taxonomy %s does not exist", repository.ErrConflict, *parentID)
+			}
+
+			cycle := false
+
+			if err := tx.GetContext(ctx, &cycle, queryIsDescendant, id, *parentID); err != nil {
+				return mapError(err)
+			}
+
+			if cycle {
+				return fmt.Errorf("%w: taxonomy %s can not be moved under its own subtree", repository.ErrConflict, id)
+			}
+		}
+
+		_, err := tx.ExecContext(ctx, queryMoveTaxonomy, parentID, id)
+
+		return mapError(err)
+	}, id)
+}
+
+// requireExists returns repository.ErrNotFound when the taxonomy does not
+// exist, or is deleted and deleted taxonomies are not included
+func (r *PostgresRepository) requireExists(ctx context.Context, id string, includeDeleted bool) error {
+	exists := false
+
+	if err := r.db.GetContext(ctx, &exists, queryTaxonomyExists, id, includeDeleted); err != nil {
+		return mapError(err)
+	}
+
+	if !exists {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
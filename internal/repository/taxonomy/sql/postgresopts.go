@@ -0,0 +1,41 @@
+package sql
+
+import (
+	"log/slog"
+
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/logging"
+)
+
+func PostgresWithDB(db sqlx.DB) PostgresRepositoryOption {
+	return func(r *PostgresRepository) {
+		r.db = db
+	}
+}
+
+// PostgresWithCursorKey sets the key signing pagination cursors, instances
+// sharing the key accept each other's cursors
+func PostgresWithCursorKey(key []byte) PostgresRepositoryOption {
+	return func(r *PostgresRepository) {
+		r.cursors = repository.NewCursorCodec(key)
+	}
+}
+
+// PostgresWithInvalidator sets a hook called with the IDs of the taxonomies
+// written once their writes are committed, for caches of the repository's
+// reads to invalidate them, moves, restores and purges included
+func PostgresWithInvalidator(fn func(ids ...string)) PostgresRepositoryOption {
+	return func(r *PostgresRepository) {
+		r.invalidate = fn
+	}
+}
+
+func PostgresWithLogging(l *slog.Logger) PostgresRepositoryOption {
+	return func(r *PostgresRepository) {
+		r.db = logging.NewDB(
+			logging.DBWithInnerDB(r.db),
+			logging.DBWithLogger(l),
+		)
+	}
+}
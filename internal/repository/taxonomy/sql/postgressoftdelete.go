@@ -0,0 +1,69 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+)
+
+// Restore PostgreSQL implementation for a taxonomy soft delete repository,
+// returns repository.ErrNotFound when no deleted taxonomy has the ID of the
+// query, and repository.ErrConflict when its parent is deleted or its name
+// was taken since
+func (r *PostgresRepository) Restore(ctx context.Context, query taxonomy.Query) error {
+	id := query.Taxonomy.ID
+
+	if !isID(id) {
+		return repository.ErrNotFound
+	}
+
+	return r.inTx(ctx, func(tx kryptonsqlx.Tx) error {
+		res, err := tx.ExecContext(ctx, queryRestoreTaxonomy, id)
+		if err != nil {
+			return mapError(err)
+		}
+
+		if err := requireAffected(res); err == nil {
+			return nil
+		}
+
+		deleted := false
+
+		if err := tx.GetContext(ctx, &deleted, queryDeletedTaxonomyExists, id); err != nil {
+			return mapError(err)
+		}
+
+		if !deleted {
+			return repository.ErrNotFound
+		}
+
+		return fmt.Errorf("%w: parent of taxonomy %s is deleted", repository.ErrConflict, id)
+	}, id)
+}
+
+// Purge PostgreSQL implementation for a taxonomy soft delete repository,
+// deleted subtrees are removed leaves first
+func (r *PostgresRepository) Purge(ctx context.Context, olderThan time.Duration) (int64, error) {
+	cutoff := r.dialect.Value(time.Now().Add(-olderThan))
+	purged := int64(0)
+
+	for {
+		ids := []string{}
+
+		if err := r.db.SelectContext(ctx, &ids, queryPurgeTaxonomies, cutoff); err != nil {
+			return purged, mapError(err)
+		}
+
+		if len(ids) == 0 {
+			return purged, nil
+		}
+
+		r.changed(ids...)
+
+		purged += int64(len(ids))
+	}
+}
@@ -2,85 +2,281 @@ package sql
 
 import (
 	"context"
+	stdsql "database/sql"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/olireadcopper/sqlxprototype/internal/model"
 	"github.com/olireadcopper/sqlxprototype/internal/repository"
 	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
-	"github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/dialect"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/filter"
 	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/nop"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/outbox"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/repo"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/sqlerr"
 )
 
 const (
-	queryCreateTaxonomy = `
-		INSERT INTO taxonomy (name) VALUES ($1)`
 	queryReadTaxonomyByID = `
-		SELECT (id, name) FROM taxonomy WHERE id=($1) LIMIT $2 OFFSET $3`
+		SELECT id, name, parent_id, created_at, updated_at, deleted_at FROM taxonomy
+		WHERE id = $1 AND ($2 OR deleted_at IS NULL)`
+	// the table, filter, order and page are appended to the queries reading
+	// lists
+	queryReadTaxonomies = `
+		SELECT id, name, parent_id, created_at, updated_at, deleted_at FROM `
+	queryCountTaxonomies = `
+		SELECT COUNT(*) FROM `
+	queryEstimateTaxonomies = `
+		SELECT COALESCE((SELECT seq FROM sqlite_sequence WHERE name = 'taxonomy'), 0)`
+	// the writes of the generic repository are named queries, only the name
+	// of a taxonomy is updated and a deletion is soft
+	queryUpdateTaxonomy = `
+		UPDATE taxonomy SET name = :name, updated_at = CURRENT_TIMESTAMP WHERE id = :id AND deleted_at IS NULL`
+	// a taxonomy is only deleted once its children are
+	queryDeleteTaxonomy = `
+		UPDATE taxonomy SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id = :id AND deleted_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM taxonomy c WHERE c.parent_id = taxonomy.id AND c.deleted_at IS NULL)`
 )
 
 type SQLiteRepositoryOption func(*SQLiteRepository)
 
 type SQLiteRepository struct {
-	db sqlx.DB
+	db         kryptonsqlx.DB
+	dialect    dialect.Dialect
+	taxonomies *repo.Repository[model.Taxonomy]
+	pages      *pager
+	cursors    *repository.CursorCodec
+	filters    *filter.Compiler
+	outbox     *outbox.Outbox
+	invalidate func(ids ...string)
 }
 
 // NewSQLiteRepository constructor for a new SQL repository utilising SQLite as
 // a driver
 func NewSQLiteRepository(opts ...SQLiteRepositoryOption) *SQLiteRepository {
+	d := dialect.NewSQLite()
+
 	r := SQLiteRepository{
-		db: nop.NewDB(),
+		db:      nop.NewDB(),
+		dialect: d,
+		cursors: repository.NewCursorCodec(nil),
+		filters: filter.NewCompiler(
+			filter.CompilerWithColumns(filter.ColumnsOf(model.Taxonomy{})),
+			filter.CompilerWithValuer(d.Value),
+		),
 	}
 
 	for _, opt := range opts {
 		opt(&r)
 	}
 
+	r.taxonomies = repo.NewRepository[model.Taxonomy](
+		repo.RepositoryWithDB(r.db),
+		repo.RepositoryWithDialect(r.dialect),
+		repo.RepositoryWithGenerated("id", "created_at", "updated_at", "deleted_at"),
+		repo.RepositoryWithQuery(repo.OperationUpdate, queryUpdateTaxonomy),
+		repo.RepositoryWithQuery(repo.OperationDelete, queryDeleteTaxonomy),
+	)
+
+	r.pages = &pager{
+		db:       r.db,
+		dialect:  r.dialect,
+		cursors:  r.cursors,
+		filters:  r.filters,
+		estimate: queryEstimateTaxonomies,
+	}
+
 	return &r
 }
 
-// Create SQLite implementation for a taxonomy repository
-func (r *SQLiteRepository) Create(ctx context.Context, query taxonomy.Query) error {
-	if _, err := r.db.ExecContext(ctx, queryCreateTaxonomy, query.Taxonomy.Name); err != nil {
-		return err
+// Create SQLite implementation for a taxonomy repository, the response holds
+// the created taxonomy with its generated ID
+func (r *SQLiteRepository) Create(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	t := model.Taxonomy{}
+
+	if err := r.inTx(ctx, func(tx kryptonsqlx.Tx) error {
+		var err error
+
+		if t, err = r.taxonomies.Tx(tx).Create(ctx, query.Taxonomy); err != nil {
+			return mapError(err)
+		}
+
+		return mapError(r.record(ctx, tx, query, taxonomy.OperationCreate, t.ID))
+	}); err != nil {
+		return taxonomy.Response{}, err
 	}
 
-	return nil
+	r.changed(t.ID)
+
+	return taxonomy.NewResponse(
+		taxonomy.ResponseWithPagination(repository.Pagination{
+			Count: 1,
+		}),
+		taxonomy.ResponseWithResult(t),
+	), nil
 }
 
-// Read SQLite implementation for a taxonomy repository
+// Read SQLite implementation for a taxonomy repository, reads the taxonomy
+// with the ID of the query when set, otherwise a filtered page of taxonomies
+// ordered by the sort of the query then by ID, selected by the cursor of the
+// pagination when set or by its offset, cursors require the default order,
+// the taxonomies are read from the history when the query has an AsOf
 func (r *SQLiteRepository) Read(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
-	rows, err := r.db.QueryxContext(ctx, queryReadTaxonomyByID)
-	if err != nil {
-		return taxonomy.Response{}, err
+	if query.Taxonomy.ID != "" {
+		return r.readByID(ctx, query)
 	}
 
-	taxonomies := []model.Taxonomy{}
+	table, args := "taxonomy", []any{}
+	if !query.AsOf.IsZero() {
+		table, args = queryTaxonomiesAsOf, append(args, historyTime(query.AsOf))
+	}
 
-	for rows.Next() {
-		t := model.Taxonomy{}
+	return r.pages.read(ctx, query, table, args)
+}
 
-		if err := rows.StructScan(&t); err != nil {
-			return taxonomy.Response{}, err
-		}
+func (r *SQLiteRepository) readByID(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	t := model.Taxonomy{}
+	q, args := queryReadTaxonomyByID, []any{query.Taxonomy.ID, query.IncludeDeleted}
+
+	if !query.AsOf.IsZero() {
+		q, args = queryReadTaxonomyByIDAsOf, append(args, historyTime(query.AsOf))
+	}
 
-		taxonomies = append(taxonomies, t)
+	if err := r.db.GetContext(ctx, &t, q, args...); err != nil {
+		return taxonomy.Response{}, mapError(err)
 	}
 
 	return taxonomy.NewResponse(
 		taxonomy.ResponseWithPagination(repository.Pagination{
-			Limit:  query.Pagination.Limit,
-			Offset: query.Pagination.Offset,
-			Count:  uint(len(taxonomies)),
+			Limit: query.Pagination.Limit,
+			Count: 1,
 		}),
-		taxonomy.ResponseWithResult(taxonomies...),
+		taxonomy.ResponseWithResult(t),
 	), nil
 }
 
-// Update SQLite implementation for a taxonomy repository
+// Update SQLite implementation for a taxonomy repository, returns
+// repository.ErrNotFound when no taxonomy has the ID of the query or it is
+// deleted
 func (r *SQLiteRepository) Update(ctx context.Context, query taxonomy.Query) error {
-	return nil
+	return r.inTx(ctx, func(tx kryptonsqlx.Tx) error {
+		if err := r.taxonomies.Tx(tx).Update(ctx, query.Taxonomy); err != nil {
+			return mapError(err)
+		}
+
+		return mapError(r.record(ctx, tx, query, taxonomy.OperationUpdate, query.Taxonomy.ID))
+	}, query.Taxonomy.ID)
 }
 
-// Delete SQLite implementation for a taxonomy repository
+// Delete SQLite implementation for a taxonomy repository, marks the taxonomy
+// as deleted, returns repository.ErrNotFound when no taxonomy has the ID of
+// the query and repository.ErrConflict while it has children
 func (r *SQLiteRepository) Delete(ctx context.Context, query taxonomy.Query) error {
+	id := query.Taxonomy.ID
+
+	return r.inTx(ctx, func(tx kryptonsqlx.Tx) error {
+		if err := r.taxonomies.Tx(tx).Delete(ctx, id); err != nil {
+			if !errors.Is(err, repo.ErrNotFound) {
+				return mapError(err)
+			}
+
+			exists := false
+
+			if err := tx.GetContext(ctx, &exists, queryTaxonomyExists, id, false); err != nil {
+				return mapError(err)
+			}
+
+			if !exists {
+				return repository.ErrNotFound
+			}
+
+			return fmt.Errorf("%w: taxonomy %s has children", repository.ErrConflict, id)
+		}
+
+		return mapError(r.record(ctx, tx, query, taxonomy.OperationDelete, id))
+	}, id)
+}
+
+// inTx runs fn in a transaction, the taxonomies with the IDs, when given, are
+// passed to the invalidation hook once it is committed
+func (r *SQLiteRepository) inTx(ctx context.Context, fn func(tx kryptonsqlx.Tx) error, ids ...string) error {
+	if err := inTx(ctx, r.db, fn); err != nil {
+		return err
+	}
+
+	if len(ids) > 0 {
+		r.changed(ids...)
+	}
+
+	return nil
+}
+
+// changed passes the IDs of the taxonomies written to the invalidation hook
+// when the repository has one
+func (r *SQLiteRepository) changed(ids ...string) {
+	if r.invalidate != nil {
+		r.invalidate(ids...)
+	}
+}
+
+// inTx runs fn in a transaction of db, committed when fn succeeds
+func inTx(ctx context.Context, db kryptonsqlx.DB, fn func(tx kryptonsqlx.Tx) error) error {
+	tx, err := kryptonsqlx.BeginTransaction(ctx, db, nil)
+	if err != nil {
+		return mapError(err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return mapError(tx.Commit())
+}
+
+// limit the LIMIT of a page, SQLite treats a negative limit as no limit
+func limit(p repository.Pagination) int64 {
+	if p.Limit == 0 {
+		return -1
+	}
+
+	return int64(p.Limit)
+}
+
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+func requireAffected(res stdsql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return repository.ErrNotFound
+	}
+
 	return nil
 }
+
+// mapError maps SQLite errors to the repository sentinel errors, keeping the
+// original error in the chain
+func mapError(err error) error {
+	switch {
+	case sqlerr.IsNotFound(err):
+		return fmt.Errorf("%w: %w", repository.ErrNotFound, err)
+	case sqlerr.IsConstraintViolation(err):
+		return fmt.Errorf("%w: %w", repository.ErrConflict, err)
+	}
+
+	return err
+}
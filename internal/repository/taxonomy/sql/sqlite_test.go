@@ -0,0 +1,519 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/migrate"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/outbox"
+)
+
+// newSQLiteDB returns a migrated SQLite database in a temporary file, with an
+// outbox and without the search index when SQLite lacks FTS5
+func newSQLiteDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	ctx := context.Background()
+
+	db, err := sqlx.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "taxonomy.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	searchable, err := SQLiteSupportsSearch(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := []migrate.MigratorOption{
+		migrate.MigratorWithDB(db),
+		migrate.MigratorWithFS(SQLiteMigrations()),
+	}
+
+	if !searchable {
+		opts = append(opts, migrate.MigratorWithSkip(SQLiteSearchMigration))
+	}
+
+	if err := migrate.NewMigrator(opts...).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrate.NewMigrator(
+		migrate.MigratorWithDB(db),
+		migrate.MigratorWithFS(outbox.SQLiteMigrations()),
+		migrate.MigratorWithTable(outbox.MigrationsTable),
+	).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// create creates taxonomies with the names, roots unless parentID is set
+func create(t *testing.T, r taxonomy.Repository, parentID *string, names ...string) []model.Taxonomy {
+	t.Helper()
+
+	created := []model.Taxonomy{}
+
+	for _, name := range names {
+		res, err := r.Create(context.Background(), taxonomy.Query{
+			Taxonomy: model.Taxonomy{Name: name, ParentID: parentID},
+		})
+		if err != nil {
+			t.Fatalf("Create(%s) error = %v", name, err)
+		}
+
+		created = append(created, res.Results[0])
+	}
+
+	return created
+}
+
+func TestInvalidator(t *testing.T) {
+	ctx := context.Background()
+
+	invalidated := [][]string{}
+
+	r := NewSQLiteRepository(
+		SQLiteWithDB(newSQLiteDB(t)),
+		SQLiteWithInvalidator(func(ids ...string) {
+			invalidated = append(invalidated, ids)
+		}),
+	)
+
+	roots := create(t, r, nil, "a", "b")
+	a, b := roots[0].ID, roots[1].ID
+
+	tests := []struct {
+		name  string
+		write func() error
+		want  []string
+	}{
+		{"move", func() error {
+			return r.Move(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: b, ParentID: &a}})
+		}, []string{b}},
+		{"failed move", func() error {
+			r.Move(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: a, ParentID: &b}})
+			return nil
+		}, nil},
+		{"delete", func() error {
+			return r.Delete(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: b}})
+		}, []string{b}},
+		{"restore", func() error {
+			return r.Restore(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: b}})
+		}, []string{b}},
+		{"batch", func() error {
+			_, err := r.DeleteMany(ctx, taxonomy.Query{Taxonomies: []model.Taxonomy{{ID: a}, {ID: b}}})
+			return err
+		}, []string{b}},
+		{"purge", func() error {
+			_, err := r.Purge(ctx, -time.Hour)
+			return err
+		}, []string{b}},
+	}
+
+	for _, tt := range tests {
+		invalidated = nil
+
+		if err := tt.write(); err != nil {
+			t.Fatalf("%s: error = %v", tt.name, err)
+		}
+
+		switch {
+		case tt.want == nil && len(invalidated) > 0:
+			t.Errorf("%s: invalidated %v, want nothing", tt.name, invalidated)
+		case tt.want != nil && (len(invalidated) != 1 || !slices.Equal(invalidated[0], tt.want)):
+			t.Errorf("%s: invalidated %v, want %v", tt.name, invalidated, tt.want)
+		}
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteDB(t)
+
+	r := NewSQLiteRepository(
+		SQLiteWithDB(db),
+		SQLiteWithOutbox(outbox.NewOutbox(outbox.OutboxWithDB(db))),
+	)
+
+	roots := create(t, r, nil, "a")
+	create(t, r, &roots[0].ID, "b")
+
+	// the failed writes roll their messages back
+	if _, err := r.Create(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{Name: "a"}}); !errors.Is(err, repository.ErrConflict) {
+		t.Fatalf("Create() error = %v, want %v", err, repository.ErrConflict)
+	}
+
+	if err := r.Delete(ctx, taxonomy.Query{Taxonomy: roots[0]}); !errors.Is(err, repository.ErrConflict) {
+		t.Fatalf("Delete() error = %v, want %v", err, repository.ErrConflict)
+	}
+
+	topics := []string{}
+
+	if err := db.SelectContext(ctx, &topics, "SELECT topic FROM outbox ORDER BY id"); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"taxonomy.create", "taxonomy.create"}; !slices.Equal(topics, want) {
+		t.Errorf("outbox topics = %v, want %v", topics, want)
+	}
+}
+
+// shape returns the taxonomies as nested names, a(b(c)) d
+func shape(taxonomies []model.Taxonomy) string {
+	s := ""
+
+	for i, t := range taxonomies {
+		if i > 0 {
+			s += " "
+		}
+
+		s += t.Name
+
+		if len(t.Children) > 0 {
+			s += "(" + shape(t.Children) + ")"
+		}
+	}
+
+	return s
+}
+
+func TestHierarchy(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewSQLiteRepository(SQLiteWithDB(newSQLiteDB(t)))
+
+	roots := create(t, r, nil, "a", "d")
+	a, d := roots[0].ID, roots[1].ID
+	b := create(t, r, &a, "b")[0].ID
+	c := create(t, r, &b, "c")[0].ID
+
+	reads := []struct {
+		name string
+		read func() (taxonomy.Response, error)
+		want string
+	}{
+		{"children", func() (taxonomy.Response, error) {
+			return r.Children(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: a}})
+		}, "b"},
+		{"roots", func() (taxonomy.Response, error) {
+			return r.Children(ctx, taxonomy.Query{})
+		}, "a d"},
+		{"ancestors", func() (taxonomy.Response, error) {
+			return r.Ancestors(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: c}})
+		}, "a b c"},
+		{"subtree", func() (taxonomy.Response, error) {
+			return r.Subtree(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: a}})
+		}, "a(b(c))"},
+		{"subtree to depth 1", func() (taxonomy.Response, error) {
+			return r.Subtree(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: a}, Depth: 1})
+		}, "a(b)"},
+		{"forest", func() (taxonomy.Response, error) {
+			return r.Subtree(ctx, taxonomy.Query{})
+		}, "a(b(c)) d"},
+	}
+
+	for _, tt := range reads {
+		res, err := tt.read()
+		if err != nil {
+			t.Fatalf("%s: error = %v", tt.name, err)
+		}
+
+		got := shape(res.Results)
+		if tt.name == "ancestors" {
+			got = strings.Join(names(res.Results), " ")
+		}
+
+		if got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	for _, read := range []func() (taxonomy.Response, error){
+		func() (taxonomy.Response, error) {
+			return r.Children(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: "1000"}})
+		},
+		func() (taxonomy.Response, error) {
+			return r.Ancestors(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: "1000"}})
+		},
+		func() (taxonomy.Response, error) {
+			return r.Subtree(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: "1000"}})
+		},
+	} {
+		if _, err := read(); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("read of a missing taxonomy error = %v, want %v", err, repository.ErrNotFound)
+		}
+	}
+
+	missing := "1000"
+
+	moves := []struct {
+		name     string
+		id       string
+		parentID *string
+		want     error
+	}{
+		{"under itself", a, &a, repository.ErrConflict},
+		{"under its child", a, &b, repository.ErrConflict},
+		{"under its grandchild", a, &c, repository.ErrConflict},
+		{"under a missing parent", c, &missing, repository.ErrConflict},
+		{"missing", missing, &a, repository.ErrNotFound},
+		{"under a root", c, &d, nil},
+		{"to the roots", b, nil, nil},
+	}
+
+	for _, tt := range moves {
+		err := r.Move(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: tt.id, ParentID: tt.parentID}})
+
+		if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Errorf("move %s error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	res, err := r.Subtree(ctx, taxonomy.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := shape(res.Results), "a d(c) b"; got != want {
+		t.Errorf("forest after the moves = %s, want %s", got, want)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteDB(t)
+
+	r := NewSQLiteRepository(SQLiteWithDB(db))
+
+	a := create(t, r, nil, "a")[0].ID
+	b := create(t, r, &a, "b")[0].ID
+	c := create(t, r, &b, "c")[0].ID
+
+	del := func(id string) error {
+		return r.Delete(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: id}})
+	}
+	restore := func(id string) error {
+		return r.Restore(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: id}})
+	}
+
+	if err := del(a); !errors.Is(err, repository.ErrConflict) {
+		t.Fatalf("Delete() of a parent error = %v, want %v", err, repository.ErrConflict)
+	}
+
+	for _, id := range []string{c, b, a} {
+		if err := del(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// deleted taxonomies are only read when asked for
+	if _, err := r.Read(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: b}}); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Read() of a deleted taxonomy error = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	res, err := r.Read(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: b}, IncludeDeleted: true})
+	if err != nil || res.Results[0].DeletedAt == nil {
+		t.Errorf("Read() including deleted = %+v %v, want b with its deletion time", res.Results, err)
+	}
+
+	// the name of a deleted taxonomy can be taken, which then keeps it from
+	// being restored
+	taken := create(t, r, nil, "a")[0].ID
+
+	restores := []struct {
+		name string
+		id   string
+		want error
+	}{
+		{"under a deleted parent", b, repository.ErrConflict},
+		{"with a taken name", a, repository.ErrConflict},
+		{"live", taken, repository.ErrNotFound},
+		{"missing", "1000", repository.ErrNotFound},
+	}
+
+	for _, tt := range restores {
+		if err := restore(tt.id); !errors.Is(err, tt.want) {
+			t.Errorf("Restore() %s error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	if err := del(taken); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{a, b} {
+		if err := restore(id); err != nil {
+			t.Fatalf("Restore(%s) error = %v", id, err)
+		}
+	}
+
+	if err := del(b); err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing was deleted long enough ago
+	if n, err := r.Purge(ctx, time.Hour); err != nil || n != 0 {
+		t.Errorf("Purge(1h) = %d %v, want 0", n, err)
+	}
+
+	// c is purged before b, which is then no longer referenced, a is live
+	if n, err := r.Purge(ctx, -time.Hour); err != nil || n != 3 {
+		t.Errorf("Purge(-1h) = %d %v, want 3", n, err)
+	}
+
+	ids := []string{}
+
+	if err := db.SelectContext(ctx, &ids, "SELECT id FROM taxonomy ORDER BY id"); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{a}; !slices.Equal(ids, want) {
+		t.Errorf("taxonomies after the purge = %v, want %v", ids, want)
+	}
+
+	purges := []string{}
+
+	if err := db.SelectContext(ctx, &purges, "SELECT taxonomy_id FROM taxonomy_changes WHERE operation = 'purge' ORDER BY seq"); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{c, taken, b}; !slices.Equal(purges, want) {
+		t.Errorf("purges = %v, want %v, leaves first", purges, want)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewSQLiteRepository(SQLiteWithDB(newSQLiteDB(t)))
+
+	// the times of the history have millisecond precision, each change is
+	// made in a later millisecond than the time before it
+	tick := func() time.Time {
+		time.Sleep(5 * time.Millisecond)
+		defer time.Sleep(5 * time.Millisecond)
+
+		return time.Now()
+	}
+
+	before := tick()
+	parent := create(t, r, nil, "parent")[0].ID
+	id := create(t, r, nil, "a")[0].ID
+	created := tick()
+
+	if err := r.Update(ctx, taxonomy.Query{
+		Taxonomy: model.Taxonomy{ID: id, Name: "b"},
+		Actor:    "alice",
+		Reason:   "typo",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	updated := tick()
+
+	if err := r.Move(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: id, ParentID: &parent}}); err != nil {
+		t.Fatal(err)
+	}
+
+	moved := tick()
+
+	if err := r.Delete(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: id}}); err != nil {
+		t.Fatal(err)
+	}
+
+	reads := []struct {
+		name string
+		asOf time.Time
+		want string
+		err  error
+	}{
+		{"before the creation", before, "", repository.ErrNotFound},
+		{"after the creation", created, "a", nil},
+		{"after the update", updated, "b", nil},
+		{"after the move", moved, "b", nil},
+		{"now", time.Time{}, "", repository.ErrNotFound},
+	}
+
+	for _, tt := range reads {
+		res, err := r.Read(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: id}, AsOf: tt.asOf})
+
+		if !errors.Is(err, tt.err) || (err == nil && res.Results[0].Name != tt.want) {
+			t.Errorf("Read() as of %s = %+v %v, want %q %v", tt.name, res.Results, err, tt.want, tt.err)
+		}
+	}
+
+	// pages of the past read the revisions valid at the time too
+	res, err := r.Read(ctx, taxonomy.Query{Pagination: repository.Pagination{Limit: 10}, AsOf: updated})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := names(res.Results), []string{"parent", "b"}; !slices.Equal(got, want) {
+		t.Errorf("Read() of a page as of the update = %v, want %v", got, want)
+	}
+
+	res, err = r.History(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: id}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revisions := []struct {
+		operation string
+		actor     string
+		fields    []string
+	}{
+		{taxonomy.OperationCreate, "", []string{"name"}},
+		{taxonomy.OperationUpdate, "alice", []string{"name"}},
+		{taxonomy.OperationMove, "", []string{"parent_id"}},
+		{taxonomy.OperationDelete, "", []string{"deleted_at"}},
+	}
+
+	if len(res.Revisions) != len(revisions) {
+		t.Fatalf("History() = %d revisions, want %d", len(res.Revisions), len(revisions))
+	}
+
+	for i, want := range revisions {
+		got := res.Revisions[i]
+
+		fields := []string{}
+		for _, c := range got.Changes {
+			fields = append(fields, c.Field)
+		}
+
+		if got.Operation != want.operation || got.Actor != want.actor || !slices.Equal(fields, want.fields) {
+			t.Errorf("revision %d = %s by %q changing %v, want %s by %q changing %v",
+				i, got.Operation, got.Actor, fields, want.operation, want.actor, want.fields)
+		}
+
+		// only the current revision is open
+		if (got.ValidTo == nil) != (i == len(revisions)-1) {
+			t.Errorf("revision %d valid to %v", i, got.ValidTo)
+		}
+	}
+
+	if c := res.Revisions[0].Changes[0]; c.From != nil || c.To != "a" {
+		t.Errorf("creation change = %+v, want from nil to a", c)
+	}
+
+	if c := res.Revisions[1].Changes[0]; c.From != "a" || c.To != "b" || res.Revisions[1].Reason != "typo" {
+		t.Errorf("update change = %+v for %q, want from a to b for typo", c, res.Revisions[1].Reason)
+	}
+
+	if _, err := r.History(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: "1000"}}); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("History() of a missing taxonomy error = %v, want %v", err, repository.ErrNotFound)
+	}
+}
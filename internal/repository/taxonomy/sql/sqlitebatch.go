@@ -0,0 +1,315 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/dialect"
+)
+
+const (
+	// maxVariables SQLITE_MAX_VARIABLE_NUMBER of the SQLite bundled with
+	// go-sqlite3, the most parameters a statement may have
+	maxVariables = 32766
+	// maxBatchRows the most items written by a statement, SQLite resolves
+	// numbered parameters linearly so statements near the variable limit
+	// are much slower than a few smaller ones
+	maxBatchRows = 500
+)
+
+// the values of the items of a chunk replace %s, the rows written are
+// returned in no particular order and are matched to the items by key
+const (
+	queryCreateManyTaxonomies = `
+		INSERT INTO taxonomy (name, parent_id) VALUES %s
+		ON CONFLICT (name) WHERE deleted_at IS NULL DO NOTHING
+		RETURNING id, name, parent_id, created_at, updated_at, deleted_at`
+	// a conflicting taxonomy is moved only when the new parent is not in its
+	// own subtree
+	queryUpsertTaxonomies = `
+		INSERT INTO taxonomy (name, parent_id) VALUES %s
+		ON CONFLICT (name) WHERE deleted_at IS NULL DO UPDATE SET parent_id = excluded.parent_id, updated_at = CURRENT_TIMESTAMP
+		WHERE excluded.parent_id IS NULL OR NOT EXISTS (
+			WITH RECURSIVE ancestors (id) AS (
+				SELECT excluded.parent_id
+				UNION ALL
+				SELECT t.parent_id FROM taxonomy t JOIN ancestors a ON t.id = a.id WHERE t.parent_id IS NOT NULL
+			)
+			SELECT 1 FROM ancestors WHERE id = taxonomy.id
+		)
+		RETURNING id, name, parent_id, created_at, updated_at, deleted_at`
+	queryDeleteManyTaxonomies = `
+		UPDATE taxonomy SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id IN (%s) AND deleted_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM taxonomy c WHERE c.parent_id = taxonomy.id AND c.deleted_at IS NULL)
+		RETURNING id, name, parent_id, created_at, updated_at, deleted_at`
+)
+
+// batch describes a statement writing many taxonomies, missing is the error
+// of the items the statement did not return a row for, operation is recorded
+// in the history of the rows it did return
+type batch struct {
+	query     string
+	operation string
+	columns   int
+	values    func(t model.Taxonomy) []any
+	key       func(t model.Taxonomy) string
+	missing   func(ctx context.Context, tx kryptonsqlx.Tx, t model.Taxonomy) error
+}
+
+var (
+	batchCreate = batch{
+		query:     queryCreateManyTaxonomies,
+		operation: taxonomy.OperationCreate,
+		columns:   2,
+		values:    nameAndParent,
+		key:       taxonomyName,
+		missing: func(_ context.Context, _ kryptonsqlx.Tx, t model.Taxonomy) error {
+			return fmt.Errorf("%w: name %q is taken", repository.ErrConflict, t.Name)
+		},
+	}
+	batchUpsert = batch{
+		query:     queryUpsertTaxonomies,
+		operation: taxonomy.OperationMove,
+		columns:   2,
+		values:    nameAndParent,
+		key:       taxonomyName,
+		missing: func(_ context.Context, _ kryptonsqlx.Tx, t model.Taxonomy) error {
+			return fmt.Errorf("%w: taxonomy %q can not be moved under its own subtree", repository.ErrConflict, t.Name)
+		},
+	}
+	batchDelete = batch{
+		query:     queryDeleteManyTaxonomies,
+		operation: taxonomy.OperationDelete,
+		columns:   1,
+		values: func(t model.Taxonomy) []any {
+			return []any{t.ID}
+		},
+		key: func(t model.Taxonomy) string {
+			return t.ID
+		},
+		// a live taxonomy left alone has children
+		missing: func(ctx context.Context, tx kryptonsqlx.Tx, t model.Taxonomy) error {
+			exists := false
+
+			if err := tx.GetContext(ctx, &exists, queryTaxonomyExists, t.ID, false); err != nil {
+				return mapError(err)
+			}
+
+			if !exists {
+				return repository.ErrNotFound
+			}
+
+			return fmt.Errorf("%w: taxonomy %s has children", repository.ErrConflict, t.ID)
+		},
+	}
+)
+
+// CreateMany SQLite implementation for a taxonomy batch repository
+func (r *SQLiteRepository) CreateMany(ctx context.Context, query taxonomy.Query) (taxonomy.BatchResponse, error) {
+	return r.batch(ctx, query, batchCreate)
+}
+
+// Upsert SQLite implementation for a taxonomy batch repository, taxonomies
+// are matched by name
+func (r *SQLiteRepository) Upsert(ctx context.Context, query taxonomy.Query) (taxonomy.BatchResponse, error) {
+	return r.batch(ctx, query, batchUpsert)
+}
+
+// DeleteMany SQLite implementation for a taxonomy batch repository, marks the
+// taxonomies as deleted, taxonomies with children are refused with
+// repository.ErrConflict
+func (r *SQLiteRepository) DeleteMany(ctx context.Context, query taxonomy.Query) (taxonomy.BatchResponse, error) {
+	return r.batch(ctx, query, batchDelete)
+}
+
+// batch writes the items of the query in chunks under SQLite's variable
+// limit, the failure of a statement is reported for the items causing it only
+func (r *SQLiteRepository) batch(ctx context.Context, query taxonomy.Query, b batch) (taxonomy.BatchResponse, error) {
+	items := query.Taxonomies
+	results := make([]taxonomy.BatchResult, len(items))
+	pending := make([]int, 0, len(items))
+	seen := map[string]int{}
+
+	for i, t := range items {
+		results[i] = taxonomy.BatchResult{
+			Index:    i,
+			Taxonomy: t,
+		}
+
+		if first, ok := seen[b.key(t)]; ok {
+			results[i].Err = fmt.Errorf("%w: duplicate of item %d", repository.ErrConflict, first)
+			continue
+		}
+
+		seen[b.key(t)] = i
+		pending = append(pending, i)
+	}
+
+	tx, err := kryptonsqlx.BeginTransaction(ctx, r.db, nil)
+	if err != nil {
+		return taxonomy.BatchResponse{}, mapError(err)
+	}
+	defer tx.Rollback()
+
+	size := min(maxBatchRows, maxVariables/b.columns)
+	done := len(items) - len(pending)
+
+	for start := 0; start < len(pending); start += size {
+		chunk := pending[start:min(start+size, len(pending))]
+
+		if err := r.bisect(ctx, tx, query, b, chunk, results); err != nil {
+			return taxonomy.BatchResponse{}, err
+		}
+
+		done += len(chunk)
+
+		if query.Progress != nil {
+			query.Progress(done, len(items))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return taxonomy.BatchResponse{}, mapError(err)
+	}
+
+	res := taxonomy.BatchResponse{
+		Results: results,
+	}
+
+	ids := []string{}
+
+	for _, result := range results {
+		if result.Err != nil {
+			res.Failed++
+		} else {
+			res.Succeeded++
+			ids = append(ids, result.Taxonomy.ID)
+		}
+	}
+
+	if len(ids) > 0 {
+		r.changed(ids...)
+	}
+
+	return res, nil
+}
+
+// bisect writes the items of the chunk, splitting the chunk in halves when
+// its statement fails until the items failing it are isolated, only the
+// cancellation of ctx is returned as an error
+func (r *SQLiteRepository) bisect(ctx context.Context, tx kryptonsqlx.Tx, query taxonomy.Query, b batch, chunk []int, results []taxonomy.BatchResult) error {
+	err := savepoint(ctx, tx, func() error {
+		return r.execBatch(ctx, tx, query, b, chunk, results)
+	})
+
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case len(chunk) == 1:
+		results[chunk[0]].Err = mapError(err)
+		return nil
+	}
+
+	half := len(chunk) / 2
+
+	if err := r.bisect(ctx, tx, query, b, chunk[:half], results); err != nil {
+		return err
+	}
+
+	return r.bisect(ctx, tx, query, b, chunk[half:], results)
+}
+
+// execBatch runs the statement of b for the items of the chunk, recording the
+// rows written in their results and in the history
+func (r *SQLiteRepository) execBatch(ctx context.Context, tx kryptonsqlx.Tx, query taxonomy.Query, b batch, chunk []int, results []taxonomy.BatchResult) error {
+	args := make([]any, 0, len(chunk)*b.columns)
+	for _, i := range chunk {
+		args = append(args, b.values(results[i].Taxonomy)...)
+	}
+
+	written := []model.Taxonomy{}
+
+	if err := tx.SelectContext(ctx, &written, fmt.Sprintf(b.query, values(r.dialect, len(chunk), b.columns)), args...); err != nil {
+		return err
+	}
+
+	byKey := make(map[string]model.Taxonomy, len(written))
+	ids := make([]string, 0, len(written))
+
+	for _, t := range written {
+		byKey[b.key(t)] = t
+		ids = append(ids, t.ID)
+	}
+
+	if err := r.record(ctx, tx, query, b.operation, ids...); err != nil {
+		return err
+	}
+
+	for _, i := range chunk {
+		t, ok := byKey[b.key(results[i].Taxonomy)]
+		if !ok {
+			results[i].Err = b.missing(ctx, tx, results[i].Taxonomy)
+			continue
+		}
+
+		results[i].Taxonomy = t
+	}
+
+	return nil
+}
+
+// savepoint runs fn in a savepoint of tx, undoing what fn wrote when it fails
+// without aborting the transaction
+func savepoint(ctx context.Context, tx kryptonsqlx.Tx, fn func() error) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT batch"); err != nil {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		tx.ExecContext(ctx, "ROLLBACK TO batch")
+		tx.ExecContext(ctx, "RELEASE batch")
+
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, "RELEASE batch")
+
+	return err
+}
+
+// values returns the placeholders of the dialect for n items of columns
+// values each, as rows of a VALUES clause when there is more than one column
+func values(d dialect.Dialect, n, columns int) string {
+	items := make([]string, n)
+
+	for i := range items {
+		placeholders := make([]string, columns)
+		for j := range placeholders {
+			placeholders[j] = d.Placeholder(i*columns + j + 1)
+		}
+
+		items[i] = strings.Join(placeholders, ", ")
+
+		if columns > 1 {
+			items[i] = "(" + items[i] + ")"
+		}
+	}
+
+	return strings.Join(items, ", ")
+}
+
+func nameAndParent(t model.Taxonomy) []any {
+	return []any{t.Name, t.ParentID}
+}
+
+func taxonomyName(t model.Taxonomy) string {
+	return t.Name
+}
@@ -0,0 +1,233 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/dialect"
+)
+
+func items(n int) []model.Taxonomy {
+	taxonomies := make([]model.Taxonomy, n)
+	for i := range taxonomies {
+		taxonomies[i].Name = fmt.Sprintf("taxonomy %d", i)
+	}
+
+	return taxonomies
+}
+
+func TestCreateManyBisect(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteDB(t)
+
+	r := NewSQLiteRepository(SQLiteWithDB(db))
+
+	// a missing parent fails the statement of the whole chunk, the ID is out
+	// of reach of the taxonomies created
+	missing := "1000000"
+	query := taxonomy.Query{Taxonomies: items(maxBatchRows)}
+	query.Taxonomies[317].ParentID = &missing
+
+	res, err := r.CreateMany(ctx, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Succeeded != maxBatchRows-1 || res.Failed != 1 {
+		t.Errorf("CreateMany() succeeded %d and failed %d, want %d and 1", res.Succeeded, res.Failed, maxBatchRows-1)
+	}
+
+	for i, result := range res.Results {
+		switch {
+		case i == 317 && !errors.Is(result.Err, repository.ErrConflict):
+			t.Errorf("item %d error = %v, want %v", i, result.Err, repository.ErrConflict)
+		case i != 317 && result.Err != nil:
+			t.Errorf("item %d error = %v", i, result.Err)
+		case i != 317 && (result.Taxonomy.ID == "" || result.Taxonomy.Name != query.Taxonomies[i].Name):
+			t.Errorf("item %d = %+v, want %s written", i, result.Taxonomy, query.Taxonomies[i].Name)
+		}
+	}
+
+	// the writes of the statements rolled back are neither kept nor logged
+	for table, want := range map[string]int{
+		"taxonomy":         maxBatchRows - 1,
+		"taxonomy_history": maxBatchRows - 1,
+		"taxonomy_changes": maxBatchRows - 1,
+	} {
+		n := 0
+
+		if err := db.GetContext(ctx, &n, "SELECT COUNT(*) FROM "+table); err != nil {
+			t.Fatal(err)
+		}
+
+		if n != want {
+			t.Errorf("%s has %d rows, want %d", table, n, want)
+		}
+	}
+}
+
+func TestCreateManyConflicts(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewSQLiteRepository(SQLiteWithDB(newSQLiteDB(t)))
+
+	create(t, r, nil, "taken")
+
+	res, err := r.CreateMany(ctx, taxonomy.Query{
+		Taxonomies: []model.Taxonomy{{Name: "a"}, {Name: "taken"}, {Name: "a"}, {Name: "b"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []error{nil, repository.ErrConflict, repository.ErrConflict, nil} {
+		if err := res.Results[i].Err; !errors.Is(err, want) || (want == nil) != (err == nil) {
+			t.Errorf("item %d error = %v, want %v", i, err, want)
+		}
+	}
+}
+
+func TestBatchProgress(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewSQLiteRepository(SQLiteWithDB(newSQLiteDB(t)))
+
+	// duplicates are done before any chunk is written
+	taxonomies := items(2*maxBatchRows + 1)
+	taxonomies = append(taxonomies, taxonomies[0], taxonomies[1])
+
+	type call struct{ done, total int }
+
+	calls := []call{}
+
+	res, err := r.CreateMany(ctx, taxonomy.Query{
+		Taxonomies: taxonomies,
+		Progress: func(done, total int) {
+			calls = append(calls, call{done, total})
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	total := len(taxonomies)
+	want := []call{{2 + maxBatchRows, total}, {2 + 2*maxBatchRows, total}, {total, total}}
+
+	if !slices.Equal(calls, want) {
+		t.Errorf("Progress() calls = %v, want %v", calls, want)
+	}
+
+	if res.Succeeded != 2*maxBatchRows+1 || res.Failed != 2 {
+		t.Errorf("CreateMany() succeeded %d and failed %d", res.Succeeded, res.Failed)
+	}
+}
+
+func TestBatchVariableLimit(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteDB(t)
+
+	if _, err := db.ExecContext(ctx, "CREATE TABLE variable_limit (a, b)"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		columns int
+		query   string
+		row     string
+	}{
+		{"rows", 2, "INSERT INTO variable_limit (a, b) VALUES %s", "(?, ?)"},
+		{"values", 1, "DELETE FROM variable_limit WHERE a IN (%s)", "?"},
+	}
+
+	for _, tt := range tests {
+		// the largest chunk the variable limit allows is accepted, one more
+		// row is not, anonymous parameters keep binding them fast
+		rows := maxVariables / tt.columns
+
+		for _, n := range []int{rows, rows + 1} {
+			args := make([]any, n*tt.columns)
+			for i := range args {
+				args[i] = i
+			}
+
+			placeholders := strings.Repeat(tt.row+", ", n-1) + tt.row
+			_, err := db.ExecContext(ctx, fmt.Sprintf(tt.query, placeholders), args...)
+
+			switch {
+			case n == rows && err != nil:
+				t.Errorf("%s: %d rows error = %v", tt.name, n, err)
+			case n > rows && (err == nil || !strings.Contains(err.Error(), "too many SQL variables")):
+				t.Errorf("%s: %d rows error = %v, want too many SQL variables", tt.name, n, err)
+			}
+		}
+	}
+}
+
+func TestValues(t *testing.T) {
+	d := dialect.NewSQLite()
+
+	tests := []struct {
+		n, columns int
+		want       string
+	}{
+		{1, 1, "$1"},
+		{3, 1, "$1, $2, $3"},
+		{1, 2, "($1, $2)"},
+		{3, 2, "($1, $2), ($3, $4), ($5, $6)"},
+	}
+
+	for _, tt := range tests {
+		if got := values(d, tt.n, tt.columns); got != tt.want {
+			t.Errorf("values(%d, %d) = %q, want %q", tt.n, tt.columns, got, tt.want)
+		}
+	}
+}
+
+func TestDeleteMany(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewSQLiteRepository(SQLiteWithDB(newSQLiteDB(t)))
+
+	roots := create(t, r, nil, "a", "b")
+	children := create(t, r, &roots[0].ID, "c")
+
+	res, err := r.DeleteMany(ctx, taxonomy.Query{
+		Taxonomies: []model.Taxonomy{
+			{ID: "404"},
+			{ID: roots[0].ID},
+			{ID: roots[1].ID},
+			{ID: "405"},
+			{ID: children[0].ID},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a is only deleted once c, deleted by the same statement, is
+	for i, want := range []error{repository.ErrNotFound, repository.ErrConflict, nil, repository.ErrNotFound, nil} {
+		if err := res.Results[i].Err; !errors.Is(err, want) || (want == nil) != (err == nil) {
+			t.Errorf("item %d error = %v, want %v", i, err, want)
+		}
+	}
+
+	// deleting again finds nothing to delete
+	res, err = r.DeleteMany(ctx, taxonomy.Query{
+		Taxonomies: []model.Taxonomy{{ID: roots[1].ID}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !errors.Is(res.Results[0].Err, repository.ErrNotFound) {
+		t.Errorf("error = %v, want %v", res.Results[0].Err, repository.ErrNotFound)
+	}
+}
@@ -0,0 +1,137 @@
+package sql
+
+import (
+	"context"
+	"time"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/outbox"
+)
+
+// outboxTopic the prefix of the topics of the messages enqueued for changes,
+// followed by the operation, taxonomy.create for example
+const outboxTopic = "taxonomy"
+
+const (
+	queryAppendChange = `
+		INSERT INTO taxonomy_changes (taxonomy_id, revision, operation, created_at) VALUES ($1, $2, $3, $4)
+		RETURNING seq`
+	queryReadChanges = `
+		SELECT c.seq, c.operation, c.taxonomy_id, c.created_at AS changed_at,
+			h.name, h.parent_id, h.created_at, h.updated_at, h.deleted_at
+		FROM taxonomy_changes c LEFT JOIN taxonomy_history h ON h.revision = c.revision
+		WHERE c.seq > $1 ORDER BY c.seq LIMIT $2`
+	queryReadCheckpoint = `
+		SELECT COALESCE((SELECT seq FROM taxonomy_change_checkpoints WHERE consumer = $1), 0)`
+	querySaveCheckpoint = `
+		INSERT INTO taxonomy_change_checkpoints (consumer, seq) VALUES ($1, $2)
+		ON CONFLICT (consumer) DO UPDATE SET seq = MAX(seq, excluded.seq), updated_at = CURRENT_TIMESTAMP`
+)
+
+// change a row of the log, the columns of the revision are null for purges
+type change struct {
+	Seq        uint64     `db:"seq"`
+	Operation  string     `db:"operation"`
+	TaxonomyID string     `db:"taxonomy_id"`
+	ChangedAt  time.Time  `db:"changed_at"`
+	Name       *string    `db:"name"`
+	ParentID   *string    `db:"parent_id"`
+	CreatedAt  *time.Time `db:"created_at"`
+	UpdatedAt  *time.Time `db:"updated_at"`
+	DeletedAt  *time.Time `db:"deleted_at"`
+}
+
+// Changes SQLite implementation for a taxonomy feed repository
+func (r *SQLiteRepository) Changes(ctx context.Context, after uint64, limit uint) ([]taxonomy.Event, error) {
+	changes := []change{}
+
+	if err := r.db.SelectContext(ctx, &changes, queryReadChanges, after, limit); err != nil {
+		return nil, mapError(err)
+	}
+
+	events := make([]taxonomy.Event, len(changes))
+
+	for i, c := range changes {
+		events[i] = taxonomy.Event{
+			Seq:        c.Seq,
+			Operation:  c.Operation,
+			TaxonomyID: c.TaxonomyID,
+			CreatedAt:  c.ChangedAt,
+		}
+
+		if c.Name != nil {
+			events[i].Taxonomy = &model.Taxonomy{
+				ID:        c.TaxonomyID,
+				Name:      *c.Name,
+				ParentID:  c.ParentID,
+				CreatedAt: *c.CreatedAt,
+				UpdatedAt: *c.UpdatedAt,
+				DeletedAt: c.DeletedAt,
+			}
+		}
+	}
+
+	return events, nil
+}
+
+// Checkpoint SQLite implementation for a taxonomy feed repository
+func (r *SQLiteRepository) Checkpoint(ctx context.Context, consumer string) (uint64, error) {
+	seq := uint64(0)
+
+	if err := r.db.GetContext(ctx, &seq, queryReadCheckpoint, consumer); err != nil {
+		return 0, mapError(err)
+	}
+
+	return seq, nil
+}
+
+// SaveCheckpoint SQLite implementation for a taxonomy feed repository
+func (r *SQLiteRepository) SaveCheckpoint(ctx context.Context, consumer string, seq uint64) error {
+	_, err := r.db.ExecContext(ctx, querySaveCheckpoint, consumer, seq)
+
+	return mapError(err)
+}
+
+// appendChange appends the change of the taxonomy to the log, revision is nil
+// when the taxonomy is gone, the change is also enqueued in the outbox when
+// the repository has one
+func (r *SQLiteRepository) appendChange(ctx context.Context, tx kryptonsqlx.Tx, id string, revision *uint64, operation, at string) error {
+	e := taxonomy.Event{
+		TaxonomyID: id,
+		Operation:  operation,
+	}
+
+	if err := tx.GetContext(ctx, &e.Seq, queryAppendChange, id, revision, operation, at); err != nil {
+		return err
+	}
+
+	if r.outbox == nil {
+		return nil
+	}
+
+	if revision != nil {
+		t := model.Taxonomy{}
+
+		if err := tx.GetContext(ctx, &t, queryReadTaxonomyByID, id, true); err != nil {
+			return err
+		}
+
+		e.Taxonomy = &t
+	}
+
+	createdAt, err := time.Parse(historyTimeFormat, at)
+	if err != nil {
+		return err
+	}
+
+	e.CreatedAt = createdAt
+
+	m, err := outbox.NewMessage(outboxTopic+"."+operation, id, e)
+	if err != nil {
+		return err
+	}
+
+	return r.outbox.Enqueue(ctx, tx, m)
+}
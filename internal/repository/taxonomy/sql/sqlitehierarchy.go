@@ -0,0 +1,231 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+)
+
+// an empty ID refers to the roots, SQLite compares the text of the ID with
+// the integer columns by their affinity, parameters are bound in the order
+// they first appear so they are numbered in that order
+const (
+	queryReadChildren = `
+		SELECT id, name, parent_id, created_at, updated_at, deleted_at FROM taxonomy
+		WHERE (($1 = '' AND parent_id IS NULL) OR parent_id = $1) AND ($2 OR deleted_at IS NULL)
+		ORDER BY id LIMIT $3 OFFSET $4`
+	queryReadAncestors = `
+		WITH RECURSIVE ancestors (id, name, parent_id, created_at, updated_at, deleted_at, depth) AS (
+			SELECT id, name, parent_id, created_at, updated_at, deleted_at, 0 FROM taxonomy
+			WHERE id = $1 AND ($2 OR deleted_at IS NULL)
+			UNION ALL
+			SELECT t.id, t.name, t.parent_id, t.created_at, t.updated_at, t.deleted_at, a.depth + 1
+			FROM taxonomy t JOIN ancestors a ON t.id = a.parent_id
+		)
+		SELECT id, name, parent_id, created_at, updated_at, deleted_at, depth FROM ancestors ORDER BY depth DESC`
+	queryReadSubtree = `
+		WITH RECURSIVE subtree (id, name, parent_id, created_at, updated_at, deleted_at, depth) AS (
+			SELECT id, name, parent_id, created_at, updated_at, deleted_at, 0 FROM taxonomy
+			WHERE (($1 = '' AND parent_id IS NULL) OR id = $1) AND ($2 OR deleted_at IS NULL)
+			UNION ALL
+			SELECT t.id, t.name, t.parent_id, t.created_at, t.updated_at, t.deleted_at, s.depth + 1
+			FROM taxonomy t JOIN subtree s ON t.parent_id = s.id
+			WHERE ($3 = 0 OR s.depth < $3) AND ($2 OR t.deleted_at IS NULL)
+		)
+		SELECT id, name, parent_id, created_at, updated_at, deleted_at, depth FROM subtree ORDER BY depth, id`
+	queryTaxonomyExists = `
+		SELECT EXISTS (SELECT 1 FROM taxonomy WHERE id = $1 AND ($2 OR deleted_at IS NULL))`
+	queryIsDescendant = `
+		WITH RECURSIVE descendants (id) AS (
+			SELECT id FROM taxonomy WHERE id = $1
+			UNION ALL
+			SELECT t.id FROM taxonomy t JOIN descendants d ON t.parent_id = d.id
+		)
+		SELECT EXISTS (SELECT 1 FROM descendants d JOIN taxonomy t ON t.id = d.id WHERE t.id = $2)`
+	queryMoveTaxonomy = `
+		UPDATE taxonomy SET parent_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND deleted_at IS NULL`
+)
+
+// node a taxonomy read by a recursive query, with its distance from the
+// taxonomy the query started from
+type node struct {
+	model.Taxonomy
+	Depth uint `db:"depth"`
+}
+
+// Children SQLite implementation for a taxonomy hierarchy repository,
+// returns repository.ErrNotFound when the parent does not exist
+func (r *SQLiteRepository) Children(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	children := []model.Taxonomy{}
+
+	if err := r.db.SelectContext(
+		ctx, &children, queryReadChildren,
+		query.Taxonomy.ID, query.IncludeDeleted, limit(query.Pagination), query.Pagination.Offset,
+	); err != nil {
+		return taxonomy.Response{}, mapError(err)
+	}
+
+	if len(children) == 0 && query.Taxonomy.ID != "" {
+		if err := r.requireExists(ctx, query.Taxonomy.ID, query.IncludeDeleted); err != nil {
+			return taxonomy.Response{}, err
+		}
+	}
+
+	return taxonomy.NewResponse(
+		taxonomy.ResponseWithPagination(repository.Pagination{
+			Limit:  query.Pagination.Limit,
+			Offset: query.Pagination.Offset,
+			Count:  uint(len(children)),
+		}),
+		taxonomy.ResponseWithResult(children...),
+	), nil
+}
+
+// Ancestors SQLite implementation for a taxonomy hierarchy repository, the
+// results start at the root and end with the taxonomy of the query
+func (r *SQLiteRepository) Ancestors(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	nodes := []node{}
+
+	if err := r.db.SelectContext(ctx, &nodes, queryReadAncestors, query.Taxonomy.ID, query.IncludeDeleted); err != nil {
+		return taxonomy.Response{}, mapError(err)
+	}
+
+	if len(nodes) == 0 {
+		return taxonomy.Response{}, repository.ErrNotFound
+	}
+
+	ancestors := make([]model.Taxonomy, 0, len(nodes))
+	for _, n := range nodes {
+		ancestors = append(ancestors, n.Taxonomy)
+	}
+
+	return taxonomy.NewResponse(
+		taxonomy.ResponseWithPagination(repository.Pagination{
+			Count: uint(len(ancestors)),
+		}),
+		taxonomy.ResponseWithResult(ancestors...),
+	), nil
+}
+
+// Subtree SQLite implementation for a taxonomy hierarchy repository, the
+// results hold the taxonomy of the query, or every root when its ID is
+// empty, with the descendants nested as children
+func (r *SQLiteRepository) Subtree(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	nodes := []node{}
+
+	if err := r.db.SelectContext(ctx, &nodes, queryReadSubtree, query.Taxonomy.ID, query.IncludeDeleted, query.Depth); err != nil {
+		return taxonomy.Response{}, mapError(err)
+	}
+
+	if len(nodes) == 0 && query.Taxonomy.ID != "" {
+		return taxonomy.Response{}, repository.ErrNotFound
+	}
+
+	roots := nest(nodes)
+
+	return taxonomy.NewResponse(
+		taxonomy.ResponseWithPagination(repository.Pagination{
+			Count: uint(len(roots)),
+		}),
+		taxonomy.ResponseWithResult(roots...),
+	), nil
+}
+
+// Move SQLite implementation for a taxonomy hierarchy repository, a nil
+// ParentID makes the taxonomy a root, moves under a missing parent or under
+// the taxonomy's own subtree return repository.ErrConflict
+func (r *SQLiteRepository) Move(ctx context.Context, query taxonomy.Query) error {
+	id, parentID := query.Taxonomy.ID, query.Taxonomy.ParentID
+
+	return r.inTx(ctx, func(tx kryptonsqlx.Tx) error {
+		exists := false
+
+		if err := tx.GetContext(ctx, &exists, queryTaxonomyExists, id, false); err != nil {
+			return mapError(err)
+		}
+
+		if !exists {
+			return repository.ErrNotFound
+		}
+
+		if parentID != nil {
+			if err := tx.GetContext(ctx, &exists, queryTaxonomyExists, *parentID, false); err != nil {
+				return mapError(err)
+			}
+
+			if !exists {
+				return fmt.Errorf("%w: parent taxonomy %s does not exist", repository.ErrConflict, *parentID)
+			}
+
+			cycle := false
+
+			if err := tx.GetContext(ctx, &cycle, queryIsDescendant, id, *parentID); err != nil {
+				return mapError(err)
+			}
+
+			if cycle {
+				return fmt.Errorf("%w: taxonomy %s can not be moved under its own subtree", repository.ErrConflict, id)
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, queryMoveTaxonomy, parentID, id); err != nil {
+			return mapError(err)
+		}
+
+		return mapError(r.record(ctx, tx, query, taxonomy.OperationMove, id))
+	}, id)
+}
+
+// requireExists returns repository.ErrNotFound when the taxonomy does not
+// exist, or is deleted and deleted taxonomies are not included
+func (r *SQLiteRepository) requireExists(ctx context.Context, id string, includeDeleted bool) error {
+	exists := false
+
+	if err := r.db.GetContext(ctx, &exists, queryTaxonomyExists, id, includeDeleted); err != nil {
+		return mapError(err)
+	}
+
+	if !exists {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// nest builds the trees of nodes ordered by depth, nodes at depth 0 are the
+// roots of the trees
+func nest(nodes []node) []model.Taxonomy {
+	roots := []int{}
+	children := map[string][]int{}
+
+	for i, n := range nodes {
+		if n.Depth == 0 || n.ParentID == nil {
+			roots = append(roots, i)
+			continue
+		}
+
+		children[*n.ParentID] = append(children[*n.ParentID], i)
+	}
+
+	var build func(i int) model.Taxonomy
+	build = func(i int) model.Taxonomy {
+		t := nodes[i].Taxonomy
+
+		for _, child := range children[t.ID] {
+			t.Children = append(t.Children, build(child))
+		}
+
+		return t
+	}
+
+	trees := make([]model.Taxonomy, 0, len(roots))
+	for _, root := range roots {
+		trees = append(trees, build(root))
+	}
+
+	return trees
+}
@@ -0,0 +1,170 @@
+package sql
+
+import (
+	"context"
+	"time"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+)
+
+// historyTimeFormat the format of the times of the history, fixed width so
+// they compare as text and with the milliseconds so changes made within a
+// second keep their order
+const historyTimeFormat = "2006-01-02 15:04:05.000"
+
+const (
+	queryCloseRevision = `
+		UPDATE taxonomy_history SET valid_to = $1 WHERE taxonomy_id = $2 AND valid_to IS NULL`
+	// the revision is a copy of the taxonomy as written by the transaction
+	queryCreateRevision = `
+		INSERT INTO taxonomy_history (
+			taxonomy_id, name, parent_id, created_at, updated_at, deleted_at,
+			operation, actor, reason, valid_from
+		)
+		SELECT id, name, parent_id, created_at, updated_at, deleted_at, $1, $2, $3, $4
+		FROM taxonomy WHERE id = $5
+		RETURNING revision`
+	queryReadRevisions = `
+		SELECT revision, taxonomy_id AS id, name, parent_id, created_at, updated_at, deleted_at,
+			operation, actor, reason, valid_from, valid_to
+		FROM taxonomy_history WHERE taxonomy_id = $1 ORDER BY revision`
+	// the revisions valid at $1 stand in for the taxonomy table, so filters
+	// and pages apply to them alike
+	queryTaxonomiesAsOf = `(
+		SELECT taxonomy_id AS id, name, parent_id, created_at, updated_at, deleted_at FROM taxonomy_history
+		WHERE valid_from <= $1 AND (valid_to IS NULL OR valid_to > $1)
+	) AS taxonomy`
+	queryReadTaxonomyByIDAsOf = `
+		SELECT taxonomy_id AS id, name, parent_id, created_at, updated_at, deleted_at FROM taxonomy_history
+		WHERE taxonomy_id = $1 AND ($2 OR deleted_at IS NULL)
+		AND valid_from <= $3 AND (valid_to IS NULL OR valid_to > $3)`
+)
+
+// revision a row of the history
+type revision struct {
+	model.Taxonomy
+	Revision  uint64     `db:"revision"`
+	Operation string     `db:"operation"`
+	Actor     string     `db:"actor"`
+	Reason    string     `db:"reason"`
+	ValidFrom time.Time  `db:"valid_from"`
+	ValidTo   *time.Time `db:"valid_to"`
+}
+
+// History SQLite implementation for a taxonomy history repository, returns
+// repository.ErrNotFound when the taxonomy has no revision, deleted
+// taxonomies keep their history until they are purged and after
+func (r *SQLiteRepository) History(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	rows := []revision{}
+
+	if err := r.db.SelectContext(ctx, &rows, queryReadRevisions, query.Taxonomy.ID); err != nil {
+		return taxonomy.Response{}, mapError(err)
+	}
+
+	if len(rows) == 0 {
+		return taxonomy.Response{}, repository.ErrNotFound
+	}
+
+	revisions := make([]taxonomy.Revision, len(rows))
+	previous := model.Taxonomy{}
+
+	for i, row := range rows {
+		revisions[i] = taxonomy.Revision{
+			Revision:  row.Revision,
+			Taxonomy:  row.Taxonomy,
+			Operation: row.Operation,
+			Actor:     row.Actor,
+			Reason:    row.Reason,
+			ValidFrom: row.ValidFrom,
+			ValidTo:   row.ValidTo,
+			Changes:   diff(previous, row.Taxonomy, i == 0),
+		}
+
+		previous = row.Taxonomy
+	}
+
+	return taxonomy.NewResponse(
+		taxonomy.ResponseWithPagination(repository.Pagination{
+			Count: uint(len(revisions)),
+		}),
+		taxonomy.ResponseWithRevisions(revisions...),
+	), nil
+}
+
+// record closes the current revisions of the taxonomies with the IDs, writes
+// their new ones valid from now and appends them to the change log, the first
+// revision of a taxonomy is its creation whatever the operation
+func (r *SQLiteRepository) record(ctx context.Context, tx kryptonsqlx.Tx, query taxonomy.Query, operation string, ids ...string) error {
+	now := historyTime(time.Now())
+
+	for _, id := range ids {
+		res, err := tx.ExecContext(ctx, queryCloseRevision, now, id)
+		if err != nil {
+			return err
+		}
+
+		closed, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		op := operation
+		if closed == 0 {
+			op = taxonomy.OperationCreate
+		}
+
+		revision := uint64(0)
+
+		if err := tx.GetContext(ctx, &revision, queryCreateRevision, op, query.Actor, query.Reason, now, id); err != nil {
+			return err
+		}
+
+		if err := r.appendChange(ctx, tx, id, &revision, op, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diff lists the fields of to which differ from from, from is the zero
+// taxonomy for the first revision so every field set by it is listed
+func diff(from, to model.Taxonomy, first bool) []taxonomy.Change {
+	changes := []taxonomy.Change{}
+
+	if from.Name != to.Name {
+		changes = append(changes, taxonomy.Change{Field: "name", From: from.Name, To: to.Name})
+	}
+
+	if !equal(from.ParentID, to.ParentID) {
+		changes = append(changes, taxonomy.Change{Field: "parent_id", From: from.ParentID, To: to.ParentID})
+	}
+
+	if !equalTime(from.DeletedAt, to.DeletedAt) {
+		changes = append(changes, taxonomy.Change{Field: "deleted_at", From: from.DeletedAt, To: to.DeletedAt})
+	}
+
+	if first {
+		for i := range changes {
+			changes[i].From = nil
+		}
+	}
+
+	return changes
+}
+
+func equal(a, b *string) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+func equalTime(a, b *time.Time) bool {
+	return a == nil && b == nil || a != nil && b != nil && a.Equal(*b)
+}
+
+// historyTime formats t the way the times of the history are stored
+func historyTime(t time.Time) string {
+	return t.UTC().Format(historyTimeFormat)
+}
@@ -3,8 +3,10 @@ package sql
 import (
 	"log/slog"
 
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
 	"github.com/olireadcopper/sqlxprototype/pkg/sqlx"
 	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/logging"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/outbox"
 )
 
 func SQLiteWithDB(db sqlx.DB) SQLiteRepositoryOption {
@@ -13,6 +15,32 @@ func SQLiteWithDB(db sqlx.DB) SQLiteRepositoryOption {
 	}
 }
 
+// SQLiteWithCursorKey sets the key signing pagination cursors, instances
+// sharing the key accept each other's cursors
+func SQLiteWithCursorKey(key []byte) SQLiteRepositoryOption {
+	return func(r *SQLiteRepository) {
+		r.cursors = repository.NewCursorCodec(key)
+	}
+}
+
+// SQLiteWithOutbox enqueues a message in the outbox for every change, in the
+// transaction making the change, the topic is taxonomy. followed by the
+// operation and the payload a taxonomy.Event
+func SQLiteWithOutbox(o *outbox.Outbox) SQLiteRepositoryOption {
+	return func(r *SQLiteRepository) {
+		r.outbox = o
+	}
+}
+
+// SQLiteWithInvalidator sets a hook called with the IDs of the taxonomies
+// written once their writes are committed, for caches of the repository's
+// reads to invalidate them, moves, restores, purges and batches included
+func SQLiteWithInvalidator(fn func(ids ...string)) SQLiteRepositoryOption {
+	return func(r *SQLiteRepository) {
+		r.invalidate = fn
+	}
+}
+
 func SQLiteWithLogging(l *slog.Logger) SQLiteRepositoryOption {
 	return func(r *SQLiteRepository) {
 		r.db = logging.NewDB(
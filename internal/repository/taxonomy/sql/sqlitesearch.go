@@ -0,0 +1,244 @@
+package sql
+
+import (
+	"context"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+)
+
+// markOpen and markClose delimit the matches in the snippets read from
+// SQLite, runes of the private use area so they survive the escaping of the
+// name before they are replaced with <mark> tags
+const (
+	markOpen  = "\uE000"
+	markClose = "\uE001"
+)
+
+// bm25 ranks better matches lower, scores are negated so higher is better
+const (
+	querySearchTaxonomies = `
+		SELECT t.id, t.name, t.parent_id, t.created_at, t.updated_at, t.deleted_at,
+			-bm25(taxonomy_search) AS score,
+			snippet(taxonomy_search, 0, '` + markOpen + `', '` + markClose + `', '…', 16) AS snippet
+		FROM taxonomy_search JOIN taxonomy t ON t.id = taxonomy_search.rowid
+		WHERE taxonomy_search MATCH $1 AND t.deleted_at IS NULL
+		ORDER BY bm25(taxonomy_search), t.id LIMIT $2 OFFSET $3`
+	querySearchExists = `
+		SELECT EXISTS (
+			SELECT 1 FROM taxonomy_search JOIN taxonomy t ON t.id = taxonomy_search.rowid
+			WHERE taxonomy_search MATCH $1 AND t.deleted_at IS NULL
+		)`
+	queryFuzzySearchTaxonomies = `
+		SELECT t.id, t.name, t.parent_id, t.created_at, t.updated_at, t.deleted_at,
+			-bm25(taxonomy_search_trigram) AS score,
+			highlight(taxonomy_search_trigram, 0, '` + markOpen + `', '` + markClose + `') AS snippet
+		FROM taxonomy_search_trigram JOIN taxonomy t ON t.id = taxonomy_search_trigram.rowid
+		WHERE taxonomy_search_trigram MATCH $1 AND t.deleted_at IS NULL
+		ORDER BY bm25(taxonomy_search_trigram) LIMIT $2`
+)
+
+const (
+	// fuzzyCandidates the number of names sharing trigrams with the search
+	// that are compared with it
+	fuzzyCandidates = 200
+	// fuzzySimilarity the minimum share of trigrams a name must have in
+	// common with the search to be a fuzzy match
+	fuzzySimilarity = 0.3
+)
+
+type searchResult struct {
+	model.Taxonomy
+	Score   float64 `db:"score"`
+	Snippet string  `db:"snippet"`
+}
+
+// Search SQLite implementation for a taxonomy search repository, names are
+// matched by their terms ranked with bm25, falling back to names sharing
+// trigrams with the search when no term matches, snippets are the HTML
+// escaped names with the matches marked by <mark>, deleted taxonomies are
+// never found
+func (r *SQLiteRepository) Search(ctx context.Context, query taxonomy.Query) (taxonomy.Response, error) {
+	terms := searchTerms(query.Search)
+	if len(terms) == 0 {
+		return searchResponse(query.Pagination, nil, false), nil
+	}
+
+	match := matchExpression(terms)
+	results := []searchResult{}
+
+	if err := r.db.SelectContext(
+		ctx, &results, querySearchTaxonomies,
+		match, limit(query.Pagination), query.Pagination.Offset,
+	); err != nil {
+		return taxonomy.Response{}, mapError(err)
+	}
+
+	if len(results) > 0 {
+		return searchResponse(query.Pagination, results, false), nil
+	}
+
+	// an empty page past the last match is not a reason to go fuzzy
+	exists := false
+
+	if err := r.db.GetContext(ctx, &exists, querySearchExists, match); err != nil {
+		return taxonomy.Response{}, mapError(err)
+	}
+
+	if exists {
+		return searchResponse(query.Pagination, nil, false), nil
+	}
+
+	results, err := r.fuzzySearch(ctx, terms)
+	if err != nil {
+		return taxonomy.Response{}, err
+	}
+
+	return searchResponse(query.Pagination, page(results, query.Pagination), true), nil
+}
+
+// fuzzySearch reads the names sharing trigrams with the terms, ordered by the
+// share of trigrams they have in common with the terms
+func (r *SQLiteRepository) fuzzySearch(ctx context.Context, terms []string) ([]searchResult, error) {
+	want := trigrams(terms)
+	if len(want) == 0 {
+		return nil, nil
+	}
+
+	alternatives := make([]string, 0, len(want))
+	for trigram := range want {
+		alternatives = append(alternatives, quote(trigram))
+	}
+	sort.Strings(alternatives)
+
+	candidates := []searchResult{}
+
+	if err := r.db.SelectContext(
+		ctx, &candidates, queryFuzzySearchTaxonomies,
+		strings.Join(alternatives, " OR "), fuzzyCandidates,
+	); err != nil {
+		return nil, mapError(err)
+	}
+
+	results := candidates[:0]
+
+	for _, c := range candidates {
+		c.Score = similarity(want, trigrams(searchTerms(c.Name)))
+		if c.Score >= fuzzySimilarity {
+			results = append(results, c)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results, nil
+}
+
+func searchResponse(p repository.Pagination, results []searchResult, fuzzy bool) taxonomy.Response {
+	taxonomies := make([]model.Taxonomy, 0, len(results))
+	matches := make([]taxonomy.Match, 0, len(results))
+
+	for _, result := range results {
+		taxonomies = append(taxonomies, result.Taxonomy)
+		matches = append(matches, taxonomy.Match{
+			ID:      result.ID,
+			Score:   result.Score,
+			Snippet: markup(result.Snippet),
+			Fuzzy:   fuzzy,
+		})
+	}
+
+	return taxonomy.NewResponse(
+		taxonomy.ResponseWithPagination(repository.Pagination{
+			Limit:  p.Limit,
+			Offset: p.Offset,
+			Count:  uint(len(taxonomies)),
+		}),
+		taxonomy.ResponseWithResult(taxonomies...),
+		taxonomy.ResponseWithMatches(matches...),
+	)
+}
+
+var markReplacer = strings.NewReplacer(markOpen, "<mark>", markClose, "</mark>")
+
+// markup escapes the snippet for HTML and marks its matches, the only markup
+// of the result is the <mark> tags
+func markup(snippet string) string {
+	return markReplacer.Replace(html.EscapeString(snippet))
+}
+
+func page(results []searchResult, p repository.Pagination) []searchResult {
+	if p.Offset >= uint(len(results)) {
+		return nil
+	}
+
+	results = results[p.Offset:]
+
+	if p.Limit > 0 && p.Limit < uint(len(results)) {
+		results = results[:p.Limit]
+	}
+
+	return results
+}
+
+// searchTerms splits a search into lower case terms of letters and digits,
+// anything else separates terms so no FTS5 syntax can be injected
+func searchTerms(search string) []string {
+	return strings.FieldsFunc(strings.ToLower(search), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// matchExpression builds an FTS5 query matching every term, the last term as
+// a prefix so partially typed names are completed
+func matchExpression(terms []string) string {
+	phrases := make([]string, len(terms))
+	for i, term := range terms {
+		phrases[i] = quote(term)
+	}
+
+	phrases[len(phrases)-1] += "*"
+
+	return strings.Join(phrases, " ")
+}
+
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func trigrams(terms []string) map[string]struct{} {
+	set := map[string]struct{}{}
+
+	for _, term := range terms {
+		runes := []rune(term)
+
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = struct{}{}
+		}
+	}
+
+	return set
+}
+
+// similarity the Jaccard index of two sets of trigrams
+func similarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for trigram := range a {
+		if _, ok := b[trigram]; ok {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
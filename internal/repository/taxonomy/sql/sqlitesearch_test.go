@@ -0,0 +1,108 @@
+//go:build sqlite_fts5
+
+package sql
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/olireadcopper/sqlxprototype/internal/model"
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+)
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewSQLiteRepository(SQLiteWithDB(newSQLiteDB(t)))
+
+	created := create(t, r, nil,
+		"Mammals",
+		"Mammoths",
+		"Big cats and small dogs",
+		"Cats",
+		"Marsupials",
+		`<img src=x onerror="alert(1)"> Birds`,
+	)
+
+	if err := r.Delete(ctx, taxonomy.Query{Taxonomy: created[4]}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		search   string
+		want     []string
+		fuzzy    bool
+		snippets []string
+	}{
+		{"prefix", "mamm", []string{"Mammals", "Mammoths"}, false, []string{"<mark>Mammals</mark>", "<mark>Mammoths</mark>"}},
+		// bm25 ranks the shorter name with the term higher
+		{"ranked", "cats", []string{"Cats", "Big cats and small dogs"}, false, []string{"<mark>Cats</mark>", "Big <mark>cats</mark> and small dogs"}},
+		{"every term", "small cat", []string{"Big cats and small dogs"}, false, nil},
+		{"misspelt", "mamals", []string{"Mammals"}, true, nil},
+		{"deleted", "marsupials", []string{}, true, nil},
+		{"no syntax", `small^ (dogs*"`, []string{"Big cats and small dogs"}, false, nil},
+		{"escaped", "birds", []string{`<img src=x onerror="alert(1)"> Birds`}, false, []string{`&lt;img src=x onerror=&#34;alert(1)&#34;&gt; <mark>Birds</mark>`}},
+		{"nothing", "   ", []string{}, false, nil},
+	}
+
+	for _, tt := range tests {
+		res, err := r.Search(ctx, taxonomy.Query{
+			Search:     tt.search,
+			Pagination: repository.Pagination{Limit: 10},
+		})
+		if err != nil {
+			t.Fatalf("%s: error = %v", tt.name, err)
+		}
+
+		if got := names(res.Results); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Search(%q) = %v, want %v", tt.name, tt.search, got, tt.want)
+			continue
+		}
+
+		snippets := []string{}
+
+		for i, m := range res.Matches {
+			if m.ID != res.Results[i].ID || m.Fuzzy != tt.fuzzy {
+				t.Errorf("%s: match %d = %+v, want %s with fuzzy %v", tt.name, i, m, res.Results[i].ID, tt.fuzzy)
+			}
+
+			if i > 0 && m.Score > res.Matches[i-1].Score {
+				t.Errorf("%s: match %d scored %v above the one before it", tt.name, i, m.Score)
+			}
+
+			snippets = append(snippets, m.Snippet)
+		}
+
+		if tt.snippets != nil && !slices.Equal(snippets, tt.snippets) {
+			t.Errorf("%s: snippets = %q, want %q", tt.name, snippets, tt.snippets)
+		}
+	}
+
+	// a page past the last match is empty rather than fuzzy
+	res, err := r.Search(ctx, taxonomy.Query{
+		Search:     "cats",
+		Pagination: repository.Pagination{Limit: 10, Offset: 10},
+	})
+	if err != nil || len(res.Results) != 0 {
+		t.Errorf("Search() past the last match = %v %v, want nothing", names(res.Results), err)
+	}
+
+	for _, m := range res.Matches {
+		if m.Fuzzy {
+			t.Errorf("Search() past the last match went fuzzy")
+		}
+	}
+
+	// renamed taxonomies are found by their new name only
+	if err := r.Update(ctx, taxonomy.Query{Taxonomy: model.Taxonomy{ID: created[3].ID, Name: "Felines"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = r.Search(ctx, taxonomy.Query{Search: "feli", Pagination: repository.Pagination{Limit: 10}})
+	if err != nil || !slices.Equal(names(res.Results), []string{"Felines"}) {
+		t.Errorf("Search(feli) = %v %v, want Felines", names(res.Results), err)
+	}
+}
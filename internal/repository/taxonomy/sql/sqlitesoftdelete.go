@@ -0,0 +1,96 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/internal/repository/taxonomy"
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+)
+
+const (
+	// a taxonomy is only restored under a live parent
+	queryRestoreTaxonomy = `
+		UPDATE taxonomy SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		AND (parent_id IS NULL OR EXISTS (SELECT 1 FROM taxonomy p WHERE p.id = taxonomy.parent_id AND p.deleted_at IS NULL))`
+	queryDeletedTaxonomyExists = `
+		SELECT EXISTS (SELECT 1 FROM taxonomy WHERE id = $1 AND deleted_at IS NOT NULL)`
+	// taxonomies still referenced by children are left for a later pass
+	queryPurgeTaxonomies = `
+		DELETE FROM taxonomy
+		WHERE deleted_at < $1
+		AND NOT EXISTS (SELECT 1 FROM taxonomy c WHERE c.parent_id = taxonomy.id)
+		RETURNING id`
+)
+
+// Restore SQLite implementation for a taxonomy soft delete repository,
+// returns repository.ErrNotFound when no deleted taxonomy has the ID of the
+// query, and repository.ErrConflict when its parent is deleted or its name
+// was taken since
+func (r *SQLiteRepository) Restore(ctx context.Context, query taxonomy.Query) error {
+	id := query.Taxonomy.ID
+
+	return r.inTx(ctx, func(tx kryptonsqlx.Tx) error {
+		res, err := tx.ExecContext(ctx, queryRestoreTaxonomy, id)
+		if err != nil {
+			return mapError(err)
+		}
+
+		if err := requireAffected(res); err == nil {
+			return mapError(r.record(ctx, tx, query, taxonomy.OperationRestore, id))
+		}
+
+		deleted := false
+
+		if err := tx.GetContext(ctx, &deleted, queryDeletedTaxonomyExists, id); err != nil {
+			return mapError(err)
+		}
+
+		if !deleted {
+			return repository.ErrNotFound
+		}
+
+		return fmt.Errorf("%w: parent of taxonomy %s is deleted", repository.ErrConflict, id)
+	}, id)
+}
+
+// Purge SQLite implementation for a taxonomy soft delete repository, deleted
+// subtrees are removed leaves first, each removal is appended to the change
+// log
+func (r *SQLiteRepository) Purge(ctx context.Context, olderThan time.Duration) (int64, error) {
+	cutoff := r.dialect.Value(time.Now().Add(-olderThan))
+	purged := int64(0)
+
+	for {
+		ids := []string{}
+
+		if err := r.inTx(ctx, func(tx kryptonsqlx.Tx) error {
+			if err := tx.SelectContext(ctx, &ids, queryPurgeTaxonomies, cutoff); err != nil {
+				return mapError(err)
+			}
+
+			now := historyTime(time.Now())
+
+			for _, id := range ids {
+				if err := r.appendChange(ctx, tx, id, nil, taxonomy.OperationPurge, now); err != nil {
+					return mapError(err)
+				}
+			}
+
+			return nil
+		}); err != nil {
+			return purged, err
+		}
+
+		if len(ids) == 0 {
+			return purged, nil
+		}
+
+		r.changed(ids...)
+
+		purged += int64(len(ids))
+	}
+}
@@ -0,0 +1,4 @@
+SELECT id, name, parent_id, created_at, updated_at, deleted_at FROM taxonomy WHERE (($1::TEXT = '' AND parent_id IS NULL) OR parent_id = NULLIF($1::TEXT, '')::BIGINT) AND ($2 OR deleted_at IS NULL) ORDER BY id
+-- [1 false]
+SELECT EXISTS (SELECT 1 FROM taxonomy WHERE id = $1 AND ($2 OR deleted_at IS NULL))
+-- [1 false]
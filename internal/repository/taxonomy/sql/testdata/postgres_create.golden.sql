@@ -0,0 +1,2 @@
+INSERT INTO taxonomy (name, parent_id) VALUES ($1, $2) RETURNING id, name, parent_id, created_at, updated_at, deleted_at
+-- [a 2]
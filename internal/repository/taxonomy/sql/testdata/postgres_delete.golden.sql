@@ -0,0 +1,2 @@
+UPDATE taxonomy SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL AND NOT EXISTS (SELECT 1 FROM taxonomy c WHERE c.parent_id = taxonomy.id AND c.deleted_at IS NULL)
+-- [1]
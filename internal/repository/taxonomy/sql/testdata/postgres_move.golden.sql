@@ -0,0 +1,10 @@
+SELECT id FROM taxonomy WHERE id = $1::BIGINT OR id IN ( WITH RECURSIVE ancestors (id, parent_id) AS ( SELECT id, parent_id FROM taxonomy WHERE id = $2::BIGINT UNION ALL SELECT t.id, t.parent_id FROM taxonomy t JOIN ancestors a ON t.id = a.parent_id ) SELECT id FROM ancestors ) ORDER BY id FOR UPDATE
+-- [1 2]
+SELECT EXISTS (SELECT 1 FROM taxonomy WHERE id = $1 AND ($2 OR deleted_at IS NULL))
+-- [1 false]
+SELECT EXISTS (SELECT 1 FROM taxonomy WHERE id = $1 AND ($2 OR deleted_at IS NULL))
+-- [2 false]
+WITH RECURSIVE descendants (id) AS ( SELECT id FROM taxonomy WHERE id = $1 UNION ALL SELECT t.id FROM taxonomy t JOIN descendants d ON t.parent_id = d.id ) SELECT EXISTS (SELECT 1 FROM descendants d JOIN taxonomy t ON t.id = d.id WHERE t.id = $2)
+-- [1 2]
+UPDATE taxonomy SET parent_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND deleted_at IS NULL
+-- [2 1]
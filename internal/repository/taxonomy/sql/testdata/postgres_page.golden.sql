@@ -0,0 +1,4 @@
+SELECT id, name, parent_id, created_at, updated_at, deleted_at FROM taxonomy WHERE (name LIKE $1 ESCAPE '\' AND id IN ($2, $3)) AND deleted_at IS NULL ORDER BY name DESC, id LIMIT $4 OFFSET $5
+-- [a% 1 2 11 20]
+SELECT COUNT(*) FROM taxonomy WHERE (name LIKE $1 ESCAPE '\' AND id IN ($2, $3)) AND deleted_at IS NULL
+-- [a% 1 2]
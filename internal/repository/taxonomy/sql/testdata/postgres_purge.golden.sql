@@ -0,0 +1,2 @@
+DELETE FROM taxonomy WHERE deleted_at < $1 AND NOT EXISTS (SELECT 1 FROM taxonomy c WHERE c.parent_id = taxonomy.id) RETURNING id
+-- [<time>]
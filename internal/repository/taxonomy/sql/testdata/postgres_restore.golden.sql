@@ -0,0 +1,2 @@
+UPDATE taxonomy SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NOT NULL AND (parent_id IS NULL OR EXISTS (SELECT 1 FROM taxonomy p WHERE p.id = taxonomy.parent_id AND p.deleted_at IS NULL))
+-- [1]
@@ -0,0 +1,2 @@
+WITH RECURSIVE subtree (id, name, parent_id, created_at, updated_at, deleted_at, depth) AS ( SELECT id, name, parent_id, created_at, updated_at, deleted_at, 0 FROM taxonomy WHERE (($1::TEXT = '' AND parent_id IS NULL) OR id = NULLIF($1::TEXT, '')::BIGINT) AND ($2 OR deleted_at IS NULL) UNION ALL SELECT t.id, t.name, t.parent_id, t.created_at, t.updated_at, t.deleted_at, s.depth + 1 FROM taxonomy t JOIN subtree s ON t.parent_id = s.id WHERE ($3 = 0 OR s.depth < $3) AND ($2 OR t.deleted_at IS NULL) ) SELECT id, name, parent_id, created_at, updated_at, deleted_at, depth FROM subtree ORDER BY depth, id
+-- [1 false 2]
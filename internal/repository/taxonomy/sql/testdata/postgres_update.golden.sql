@@ -0,0 +1,2 @@
+UPDATE taxonomy SET name = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND deleted_at IS NULL
+-- [b 1]
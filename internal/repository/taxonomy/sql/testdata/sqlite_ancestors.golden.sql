@@ -0,0 +1,2 @@
+WITH RECURSIVE ancestors (id, name, parent_id, created_at, updated_at, deleted_at, depth) AS ( SELECT id, name, parent_id, created_at, updated_at, deleted_at, 0 FROM taxonomy WHERE id = $1 AND ($2 OR deleted_at IS NULL) UNION ALL SELECT t.id, t.name, t.parent_id, t.created_at, t.updated_at, t.deleted_at, a.depth + 1 FROM taxonomy t JOIN ancestors a ON t.id = a.parent_id ) SELECT id, name, parent_id, created_at, updated_at, deleted_at, depth FROM ancestors ORDER BY depth DESC
+-- [1 false]
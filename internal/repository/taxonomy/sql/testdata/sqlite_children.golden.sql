@@ -0,0 +1,4 @@
+SELECT id, name, parent_id, created_at, updated_at, deleted_at FROM taxonomy WHERE (($1 = '' AND parent_id IS NULL) OR parent_id = $1) AND ($2 OR deleted_at IS NULL) ORDER BY id LIMIT $3 OFFSET $4
+-- [1 false -1 0]
+SELECT EXISTS (SELECT 1 FROM taxonomy WHERE id = $1 AND ($2 OR deleted_at IS NULL))
+-- [1 false]
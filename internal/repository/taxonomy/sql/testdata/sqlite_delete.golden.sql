@@ -0,0 +1,8 @@
+UPDATE taxonomy SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL AND NOT EXISTS (SELECT 1 FROM taxonomy c WHERE c.parent_id = taxonomy.id AND c.deleted_at IS NULL)
+-- [1]
+UPDATE taxonomy_history SET valid_to = $1 WHERE taxonomy_id = $2 AND valid_to IS NULL
+-- [<time> 1]
+INSERT INTO taxonomy_history ( taxonomy_id, name, parent_id, created_at, updated_at, deleted_at, operation, actor, reason, valid_from ) SELECT id, name, parent_id, created_at, updated_at, deleted_at, $1, $2, $3, $4 FROM taxonomy WHERE id = $5 RETURNING revision
+-- [delete   <time> 1]
+INSERT INTO taxonomy_changes (taxonomy_id, revision, operation, created_at) VALUES ($1, $2, $3, $4) RETURNING seq
+-- [1 0 delete <time>]
@@ -0,0 +1,14 @@
+SELECT EXISTS (SELECT 1 FROM taxonomy WHERE id = $1 AND ($2 OR deleted_at IS NULL))
+-- [1 false]
+SELECT EXISTS (SELECT 1 FROM taxonomy WHERE id = $1 AND ($2 OR deleted_at IS NULL))
+-- [2 false]
+WITH RECURSIVE descendants (id) AS ( SELECT id FROM taxonomy WHERE id = $1 UNION ALL SELECT t.id FROM taxonomy t JOIN descendants d ON t.parent_id = d.id ) SELECT EXISTS (SELECT 1 FROM descendants d JOIN taxonomy t ON t.id = d.id WHERE t.id = $2)
+-- [1 2]
+UPDATE taxonomy SET parent_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND deleted_at IS NULL
+-- [2 1]
+UPDATE taxonomy_history SET valid_to = $1 WHERE taxonomy_id = $2 AND valid_to IS NULL
+-- [<time> 1]
+INSERT INTO taxonomy_history ( taxonomy_id, name, parent_id, created_at, updated_at, deleted_at, operation, actor, reason, valid_from ) SELECT id, name, parent_id, created_at, updated_at, deleted_at, $1, $2, $3, $4 FROM taxonomy WHERE id = $5 RETURNING revision
+-- [move   <time> 1]
+INSERT INTO taxonomy_changes (taxonomy_id, revision, operation, created_at) VALUES ($1, $2, $3, $4) RETURNING seq
+-- [1 0 move <time>]
@@ -0,0 +1,2 @@
+SELECT id, name, parent_id, created_at, updated_at, deleted_at FROM taxonomy WHERE id = $1 AND ($2 OR deleted_at IS NULL)
+-- [1 false]
@@ -2,21 +2,109 @@ package taxonomy
 
 import (
 	"context"
+	"time"
 
 	"github.com/olireadcopper/sqlxprototype/internal/model"
 	"github.com/olireadcopper/sqlxprototype/internal/repository"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/filter"
 )
 
 type Repository interface {
-	Create(ctx context.Context, query Query) error
+	Create(ctx context.Context, query Query) (Response, error)
 	Read(ctx context.Context, query Query) (Response, error)
 	Update(ctx context.Context, query Query) error
 	Delete(ctx context.Context, query Query) error
 }
 
+// HierarchyRepository navigates and restructures the tree formed by the
+// parents of taxonomies, an empty ID in the query refers to the roots
+type HierarchyRepository interface {
+	// Children reads a page of the direct children of the taxonomy
+	Children(ctx context.Context, query Query) (Response, error)
+	// Ancestors reads the path from the root down to the taxonomy, including
+	// the taxonomy itself
+	Ancestors(ctx context.Context, query Query) (Response, error)
+	// Subtree reads the taxonomy with its descendants nested as children, to
+	// the depth of the query, a depth of 0 reads the whole subtree
+	Subtree(ctx context.Context, query Query) (Response, error)
+	// Move sets the parent of the taxonomy to the ParentID of the query,
+	// refusing moves that would make a taxonomy its own ancestor
+	Move(ctx context.Context, query Query) error
+}
+
+// SearchRepository finds taxonomies by their names, the results are ordered
+// by relevance and each is described by the match at the same index
+type SearchRepository interface {
+	// Search reads a page of the taxonomies matching the Search of the
+	// query, the last term of the search is matched as a prefix
+	Search(ctx context.Context, query Query) (Response, error)
+}
+
+// BatchRepository writes many taxonomies at once, for example when importing
+// a taxonomy, a batch runs in one transaction and the failure of one of its
+// items does not prevent the others from being written
+type BatchRepository interface {
+	// CreateMany creates the Taxonomies of the query, items whose name is
+	// taken fail with repository.ErrConflict
+	CreateMany(ctx context.Context, query Query) (BatchResponse, error)
+	// Upsert creates the Taxonomies of the query, updating the parent of
+	// those whose name is taken instead
+	Upsert(ctx context.Context, query Query) (BatchResponse, error)
+	// DeleteMany deletes the taxonomies with the IDs of the Taxonomies of the
+	// query, items which do not exist fail with repository.ErrNotFound
+	DeleteMany(ctx context.Context, query Query) (BatchResponse, error)
+}
+
+// SoftDeleteRepository recovers and removes deleted taxonomies, Delete only
+// marks taxonomies as deleted so that references to them keep resolving
+type SoftDeleteRepository interface {
+	// Restore undeletes the taxonomy with the ID of the query, failing with
+	// repository.ErrConflict when its name was taken or its parent is deleted
+	Restore(ctx context.Context, query Query) error
+	// Purge removes the taxonomies deleted longer than olderThan ago for
+	// good, returning how many were removed
+	Purge(ctx context.Context, olderThan time.Duration) (int64, error)
+}
+
+// HistoryRepository reads the revisions written by every change of a
+// taxonomy, reads of a Repository see the revisions valid at the AsOf of
+// their query
+type HistoryRepository interface {
+	// History reads every revision of the taxonomy with the ID of the query,
+	// oldest first, each with the changes it made to the one before
+	History(ctx context.Context, query Query) (Response, error)
+}
+
+// FeedRepository reads the log of the changes of the taxonomies, the
+// sequence numbers of the changes increase in the order they were committed
+type FeedRepository interface {
+	// Changes reads up to limit changes following the sequence number after,
+	// oldest first
+	Changes(ctx context.Context, after uint64, limit uint) ([]Event, error)
+	// Checkpoint reads the sequence number of the last change the consumer
+	// processed, 0 when it never saved one
+	Checkpoint(ctx context.Context, consumer string) (uint64, error)
+	// SaveCheckpoint saves the sequence number of the last change the
+	// consumer processed, checkpoints never move backward
+	SaveCheckpoint(ctx context.Context, consumer string, seq uint64) error
+}
+
+// Progress reports the number of items of a batch done out of its total
+type Progress func(done, total int)
+
 type Query struct {
-	Pagination repository.Pagination
-	Taxonomy   model.Taxonomy
+	Pagination     repository.Pagination
+	Taxonomy       model.Taxonomy
+	Depth          uint
+	Search         string
+	Filter         filter.Expr
+	Sort           []filter.Sort
+	Taxonomies     []model.Taxonomy
+	Progress       Progress
+	IncludeDeleted bool
+	AsOf           time.Time
+	Actor          string
+	Reason         string
 }
 
 type QueryOption func(*Query)
@@ -24,8 +112,77 @@ type QueryOption func(*Query)
 type ResponseOption func(*Response)
 
 type Response struct {
-	Pagination repository.Pagination
-	Results    []model.Taxonomy
+	Pagination repository.Pagination `json:"pagination"`
+	Results    []model.Taxonomy      `json:"results"`
+	Matches    []Match               `json:"matches,omitempty"`
+	Revisions  []Revision            `json:"revisions,omitempty"`
+}
+
+// BatchResponse the outcome of each item of a batch, in the order of the
+// items of the query
+type BatchResponse struct {
+	Results   []BatchResult `json:"results"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+}
+
+// BatchResult the outcome of an item of a batch, Taxonomy is the taxonomy as
+// written when Err is nil
+type BatchResult struct {
+	Index    int            `json:"index"`
+	Taxonomy model.Taxonomy `json:"taxonomy"`
+	Err      error          `json:"-"`
+}
+
+// Match describes why a taxonomy was found by a search, Snippet is the HTML
+// escaped name with the matched terms in <mark> tags, Fuzzy reports a match
+// on fragments of the name when the terms themselves did not match
+type Match struct {
+	ID      string  `json:"id"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+	Fuzzy   bool    `json:"fuzzy"`
+}
+
+// Operations recorded by revisions
+const (
+	OperationCreate  = "create"
+	OperationUpdate  = "update"
+	OperationMove    = "move"
+	OperationDelete  = "delete"
+	OperationRestore = "restore"
+	OperationPurge   = "purge"
+)
+
+// Revision the state of a taxonomy from ValidFrom until ValidTo, the current
+// revision has no ValidTo, Changes are the fields which differ from the
+// previous revision
+type Revision struct {
+	Revision  uint64         `json:"revision"`
+	Taxonomy  model.Taxonomy `json:"taxonomy"`
+	Operation string         `json:"operation"`
+	Actor     string         `json:"actor,omitempty"`
+	Reason    string         `json:"reason,omitempty"`
+	ValidFrom time.Time      `json:"valid_from"`
+	ValidTo   *time.Time     `json:"valid_to,omitempty"`
+	Changes   []Change       `json:"changes"`
+}
+
+// Change a field changed by a revision, From is nil for the first revision
+type Change struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// Event a change of the feed, Taxonomy is the taxonomy as the change left it,
+// nil when it was purged
+type Event struct {
+	Seq        uint64          `json:"seq"`
+	Operation  string          `json:"operation"`
+	TaxonomyID string          `json:"taxonomy_id"`
+	Taxonomy   *model.Taxonomy `json:"taxonomy,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
 }
 
 func NewQuery(opts ...QueryOption) Query {
@@ -48,6 +205,89 @@ func NewResponse(opts ...ResponseOption) Response {
 	return r
 }
 
+func QueryWithPagination(p repository.Pagination) QueryOption {
+	return func(q *Query) {
+		q.Pagination = p
+	}
+}
+
+func QueryWithTaxonomy(t model.Taxonomy) QueryOption {
+	return func(q *Query) {
+		q.Taxonomy = t
+	}
+}
+
+func QueryWithDepth(depth uint) QueryOption {
+	return func(q *Query) {
+		q.Depth = depth
+	}
+}
+
+func QueryWithSearch(search string) QueryOption {
+	return func(q *Query) {
+		q.Search = search
+	}
+}
+
+// QueryWithFilter restricts a read to the taxonomies matching e, the fields
+// of e are the db tags of model.Taxonomy
+func QueryWithFilter(e filter.Expr) QueryOption {
+	return func(q *Query) {
+		q.Filter = e
+	}
+}
+
+func QueryWithSort(sorts ...filter.Sort) QueryOption {
+	return func(q *Query) {
+		q.Sort = sorts
+	}
+}
+
+func QueryWithTaxonomies(t ...model.Taxonomy) QueryOption {
+	return func(q *Query) {
+		q.Taxonomies = t
+	}
+}
+
+// QueryWithProgress sets a function called as the chunks of a batch are
+// written
+func QueryWithProgress(p Progress) QueryOption {
+	return func(q *Query) {
+		q.Progress = p
+	}
+}
+
+// QueryWithIncludeDeleted reads deleted taxonomies along with the others,
+// searches never include deleted taxonomies
+func QueryWithIncludeDeleted(include bool) QueryOption {
+	return func(q *Query) {
+		q.IncludeDeleted = include
+	}
+}
+
+// QueryWithAsOf reads the taxonomies as they were at t, the zero time reads
+// them as they are now
+func QueryWithAsOf(t time.Time) QueryOption {
+	return func(q *Query) {
+		q.AsOf = t
+	}
+}
+
+// QueryWithActor records who made the changes of the query in the history
+func QueryWithActor(actor string) QueryOption {
+	return func(q *Query) {
+		q.Actor = actor
+	}
+}
+
+// QueryWithReason records why the changes of the query were made in the
+// history
+func QueryWithReason(reason string) QueryOption {
+	return func(q *Query) {
+		q.Reason = reason
+	}
+}
+
 func ResponseWithPagination(p repository.Pagination) ResponseOption {
 	return func(r *Response) {
 		r.Pagination = p
@@ -59,3 +299,15 @@ func ResponseWithResult(t ...model.Taxonomy) ResponseOption {
 		r.Results = t
 	}
 }
+
+func ResponseWithMatches(m ...Match) ResponseOption {
+	return func(r *Response) {
+		r.Matches = m
+	}
+}
+
+func ResponseWithRevisions(revisions ...Revision) ResponseOption {
+	return func(r *Response) {
+		r.Revisions = revisions
+	}
+}
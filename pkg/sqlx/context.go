@@ -0,0 +1,23 @@
+package sqlx
+
+import "context"
+
+type contextKey byte
+
+const (
+	contextKeyTx contextKey = iota
+)
+
+// ContextWithTx returns a copy of ctx marking the calls made with it as
+// running inside tx, the decorated transactions of BeginTransaction mark the
+// context of each of their statements
+func ContextWithTx(ctx context.Context, tx Tx) context.Context {
+	return context.WithValue(ctx, contextKeyTx, tx)
+}
+
+// TxFromContext returns the transaction ctx was marked with by ContextWithTx
+func TxFromContext(ctx context.Context) (Tx, bool) {
+	tx, ok := ctx.Value(contextKeyTx).(Tx)
+
+	return tx, ok && tx != nil
+}
@@ -0,0 +1,89 @@
+// Package dialect describes how the SQL of a database differs from the SQL
+// of another, so a repository can build its queries once for SQLite and
+// PostgreSQL. Queries are written with $N placeholders, which both databases
+// accept, or with ? placeholders rebound to the bind type of the dialect.
+// Errors are classified for both drivers by package sqlerr
+package dialect
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupported returned when no dialect is known for a driver
+var ErrUnsupported = errors.New("dialect: unsupported driver")
+
+// Dialect the SQL of a database
+type Dialect interface {
+	// DriverName the name the database/sql driver of the database is
+	// registered under
+	DriverName() string
+	// BindType the sqlx bind type of the placeholders of queries
+	BindType() int
+	// Rebind rewrites the ? placeholders of query to the bind type
+	Rebind(query string) string
+	// Placeholder the placeholder of the nth argument of a query
+	Placeholder(n int) string
+	// Returning the clause returning the columns of the rows written by an
+	// INSERT, UPDATE or DELETE
+	Returning(columns ...string) string
+	// Upsert the clause following an INSERT which updates the columns of the
+	// row conflicting on the conflict columns instead, where restricts the
+	// conflict to a partial unique index when set
+	Upsert(conflict []string, where string, update ...string) string
+	// Paginate the clause limiting the rows read to a page, with placeholders
+	// numbered from first, a zero limit reads every row from the offset
+	Paginate(limit, offset uint, first int) (string, []any)
+	// Value converts v to the value the columns of the database compare
+	// with, times and booleans are stored differently by each database
+	Value(v any) any
+}
+
+// ForDriver returns the dialect of the database/sql driver registered as
+// driverName
+func ForDriver(driverName string) (Dialect, error) {
+	switch driverName {
+	case "sqlite3":
+		return NewSQLite(), nil
+	case "postgres":
+		return NewPostgres(), nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnsupported, driverName)
+}
+
+// dollar numbers placeholders $1, $2 and so on
+func dollar(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func returning(columns []string) string {
+	if len(columns) == 0 {
+		return ""
+	}
+
+	return " RETURNING " + strings.Join(columns, ", ")
+}
+
+// onConflict the upsert clause of SQLite and PostgreSQL, which share its
+// syntax, the conflict columns are rewritten when there is nothing else to
+// update so the conflicting row is still returned
+func onConflict(conflict []string, where string, update []string) string {
+	if len(update) == 0 {
+		update = conflict
+	}
+
+	set := make([]string, len(update))
+	for i, c := range update {
+		set[i] = c + " = excluded." + c
+	}
+
+	clause := " ON CONFLICT (" + strings.Join(conflict, ", ") + ")"
+	if where != "" {
+		clause += " WHERE " + where
+	}
+
+	return clause + " DO UPDATE SET " + strings.Join(set, ", ")
+}
@@ -0,0 +1,140 @@
+package dialect
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestForDriver(t *testing.T) {
+	for _, driverName := range []string{"sqlite3", "postgres"} {
+		d, err := ForDriver(driverName)
+		if err != nil {
+			t.Fatalf("ForDriver(%q): %v", driverName, err)
+		}
+
+		if d.DriverName() != driverName {
+			t.Errorf("ForDriver(%q).DriverName() = %q", driverName, d.DriverName())
+		}
+	}
+
+	if _, err := ForDriver("mysql"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("ForDriver(mysql) error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestRebind(t *testing.T) {
+	query := "SELECT id FROM taxonomy WHERE name = ? AND parent_id = ?"
+	want := "SELECT id FROM taxonomy WHERE name = $1 AND parent_id = $2"
+
+	for _, d := range []Dialect{NewSQLite(), NewPostgres()} {
+		if got := d.Rebind(query); got != want {
+			t.Errorf("%s Rebind() = %q, want %q", d.DriverName(), got, want)
+		}
+
+		if got := d.Placeholder(3); got != "$3" {
+			t.Errorf("%s Placeholder(3) = %q, want $3", d.DriverName(), got)
+		}
+	}
+}
+
+func TestReturning(t *testing.T) {
+	for _, d := range []Dialect{NewSQLite(), NewPostgres()} {
+		if got, want := d.Returning("id", "name"), " RETURNING id, name"; got != want {
+			t.Errorf("%s Returning() = %q, want %q", d.DriverName(), got, want)
+		}
+
+		if got := d.Returning(); got != "" {
+			t.Errorf("%s Returning() without columns = %q, want empty", d.DriverName(), got)
+		}
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	tests := []struct {
+		name     string
+		conflict []string
+		where    string
+		update   []string
+		want     string
+	}{
+		{
+			name:     "update",
+			conflict: []string{"id"},
+			update:   []string{"name", "parent_id"},
+			want:     " ON CONFLICT (id) DO UPDATE SET name = excluded.name, parent_id = excluded.parent_id",
+		},
+		{
+			name:     "partial index",
+			conflict: []string{"name"},
+			where:    "deleted_at IS NULL",
+			update:   []string{"parent_id"},
+			want:     " ON CONFLICT (name) WHERE deleted_at IS NULL DO UPDATE SET parent_id = excluded.parent_id",
+		},
+		{
+			name:     "nothing to update",
+			conflict: []string{"name"},
+			want:     " ON CONFLICT (name) DO UPDATE SET name = excluded.name",
+		},
+	}
+
+	for _, d := range []Dialect{NewSQLite(), NewPostgres()} {
+		for _, tt := range tests {
+			if got := d.Upsert(tt.conflict, tt.where, tt.update...); got != tt.want {
+				t.Errorf("%s Upsert() %s = %q, want %q", d.DriverName(), tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name          string
+		d             Dialect
+		limit, offset uint
+		want          string
+		wantArgs      []any
+	}{
+		{"sqlite none", NewSQLite(), 0, 0, "", nil},
+		{"sqlite limit", NewSQLite(), 10, 0, " LIMIT $3", []any{uint(10)}},
+		{"sqlite page", NewSQLite(), 10, 20, " LIMIT $3 OFFSET $4", []any{uint(10), uint(20)}},
+		{"sqlite offset", NewSQLite(), 0, 20, " LIMIT -1 OFFSET $3", []any{uint(20)}},
+		{"postgres none", NewPostgres(), 0, 0, "", nil},
+		{"postgres limit", NewPostgres(), 10, 0, " LIMIT $3", []any{uint(10)}},
+		{"postgres page", NewPostgres(), 10, 20, " LIMIT $3 OFFSET $4", []any{uint(10), uint(20)}},
+		{"postgres offset", NewPostgres(), 0, 20, " OFFSET $3", []any{uint(20)}},
+	}
+
+	for _, tt := range tests {
+		got, args := tt.d.Paginate(tt.limit, tt.offset, 3)
+		if got != tt.want || !reflect.DeepEqual(args, tt.wantArgs) {
+			t.Errorf("%s Paginate() = %q %v, want %q %v", tt.name, got, args, tt.want, tt.wantArgs)
+		}
+	}
+}
+
+func TestValue(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 30, 0, 0, time.FixedZone("CET", 3600))
+
+	tests := []struct {
+		name string
+		d    Dialect
+		v    any
+		want any
+	}{
+		{"sqlite time", NewSQLite(), at, "2024-03-01 11:30:00"},
+		{"sqlite true", NewSQLite(), true, 1},
+		{"sqlite false", NewSQLite(), false, 0},
+		{"sqlite string", NewSQLite(), "a", "a"},
+		{"postgres time", NewPostgres(), at, at.UTC()},
+		{"postgres bool", NewPostgres(), true, true},
+		{"postgres string", NewPostgres(), "a", "a"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.d.Value(tt.v); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s Value() = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package dialect
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Postgres the dialect of PostgreSQL with the lib/pq driver
+type Postgres struct{}
+
+func NewPostgres() *Postgres {
+	return &Postgres{}
+}
+
+func (*Postgres) DriverName() string {
+	return "postgres"
+}
+
+func (*Postgres) BindType() int {
+	return sqlx.DOLLAR
+}
+
+func (d *Postgres) Rebind(query string) string {
+	return sqlx.Rebind(d.BindType(), query)
+}
+
+func (*Postgres) Placeholder(n int) string {
+	return dollar(n)
+}
+
+func (*Postgres) Returning(columns ...string) string {
+	return returning(columns)
+}
+
+func (*Postgres) Upsert(conflict []string, where string, update ...string) string {
+	return onConflict(conflict, where, update)
+}
+
+// Paginate PostgreSQL takes an offset without a limit
+func (*Postgres) Paginate(limit, offset uint, first int) (string, []any) {
+	switch {
+	case limit > 0 && offset > 0:
+		return " LIMIT " + dollar(first) + " OFFSET " + dollar(first+1), []any{limit, offset}
+	case limit > 0:
+		return " LIMIT " + dollar(first), []any{limit}
+	case offset > 0:
+		return " OFFSET " + dollar(first), []any{offset}
+	}
+
+	return "", nil
+}
+
+// Value passes times in UTC to the timestamptz columns, booleans are native
+func (*Postgres) Value(v any) any {
+	if t, ok := v.(time.Time); ok {
+		return t.UTC()
+	}
+
+	return v
+}
@@ -0,0 +1,74 @@
+package dialect
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SQLite the dialect of SQLite, which binds $N placeholders in the order they
+// first appear in a query rather than by N, so queries number them in that
+// order
+type SQLite struct{}
+
+func NewSQLite() *SQLite {
+	return &SQLite{}
+}
+
+func (*SQLite) DriverName() string {
+	return "sqlite3"
+}
+
+// BindType SQLite accepts the $N placeholders of PostgreSQL, which the
+// repositories use for both
+func (*SQLite) BindType() int {
+	return sqlx.DOLLAR
+}
+
+func (d *SQLite) Rebind(query string) string {
+	return sqlx.Rebind(d.BindType(), query)
+}
+
+func (*SQLite) Placeholder(n int) string {
+	return dollar(n)
+}
+
+func (*SQLite) Returning(columns ...string) string {
+	return returning(columns)
+}
+
+func (*SQLite) Upsert(conflict []string, where string, update ...string) string {
+	return onConflict(conflict, where, update)
+}
+
+// Paginate SQLite only takes an offset after a limit, a negative limit is no
+// limit
+func (*SQLite) Paginate(limit, offset uint, first int) (string, []any) {
+	switch {
+	case limit > 0 && offset > 0:
+		return " LIMIT " + dollar(first) + " OFFSET " + dollar(first+1), []any{limit, offset}
+	case limit > 0:
+		return " LIMIT " + dollar(first), []any{limit}
+	case offset > 0:
+		return " LIMIT -1 OFFSET " + dollar(first), []any{offset}
+	}
+
+	return "", nil
+}
+
+// Value stores times the way SQLite's CURRENT_TIMESTAMP does, so they compare
+// as text with the timestamps of the tables, and booleans as integers
+func (*SQLite) Value(v any) any {
+	switch v := v.(type) {
+	case time.Time:
+		return v.UTC().Format(time.DateTime)
+	case bool:
+		if v {
+			return 1
+		}
+
+		return 0
+	}
+
+	return v
+}
@@ -0,0 +1,59 @@
+package filter
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+var identifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+var mapper = reflectx.NewMapperFunc("db", strings.ToLower)
+
+// Columns the whitelist of columns a filter may refer to, with the Go type
+// of the field each is scanned into
+type Columns map[string]reflect.Type
+
+// ColumnsOf returns the columns of the fields of the struct v mapped the way
+// sqlx maps them, fields tagged db:"-", nested structs and slices are not
+// columns
+func ColumnsOf(v any) Columns {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	columns := Columns{}
+
+	for _, field := range mapper.TypeMap(t).Index {
+		if field.Field.Tag.Get("db") == "-" || strings.Contains(field.Path, ".") {
+			continue
+		}
+
+		if !identifier.MatchString(field.Path) || !scalar(field.Field.Type) {
+			continue
+		}
+
+		columns[field.Path] = field.Field.Type
+	}
+
+	return columns
+}
+
+func scalar(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.Interface, reflect.Func, reflect.Chan:
+		return false
+	case reflect.Struct:
+		return t == reflect.TypeOf(time.Time{})
+	}
+
+	return true
+}
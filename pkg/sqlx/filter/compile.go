@@ -0,0 +1,189 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type CompilerOption func(*Compiler)
+
+// Compiler compiles filters and sorts to SQL, only the columns it was given
+// can be referred to and values are always passed as parameters
+type Compiler struct {
+	columns Columns
+	value   func(v any) any
+}
+
+// Clause a compiled filter, Where is empty when the filter matches everything
+type Clause struct {
+	Where   string
+	OrderBy string
+	Args    []any
+}
+
+// NewCompiler constructor for a compiler, without columns every filter
+// referring to a field is refused
+func NewCompiler(opts ...CompilerOption) *Compiler {
+	c := Compiler{
+		columns: Columns{},
+		value: func(v any) any {
+			return v
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return &c
+}
+
+// Compile compiles e and sorts, numbering the placeholders of the arguments
+// $first, $first+1 and so on so the clause can follow the arguments of the
+// rest of a query
+func (c *Compiler) Compile(e Expr, sorts []Sort, first int) (Clause, error) {
+	b := builder{
+		compiler: c,
+		first:    first,
+	}
+
+	where, err := b.expr(e)
+	if err != nil {
+		return Clause{}, err
+	}
+
+	orderBy, err := c.orderBy(sorts)
+	if err != nil {
+		return Clause{}, err
+	}
+
+	return Clause{
+		Where:   where,
+		OrderBy: orderBy,
+		Args:    b.args,
+	}, nil
+}
+
+func (c *Compiler) orderBy(sorts []Sort) (string, error) {
+	terms := make([]string, 0, len(sorts))
+
+	for _, s := range sorts {
+		if _, ok := c.columns[s.Field]; !ok {
+			return "", fmt.Errorf("%w: unknown column %q", ErrInvalid, s.Field)
+		}
+
+		if s.Desc {
+			terms = append(terms, s.Field+" DESC")
+		} else {
+			terms = append(terms, s.Field+" ASC")
+		}
+	}
+
+	return strings.Join(terms, ", "), nil
+}
+
+type builder struct {
+	compiler *Compiler
+	first    int
+	args     []any
+}
+
+func (b *builder) bind(v any) string {
+	b.args = append(b.args, b.compiler.value(v))
+
+	return "$" + strconv.Itoa(b.first+len(b.args)-1)
+}
+
+func (b *builder) expr(e Expr) (string, error) {
+	switch {
+	case len(e.And) > 0:
+		return b.compose(e.And, " AND ")
+	case len(e.Or) > 0:
+		return b.compose(e.Or, " OR ")
+	case e.Field == "":
+		return "", nil
+	}
+
+	return b.predicate(e)
+}
+
+func (b *builder) compose(exprs []Expr, separator string) (string, error) {
+	conditions := make([]string, 0, len(exprs))
+
+	for _, e := range exprs {
+		condition, err := b.expr(e)
+		if err != nil {
+			return "", err
+		}
+
+		if condition != "" {
+			conditions = append(conditions, condition)
+		}
+	}
+
+	switch len(conditions) {
+	case 0:
+		return "", nil
+	case 1:
+		return conditions[0], nil
+	}
+
+	return "(" + strings.Join(conditions, separator) + ")", nil
+}
+
+func (b *builder) predicate(e Expr) (string, error) {
+	if _, ok := b.compiler.columns[e.Field]; !ok {
+		return "", fmt.Errorf("%w: unknown column %q", ErrInvalid, e.Field)
+	}
+
+	want := 1
+	switch e.Op {
+	case OpNull, OpNotNull:
+		want = 0
+	case OpRange:
+		want = 2
+	case OpIn:
+		want = len(e.Values)
+
+		if want == 0 {
+			return "", fmt.Errorf("%w: %s in requires at least one value", ErrInvalid, e.Field)
+		}
+	}
+
+	if len(e.Values) != want {
+		return "", fmt.Errorf("%w: %s %s takes %d values, got %d", ErrInvalid, e.Field, e.Op, want, len(e.Values))
+	}
+
+	switch e.Op {
+	case OpEq:
+		return e.Field + " = " + b.bind(e.Values[0]), nil
+	case OpNe:
+		return e.Field + " <> " + b.bind(e.Values[0]), nil
+	case OpGt:
+		return e.Field + " > " + b.bind(e.Values[0]), nil
+	case OpGte:
+		return e.Field + " >= " + b.bind(e.Values[0]), nil
+	case OpLt:
+		return e.Field + " < " + b.bind(e.Values[0]), nil
+	case OpLte:
+		return e.Field + " <= " + b.bind(e.Values[0]), nil
+	case OpLike:
+		return e.Field + " LIKE " + b.bind(e.Values[0]) + ` ESCAPE '\'`, nil
+	case OpRange:
+		return e.Field + " BETWEEN " + b.bind(e.Values[0]) + " AND " + b.bind(e.Values[1]), nil
+	case OpNull:
+		return e.Field + " IS NULL", nil
+	case OpNotNull:
+		return e.Field + " IS NOT NULL", nil
+	case OpIn:
+		placeholders := make([]string, len(e.Values))
+		for i, v := range e.Values {
+			placeholders[i] = b.bind(v)
+		}
+
+		return e.Field + " IN (" + strings.Join(placeholders, ", ") + ")", nil
+	}
+
+	return "", fmt.Errorf("%w: unknown operator %q", ErrInvalid, e.Op)
+}
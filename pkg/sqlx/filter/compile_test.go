@@ -0,0 +1,118 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  string
+		sort    string
+		first   int
+		where   string
+		orderBy string
+		args    []any
+	}{
+		{"empty", "", "", 1, "", "", nil},
+		{"eq", "name = a", "", 1, "name = $1", "", []any{"a"}},
+		{"first", "name = a", "", 3, "name = $3", "", []any{"a"}},
+		{"ne", "id != 1", "", 1, "id <> $1", "", []any{int64(1)}},
+		{"like", "name like 'a\\%%'", "", 1, `name LIKE $1 ESCAPE '\'`, "", []any{`a\%%`}},
+		{"in", "id in (1, 2, 3)", "", 1, "id IN ($1, $2, $3)", "", []any{int64(1), int64(2), int64(3)}},
+		{"between", "id between 1 and 2", "", 1, "id BETWEEN $1 AND $2", "", []any{int64(1), int64(2)}},
+		{"is null", "parent_id is null", "", 1, "parent_id IS NULL", "", nil},
+		{"is not null", "parent_id is not null", "", 1, "parent_id IS NOT NULL", "", nil},
+		{
+			"and before or",
+			"id = 1 or id = 2 and name = a",
+			"", 1,
+			"(id = $1 OR (id = $2 AND name = $3))", "",
+			[]any{int64(1), int64(2), "a"},
+		},
+		{
+			// placeholders are numbered in the order they appear in the SQL
+			"placeholders in textual order",
+			"(name = a or id in (4, 5)) and score between 1 and 2 and active = true",
+			"", 2,
+			"((name = $2 OR id IN ($3, $4)) AND score BETWEEN $5 AND $6 AND active = $7)", "",
+			[]any{"a", int64(4), int64(5), float64(1), float64(2), true},
+		},
+		{"sort", "", "name,-created_at", 1, "", "name ASC, created_at DESC", nil},
+	}
+
+	c := NewCompiler(CompilerWithColumns(columns))
+
+	for _, tt := range tests {
+		e, err := Parse(tt.filter, columns)
+		if err != nil {
+			t.Fatalf("%s: Parse(%q) error = %v", tt.name, tt.filter, err)
+		}
+
+		sorts, err := ParseSort(tt.sort, columns)
+		if err != nil {
+			t.Fatalf("%s: ParseSort(%q) error = %v", tt.name, tt.sort, err)
+		}
+
+		got, err := c.Compile(e, sorts, tt.first)
+		if err != nil {
+			t.Errorf("%s: Compile() error = %v", tt.name, err)
+			continue
+		}
+
+		want := Clause{Where: tt.where, OrderBy: tt.orderBy, Args: tt.args}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Compile() = %#v, want %#v", tt.name, got, want)
+		}
+	}
+}
+
+func TestCompileValuer(t *testing.T) {
+	c := NewCompiler(
+		CompilerWithColumns(columns),
+		CompilerWithValuer(func(v any) any {
+			return []any{v}
+		}),
+	)
+
+	got, err := c.Compile(Eq("id", 1), nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []any{[]any{1}}; !reflect.DeepEqual(got.Args, want) {
+		t.Errorf("Args = %v, want %v", got.Args, want)
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		e     Expr
+		sorts []Sort
+	}{
+		{"unknown column", Eq("colour", "red"), nil},
+		{"unknown column nested", And(Eq("id", 1), Or(Eq("name", "a"), Eq("colour", "red"))), nil},
+		{"unknown operator", Expr{Field: "id", Op: "regexp", Values: []any{"a"}}, nil},
+		{"in without values", In("id"), nil},
+		{"too many values", Expr{Field: "id", Op: OpEq, Values: []any{1, 2}}, nil},
+		{"range with one value", Expr{Field: "id", Op: OpRange, Values: []any{1}}, nil},
+		{"null with a value", Expr{Field: "id", Op: OpNull, Values: []any{1}}, nil},
+		{"unknown sort column", Expr{}, []Sort{Desc("colour")}},
+	}
+
+	c := NewCompiler(CompilerWithColumns(columns))
+
+	for _, tt := range tests {
+		if _, err := c.Compile(tt.e, tt.sorts, 1); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: Compile() error = %v, want %v", tt.name, err, ErrInvalid)
+		}
+	}
+
+	// without columns every field is refused
+	if _, err := NewCompiler().Compile(Eq("id", 1), nil, 1); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Compile() without columns error = %v, want %v", err, ErrInvalid)
+	}
+}
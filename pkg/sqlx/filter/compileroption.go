@@ -0,0 +1,18 @@
+package filter
+
+// CompilerWithColumns sets the whitelist of columns filters may refer to,
+// usually built with ColumnsOf
+func CompilerWithColumns(columns Columns) CompilerOption {
+	return func(c *Compiler) {
+		c.columns = columns
+	}
+}
+
+// CompilerWithValuer sets a function converting the values of predicates
+// before they are passed as arguments, allowing a dialect to store values
+// such as times in the format of its columns
+func CompilerWithValuer(f func(v any) any) CompilerOption {
+	return func(c *Compiler) {
+		c.value = f
+	}
+}
@@ -0,0 +1,114 @@
+// Package filter describes the filtering and sorting of queries as a tree of
+// typed predicates, compiled to parameterised SQL against a whitelist of
+// columns, and parsed from a query string syntax for HTTP callers
+package filter
+
+import "errors"
+
+// ErrInvalid returned when a filter or sort refers to an unknown column, uses
+// an operator incorrectly or can not be parsed
+var ErrInvalid = errors.New("filter: invalid filter")
+
+// Op the operator of a predicate
+type Op string
+
+const (
+	OpEq      Op = "eq"
+	OpNe      Op = "ne"
+	OpGt      Op = "gt"
+	OpGte     Op = "gte"
+	OpLt      Op = "lt"
+	OpLte     Op = "lte"
+	OpIn      Op = "in"
+	OpLike    Op = "like"
+	OpRange   Op = "range"
+	OpNull    Op = "null"
+	OpNotNull Op = "notnull"
+)
+
+// Expr a node of a filter, either a predicate on Field or, when And or Or is
+// set, the composition of its children, the zero Expr matches everything
+type Expr struct {
+	And    []Expr
+	Or     []Expr
+	Field  string
+	Op     Op
+	Values []any
+}
+
+// Sort orders the results by Field, descending when Desc is set
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// IsZero reports whether e is the zero Expr, which matches everything
+func (e Expr) IsZero() bool {
+	return len(e.And) == 0 && len(e.Or) == 0 && e.Field == ""
+}
+
+// And matches when every expression matches
+func And(exprs ...Expr) Expr {
+	return Expr{And: exprs}
+}
+
+// Or matches when any expression matches
+func Or(exprs ...Expr) Expr {
+	return Expr{Or: exprs}
+}
+
+func Eq(field string, v any) Expr {
+	return Expr{Field: field, Op: OpEq, Values: []any{v}}
+}
+
+func Ne(field string, v any) Expr {
+	return Expr{Field: field, Op: OpNe, Values: []any{v}}
+}
+
+func Gt(field string, v any) Expr {
+	return Expr{Field: field, Op: OpGt, Values: []any{v}}
+}
+
+func Gte(field string, v any) Expr {
+	return Expr{Field: field, Op: OpGte, Values: []any{v}}
+}
+
+func Lt(field string, v any) Expr {
+	return Expr{Field: field, Op: OpLt, Values: []any{v}}
+}
+
+func Lte(field string, v any) Expr {
+	return Expr{Field: field, Op: OpLte, Values: []any{v}}
+}
+
+// In matches when the field equals any of the values
+func In(field string, values ...any) Expr {
+	return Expr{Field: field, Op: OpIn, Values: values}
+}
+
+// Like matches the field against an SQL LIKE pattern, backslash escapes the
+// wildcards % and _
+func Like(field string, pattern string) Expr {
+	return Expr{Field: field, Op: OpLike, Values: []any{pattern}}
+}
+
+// Range matches when the field is between from and to inclusive
+func Range(field string, from, to any) Expr {
+	return Expr{Field: field, Op: OpRange, Values: []any{from, to}}
+}
+
+func IsNull(field string) Expr {
+	return Expr{Field: field, Op: OpNull}
+}
+
+func IsNotNull(field string) Expr {
+	return Expr{Field: field, Op: OpNotNull}
+}
+
+func Asc(field string) Sort {
+	return Sort{Field: field}
+}
+
+func Desc(field string) Sort {
+	return Sort{Field: field, Desc: true}
+}
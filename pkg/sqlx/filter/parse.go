@@ -0,0 +1,394 @@
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// maxDepth the deepest nesting of parentheses a parsed filter may have
+const maxDepth = 16
+
+var operators = map[string]Op{
+	"eq":   OpEq,
+	"=":    OpEq,
+	"ne":   OpNe,
+	"!=":   OpNe,
+	"<>":   OpNe,
+	"gt":   OpGt,
+	">":    OpGt,
+	"gte":  OpGte,
+	">=":   OpGte,
+	"lt":   OpLt,
+	"<":    OpLt,
+	"lte":  OpLte,
+	"<=":   OpLte,
+	"like": OpLike,
+}
+
+// Parse parses a filter written in the query string syntax, predicates are
+// combined with and, or and parentheses, and is evaluated before or
+//
+//	name like 'Mam%' and (created_at gte 2024-01-01 or parent_id is null)
+//	id in (1, 2, 3) and updated_at between 2024-01-01 and 2024-02-01
+//
+// values are quoted with single quotes when they contain spaces or
+// punctuation, and are converted to the Go type of their column
+func Parse(s string, columns Columns) (Expr, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return Expr{}, err
+	}
+
+	p := parser{
+		tokens:  tokens,
+		columns: columns,
+	}
+
+	if p.peek().kind == tokenEOF {
+		return Expr{}, nil
+	}
+
+	e, err := p.or(0)
+	if err != nil {
+		return Expr{}, err
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return Expr{}, fmt.Errorf("%w: unexpected %q", ErrInvalid, t.text)
+	}
+
+	return e, nil
+}
+
+// ParseSort parses a comma separated list of columns, a column prefixed
+// with - is sorted in descending order
+//
+//	name,-created_at
+func ParseSort(s string, columns Columns) ([]Sort, error) {
+	sorts := []Sort{}
+
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		sort := Asc(field)
+		if name, ok := strings.CutPrefix(field, "-"); ok {
+			sort = Desc(name)
+		}
+
+		if _, ok := columns[sort.Field]; !ok {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalid, sort.Field)
+		}
+
+		sorts = append(sorts, sort)
+	}
+
+	return sorts, nil
+}
+
+type tokenKind uint8
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOpen
+	tokenClose
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func lex(s string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(s)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenOpen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenClose, text: ")"})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ","})
+			i++
+		case r == '\'':
+			// a quote inside a string is written as two quotes
+			text := strings.Builder{}
+			i++
+
+			for {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("%w: unterminated string", ErrInvalid)
+				}
+
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						text.WriteRune('\'')
+						i += 2
+						continue
+					}
+
+					i++
+					break
+				}
+
+				text.WriteRune(runes[i])
+				i++
+			}
+
+			tokens = append(tokens, token{kind: tokenString, text: text.String()})
+		default:
+			start := i
+
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("(),'", runes[i]) {
+				i++
+			}
+
+			tokens = append(tokens, token{kind: tokenWord, text: string(runes[start:i])})
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+type parser struct {
+	tokens  []token
+	pos     int
+	columns Columns
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+// keyword consumes the next token when it is the word, ignoring case
+func (p *parser) keyword(word string) bool {
+	if t := p.peek(); t.kind == tokenWord && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	if t := p.next(); t.kind != kind {
+		return fmt.Errorf("%w: expected %s, got %q", ErrInvalid, text, t.text)
+	}
+
+	return nil
+}
+
+func (p *parser) or(depth int) (Expr, error) {
+	exprs := []Expr{}
+
+	for {
+		e, err := p.and(depth)
+		if err != nil {
+			return Expr{}, err
+		}
+
+		exprs = append(exprs, e)
+
+		if !p.keyword("or") {
+			break
+		}
+	}
+
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+
+	return Or(exprs...), nil
+}
+
+func (p *parser) and(depth int) (Expr, error) {
+	exprs := []Expr{}
+
+	for {
+		e, err := p.factor(depth)
+		if err != nil {
+			return Expr{}, err
+		}
+
+		exprs = append(exprs, e)
+
+		if !p.keyword("and") {
+			break
+		}
+	}
+
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+
+	return And(exprs...), nil
+}
+
+func (p *parser) factor(depth int) (Expr, error) {
+	if p.peek().kind != tokenOpen {
+		return p.predicate()
+	}
+
+	if depth >= maxDepth {
+		return Expr{}, fmt.Errorf("%w: nested deeper than %d", ErrInvalid, maxDepth)
+	}
+
+	p.next()
+
+	e, err := p.or(depth + 1)
+	if err != nil {
+		return Expr{}, err
+	}
+
+	return e, p.expect(tokenClose, ")")
+}
+
+func (p *parser) predicate() (Expr, error) {
+	t := p.next()
+	if t.kind != tokenWord {
+		return Expr{}, fmt.Errorf("%w: expected a column, got %q", ErrInvalid, t.text)
+	}
+
+	field := t.text
+
+	if _, ok := p.columns[field]; !ok {
+		return Expr{}, fmt.Errorf("%w: unknown column %q", ErrInvalid, field)
+	}
+
+	switch {
+	case p.keyword("is"):
+		not := p.keyword("not")
+
+		if !p.keyword("null") {
+			return Expr{}, fmt.Errorf("%w: expected null after is", ErrInvalid)
+		}
+
+		if not {
+			return IsNotNull(field), nil
+		}
+
+		return IsNull(field), nil
+	case p.keyword("in"):
+		if err := p.expect(tokenOpen, "("); err != nil {
+			return Expr{}, err
+		}
+
+		values := []any{}
+
+		for {
+			v, err := p.value(field)
+			if err != nil {
+				return Expr{}, err
+			}
+
+			values = append(values, v)
+
+			if p.peek().kind != tokenComma {
+				break
+			}
+
+			p.next()
+		}
+
+		return In(field, values...), p.expect(tokenClose, ")")
+	case p.keyword("between"):
+		from, err := p.value(field)
+		if err != nil {
+			return Expr{}, err
+		}
+
+		if !p.keyword("and") {
+			return Expr{}, fmt.Errorf("%w: expected and in between", ErrInvalid)
+		}
+
+		to, err := p.value(field)
+		if err != nil {
+			return Expr{}, err
+		}
+
+		return Range(field, from, to), nil
+	}
+
+	t = p.next()
+
+	op, ok := operators[strings.ToLower(t.text)]
+	if t.kind != tokenWord || !ok {
+		return Expr{}, fmt.Errorf("%w: unknown operator %q", ErrInvalid, t.text)
+	}
+
+	v, err := p.value(field)
+	if err != nil {
+		return Expr{}, err
+	}
+
+	return Expr{Field: field, Op: op, Values: []any{v}}, nil
+}
+
+func (p *parser) value(field string) (any, error) {
+	t := p.next()
+	if t.kind != tokenWord && t.kind != tokenString {
+		return nil, fmt.Errorf("%w: expected a value for %s, got %q", ErrInvalid, field, t.text)
+	}
+
+	v, err := convert(t.text, p.columns[field])
+	if err != nil {
+		return nil, fmt.Errorf("%w: value %q of %s: %w", ErrInvalid, t.text, field, err)
+	}
+
+	return v, nil
+}
+
+// convert converts s to the kind of t, times are RFC 3339 timestamps or
+// dates, anything which is not a number, boolean or time is kept a string
+func convert(s string, t reflect.Type) (any, error) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return ts, nil
+		}
+
+		return time.Parse(time.DateOnly, s)
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(s, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(s, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(s, 64)
+	case reflect.Bool:
+		return strconv.ParseBool(s)
+	}
+
+	return s, nil
+}
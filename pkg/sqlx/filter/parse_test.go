@@ -0,0 +1,140 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type row struct {
+	ID        int64     `db:"id"`
+	Name      string    `db:"name"`
+	ParentID  *int64    `db:"parent_id"`
+	Score     float64   `db:"score"`
+	Active    bool      `db:"active"`
+	CreatedAt time.Time `db:"created_at"`
+	Tags      []string  `db:"tags"`
+	Hidden    string    `db:"-"`
+}
+
+var columns = ColumnsOf(row{})
+
+func TestParse(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter string
+		want   Expr
+	}{
+		{"empty", "  ", Expr{}},
+		{"eq", "name eq mammal", Eq("name", "mammal")},
+		{"symbol", "id >= 3", Gte("id", int64(3))},
+		{"operator case", "id LT 3", Lt("id", int64(3))},
+		{"ne", "id <> 3", Ne("id", int64(3))},
+		{"quoted", "name = 'big cat'", Eq("name", "big cat")},
+		{"escaped quote", "name = 'O''Brien'''", Eq("name", "O'Brien'")},
+		{"empty string", "name = ''", Eq("name", "")},
+		{"like", "name like 'Mam%'", Like("name", "Mam%")},
+		{"in", "id in (1, 2,3)", In("id", int64(1), int64(2), int64(3))},
+		{"between", "created_at between 2024-01-01 and 2024-01-01T00:00:00Z", Range("created_at", day, day)},
+		{"is null", "parent_id is null", IsNull("parent_id")},
+		{"is not null", "parent_id IS NOT NULL", IsNotNull("parent_id")},
+		{"pointer column", "parent_id = 7", Eq("parent_id", int64(7))},
+		{"float", "score > 1.5", Gt("score", 1.5)},
+		{"bool", "active = true", Eq("active", true)},
+		{
+			"and before or",
+			"id = 1 or id = 2 and name = a",
+			Or(Eq("id", int64(1)), And(Eq("id", int64(2)), Eq("name", "a"))),
+		},
+		{
+			"and before or on the right",
+			"id = 1 and name = a or id = 2",
+			Or(And(Eq("id", int64(1)), Eq("name", "a")), Eq("id", int64(2))),
+		},
+		{
+			"parentheses",
+			"id = 1 and (name = a or name = b)",
+			And(Eq("id", int64(1)), Or(Eq("name", "a"), Eq("name", "b"))),
+		},
+		{
+			"between and",
+			"id between 1 and 2 and name = a",
+			And(Range("id", int64(1), int64(2)), Eq("name", "a")),
+		},
+		{"nested", strings.Repeat("(", maxDepth) + "id = 1" + strings.Repeat(")", maxDepth), Eq("id", int64(1))},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.filter, columns)
+		if err != nil {
+			t.Errorf("%s: Parse(%q) error = %v", tt.name, tt.filter, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Parse(%q) = %+v, want %+v", tt.name, tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   string
+	}{
+		{"unknown column", "colour = red", `unknown column "colour"`},
+		{"ignored column", "hidden = a", `unknown column "hidden"`},
+		{"slice column", "tags = a", `unknown column "tags"`},
+		{"unknown operator", "name ~ a", `unknown operator "~"`},
+		{"operator missing", "name", `unknown operator ""`},
+		{"value missing", "name =", "expected a value for name"},
+		{"int", "id = one", `value "one" of id`},
+		{"int overflow", "id = 9223372036854775808", `value "9223372036854775808" of id`},
+		{"float", "score > high", `value "high" of score`},
+		{"bool", "active = yes", `value "yes" of active`},
+		{"time", "created_at > yesterday", `value "yesterday" of created_at`},
+		{"in value", "id in (1, two)", `value "two" of id`},
+		{"in unclosed", "id in (1, 2", `expected ), got ""`},
+		{"in empty", "id in ()", "expected a value for id"},
+		{"between without and", "id between 1 or 2", "expected and in between"},
+		{"is without null", "parent_id is not empty", "expected null after is"},
+		{"unterminated string", "name = 'a", "unterminated string"},
+		{"unclosed parenthesis", "(id = 1", `expected ), got ""`},
+		{"trailing", "id = 1 id = 2", `unexpected "id"`},
+		{"dangling or", "id = 1 or", "expected a column"},
+		{"too deep", strings.Repeat("(", maxDepth+1) + "id = 1" + strings.Repeat(")", maxDepth+1), "nested deeper than 16"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.filter, columns)
+
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: Parse(%q) error = %v, want %v", tt.name, tt.filter, err, ErrInvalid)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Parse(%q) error = %q, want it to contain %q", tt.name, tt.filter, err, tt.want)
+		}
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	got, err := ParseSort(" name, -created_at,,", columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []Sort{Asc("name"), Desc("created_at")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSort() = %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseSort("name,-colour", columns); !errors.Is(err, ErrInvalid) {
+		t.Errorf("ParseSort() error = %v, want %v", err, ErrInvalid)
+	}
+}
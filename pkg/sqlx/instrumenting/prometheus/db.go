@@ -11,160 +11,92 @@ import (
 	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/nop"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 type DB struct {
-	inner kryptonsqlx.DB
+	inner       kryptonsqlx.DB
+	registerer  prometheus.Registerer
+	namespace   string
+	subsystem   string
+	constLabels prometheus.Labels
+	buckets     []float64
+	native      *nativeHistograms
+	classifier  Classifier
+	labels      *queryLabels
+	metrics     *metrics
 }
 
 type DBOption func(db *DB)
 
-var (
-	beginCount    prometheus.Counter
-	beginErrors   prometheus.Counter
-	beginDuration prometheus.Histogram
-	execCount     *prometheus.CounterVec
-	execErrors    *prometheus.CounterVec
-	execDuration  *prometheus.HistogramVec
-)
-
-func init() {
-	reg := prometheus.NewRegistry()
-	factory := promauto.With(reg)
-
-	beginCount = factory.NewCounter(
-		prometheus.CounterOpts{
-			Namespace: "copper.co",
-			Name:      "sql_begin",
-			Help:      "Number of calls to begin an SQL transaction",
-		},
-	)
-
-	beginErrors = factory.NewCounter(
-		prometheus.CounterOpts{
-			Namespace: "copper.co",
-			Name:      "sql_begin_errors",
-			Help:      "Number of erors from trying to begin an SQL transaction",
-		},
-	)
-
-	beginDuration = factory.NewHistogram(
-		prometheus.HistogramOpts{
-			Namespace: "copper.co",
-			Name:      "sql_begin_errors",
-			Help:      "Duration fo calls to begin an SQL transaction, measured in seconds",
-			Buckets:   []float64{0.1, 0.2, 0.3, 0.5, 1},
-		},
-	)
-
-	execCount = factory.NewCounterVec(
-		prometheus.CounterOpts{
-			Namespace: "copper.co",
-			Name:      "sql_exec_count",
-			Help:      "Number of calls to execute an SQL query",
-		},
-		[]string{"query"},
-	)
-
-	execErrors = factory.NewCounterVec(
-		prometheus.CounterOpts{
-			Namespace: "copper.co",
-			Name:      "sql_exec_errors",
-			Help:      "Number of erors from trying to execute an SQL query",
-		},
-		[]string{"query"},
-	)
-
-	execDuration = factory.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Namespace: "copper.co",
-			Name:      "sql_exec_duration",
-			Help:      "Duration of execution of an SQL query, measured in seconds",
-			Buckets:   []float64{0.1, 0.2, 0.3, 0.5, 1},
-		},
-		[]string{"query"},
-	)
-}
-
 func NewDB(opts ...DBOption) kryptonsqlx.DB {
 	db := &DB{
-		inner: nop.NewDB(),
+		inner:      nop.NewDB(),
+		registerer: prometheus.DefaultRegisterer,
+		namespace:  defaultNamespace,
+		buckets:    prometheus.DefBuckets,
+		classifier: DefaultClassifier,
+		labels: &queryLabels{
+			labeler: DefaultQueryLabeler,
+			max:     defaultMaxQueryLabels,
+			seen:    map[string]struct{}{},
+		},
 	}
 
 	for _, opt := range opts {
 		opt(db)
 	}
 
+	db.metrics = newMetrics(db)
+
 	return db
 }
 
-// Begin prometheus instrumentation implementation of sqlx.Begin
+// Begin prometheus instrumentation implementation of sqlx.Begin, without a
+// context the commit or rollback is not recorded, see BeginTx
 func (db *DB) Begin() (*sql.Tx, error) {
 	begin := time.Now()
-	defer func() {
-		beginDuration.Observe(time.Since(begin).Seconds())
-	}()
-
-	beginCount.Inc()
 
 	tx, err := db.inner.Begin()
-	if err != nil {
-		beginErrors.Inc()
-	}
+
+	db.observeBegin(context.TODO(), begin, err)
 
 	return tx, err
 }
 
-// BeginTx prometheus instrumentation implementation of sqlx.BeginTx
+// BeginTx prometheus instrumentation implementation of sqlx.BeginTx, the commit
+// or rollback is recorded when the driver is wrapped with WrapDriver
 func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
 	begin := time.Now()
-	defer func() {
-		beginDuration.Observe(time.Since(begin).Seconds())
-	}()
 
-	beginCount.Inc()
+	tx, err := db.inner.BeginTx(db.withTxObserver(ctx), opts)
 
-	tx, err := db.inner.BeginTx(ctx, opts)
-	if err != nil {
-		beginErrors.Inc()
-	}
+	db.observeBegin(ctx, begin, err)
 
 	return tx, err
 }
 
-// BeginTxx prometheus instrumentation implementation of sqlx.BeginTxx
+// BeginTxx prometheus instrumentation implementation of sqlx.BeginTxx, the commit
+// or rollback is recorded when the driver is wrapped with WrapDriver
 func (db *DB) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error) {
 	begin := time.Now()
-	defer func() {
-		beginDuration.Observe(time.Since(begin).Seconds())
-	}()
 
-	beginCount.Inc()
+	tx, err := db.inner.BeginTxx(db.withTxObserver(ctx), opts)
 
-	tx, err := db.inner.BeginTxx(ctx, opts)
-	if err != nil {
-		beginErrors.Inc()
-	}
+	db.observeBegin(ctx, begin, err)
 
 	return tx, err
 }
 
-// Beginx prometheus instrumentation implementation of sqlx.Beginx
+// Beginx prometheus instrumentation implementation of sqlx.Beginx, without a
+// context the commit or rollback is not recorded, see BeginTxx
 func (db *DB) Beginx() (*sqlx.Tx, error) {
 	begin := time.Now()
-	defer func() {
-		beginDuration.Observe(time.Since(begin).Seconds())
-	}()
 
-	beginCount.Inc()
+	tx, err := db.inner.Beginx()
 
-	sql, err := db.inner.Beginx()
-	if err != nil {
-		beginErrors.Inc()
-	}
+	db.observeBegin(context.TODO(), begin, err)
 
-	return sql, err
+	return tx, err
 }
 
 // BindNamed prometheus instrumentation implementation of sqlx.BindNamed, does not
@@ -195,37 +127,22 @@ func (db *DB) Connx(ctx context.Context) (*sqlx.Conn, error) {
 // gather metrics as it's uneccesary
 func (db *DB) Driver() driver.Driver {
 	return db.inner.Driver()
-
 }
 
 // DriverName prometheus instrumentation implementation of sqlx.DriverName, does not
 // gather metrics as it's uneccesary
 func (db *DB) DriverName() string {
 	return db.inner.DriverName()
-
 }
 
 // Exec prometheus instrumentation implementation of sqlx.Exec
 func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
 	begin := time.Now()
 
-	defer func() {
-		execDuration.With(prometheus.Labels{
-			"query": query,
-		}).Observe(time.Since(begin).Seconds())
-	}()
-
-	execCount.With(prometheus.Labels{
-		"query": query,
-	}).Inc()
-
 	res, err := db.inner.Exec(query, args...)
 
-	if err != nil {
-		execErrors.With(prometheus.Labels{
-			"query": query,
-		}).Inc()
-	}
+	db.observeExec(context.TODO(), query, begin, err)
+	db.observeRowsAffected(context.TODO(), query, res)
 
 	return res, err
 }
@@ -234,23 +151,10 @@ func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
 func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
 	begin := time.Now()
 
-	defer func() {
-		execDuration.With(prometheus.Labels{
-			"query": query,
-		}).Observe(time.Since(begin).Seconds())
-	}()
-
-	execCount.With(prometheus.Labels{
-		"query": query,
-	}).Inc()
-
 	res, err := db.inner.ExecContext(ctx, query, args...)
 
-	if err != nil {
-		execErrors.With(prometheus.Labels{
-			"query": query,
-		}).Inc()
-	}
+	db.observeExec(ctx, query, begin, err)
+	db.observeRowsAffected(ctx, query, res)
 
 	return res, err
 }
@@ -259,22 +163,12 @@ func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.R
 func (db *DB) Get(dest interface{}, query string, args ...interface{}) error {
 	begin := time.Now()
 
-	defer func() {
-		execDuration.With(prometheus.Labels{
-			"query": query,
-		}).Observe(time.Since(begin).Seconds())
-	}()
-
-	execCount.With(prometheus.Labels{
-		"query": query,
-	}).Inc()
-
 	err := db.inner.Get(dest, query, args...)
 
-	if err != nil {
-		execErrors.With(prometheus.Labels{
-			"query": query,
-		}).Inc()
+	db.observeQuery(context.TODO(), query, begin, err)
+
+	if err == nil {
+		db.observeQueryRows(context.TODO(), query, 1)
 	}
 
 	return err
@@ -284,22 +178,12 @@ func (db *DB) Get(dest interface{}, query string, args ...interface{}) error {
 func (db *DB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
 	begin := time.Now()
 
-	defer func() {
-		execDuration.With(prometheus.Labels{
-			"query": query,
-		}).Observe(time.Since(begin).Seconds())
-	}()
-
-	execCount.With(prometheus.Labels{
-		"query": query,
-	}).Inc()
-
 	err := db.inner.GetContext(ctx, dest, query, args...)
 
-	if err != nil {
-		execErrors.With(prometheus.Labels{
-			"query": query,
-		}).Inc()
+	db.observeQuery(ctx, query, begin, err)
+
+	if err == nil {
+		db.observeQueryRows(ctx, query, 1)
 	}
 
 	return err
@@ -309,89 +193,56 @@ func (db *DB) GetContext(ctx context.Context, dest interface{}, query string, ar
 // gather metrics as it's uneccesary
 func (db *DB) MapperFunc(mf func(string) string) {
 	db.inner.MapperFunc(mf)
-
 }
 
-// MustBegin prometheus instrumentation implementation of sqlx.MustBegin
+// MustBegin prometheus instrumentation implementation of sqlx.MustBegin, without a
+// context the commit or rollback is not recorded, see MustBeginTx
 func (db *DB) MustBegin() *sqlx.Tx {
-	begin := time.Now()
-
-	defer func() {
-		beginDuration.Observe(time.Since(begin).Seconds())
-	}()
-
-	beginCount.Inc()
+	defer db.observeMustBegin(context.TODO(), time.Now())
 
 	return db.inner.MustBegin()
 }
 
-// MustBeginTx prometheus instrumentation implementation of sqlx.MustBeginTx
+// MustBeginTx prometheus instrumentation implementation of sqlx.MustBeginTx, the commit
+// or rollback is recorded when the driver is wrapped with WrapDriver
 func (db *DB) MustBeginTx(ctx context.Context, opts *sql.TxOptions) *sqlx.Tx {
-	begin := time.Now()
-
-	defer func() {
-		beginDuration.Observe(time.Since(begin).Seconds())
-	}()
+	defer db.observeMustBegin(ctx, time.Now())
 
-	beginCount.Inc()
-
-	return db.inner.MustBeginTx(ctx, opts)
+	return db.inner.MustBeginTx(db.withTxObserver(ctx), opts)
 }
 
 // MustExec prometheus instrumentation implementation of sqlx.MustExec
 func (db *DB) MustExec(query string, args ...interface{}) sql.Result {
 	begin := time.Now()
 
-	defer func() {
-		execDuration.With(prometheus.Labels{
-			"query": query,
-		}).Observe(time.Since(begin).Seconds())
-	}()
+	res := db.inner.MustExec(query, args...)
 
-	execCount.With(prometheus.Labels{
-		"query": query,
-	}).Inc()
+	db.observeExec(context.TODO(), query, begin, nil)
+	db.observeRowsAffected(context.TODO(), query, res)
 
-	return db.inner.MustExec(query, args...)
+	return res
 }
 
 // MustExecContext prometheus instrumentation implementation of sqlx.MustExecContext
 func (db *DB) MustExecContext(ctx context.Context, query string, args ...interface{}) sql.Result {
 	begin := time.Now()
 
-	defer func() {
-		execDuration.With(prometheus.Labels{
-			"query": query,
-		}).Observe(time.Since(begin).Seconds())
-	}()
+	res := db.inner.MustExecContext(ctx, query, args...)
 
-	execCount.With(prometheus.Labels{
-		"query": query,
-	}).Inc()
+	db.observeExec(ctx, query, begin, nil)
+	db.observeRowsAffected(ctx, query, res)
 
-	return db.inner.MustExecContext(ctx, query, args...)
+	return res
 }
 
 // NamedExec prometheus instrumentation implementation of sqlx.NamedExec
 func (db *DB) NamedExec(query string, arg interface{}) (sql.Result, error) {
 	begin := time.Now()
 
-	defer func() {
-		execDuration.With(prometheus.Labels{
-			"query": query,
-		}).Observe(time.Since(begin).Seconds())
-	}()
-
-	execCount.With(prometheus.Labels{
-		"query": query,
-	}).Inc()
-
 	res, err := db.inner.NamedExec(query, arg)
-	if err != nil {
-		execErrors.With(prometheus.Labels{
-			"query": query,
-		}).Inc()
-	}
+
+	db.observeExec(context.TODO(), query, begin, err)
+	db.observeRowsAffected(context.TODO(), query, res)
 
 	return res, err
 }
@@ -400,142 +251,134 @@ func (db *DB) NamedExec(query string, arg interface{}) (sql.Result, error) {
 func (db *DB) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
 	begin := time.Now()
 
-	defer func() {
-		execDuration.With(prometheus.Labels{
-			"query": query,
-		}).Observe(time.Since(begin).Seconds())
-	}()
-
-	execCount.With(prometheus.Labels{
-		"query": query,
-	}).Inc()
-
 	res, err := db.inner.NamedExecContext(ctx, query, arg)
-	if err != nil {
-		execErrors.With(prometheus.Labels{
-			"query": query,
-		}).Inc()
-	}
+
+	db.observeExec(ctx, query, begin, err)
+	db.observeRowsAffected(ctx, query, res)
 
 	return res, err
 }
 
-// NamedQuery prometheus instrumentation implementation of sqlx.NamedQuery
+// NamedQuery prometheus instrumentation implementation of sqlx.NamedQuery, without a
+// context the returned rows are not counted, even when the driver is wrapped
+// with WrapDriver, see NamedQueryContext
 func (db *DB) NamedQuery(query string, arg interface{}) (*sqlx.Rows, error) {
 	begin := time.Now()
 
-	defer func() {
-		execDuration.With(prometheus.Labels{
-			"query": query,
-		}).Observe(time.Since(begin).Seconds())
-	}()
-
-	execCount.With(prometheus.Labels{
-		"query": query,
-	}).Inc()
-
 	res, err := db.inner.NamedQuery(query, arg)
-	if err != nil {
-		execErrors.With(prometheus.Labels{
-			"query": query,
-		}).Inc()
-	}
+
+	db.observeQuery(context.TODO(), query, begin, err)
 
 	return res, err
 }
 
-// NamedQueryContext prometheus instrumentation implementation of sqlx.NamedQueryContext
+// NamedQueryContext prometheus instrumentation implementation of sqlx.NamedQueryContext, the returned
+// rows are counted and timed when the driver is wrapped with WrapDriver
 func (db *DB) NamedQueryContext(ctx context.Context, query string, arg interface{}) (*sqlx.Rows, error) {
 	begin := time.Now()
 
-	defer func() {
-		execDuration.With(prometheus.Labels{
-			"query": query,
-		}).Observe(time.Since(begin).Seconds())
-	}()
-
-	execCount.With(prometheus.Labels{
-		"query": query,
-	}).Inc()
-
-	res, err := db.inner.NamedQueryContext(ctx, query, arg)
-	if err != nil {
-		execErrors.With(prometheus.Labels{
-			"query": query,
-		}).Inc()
-	}
+	res, err := db.inner.NamedQueryContext(db.withRowsObserver(ctx, query), query, arg)
+
+	db.observeQuery(ctx, query, begin, err)
 
 	return res, err
 }
 
-// Ping prometheus instrumentation implementation of sqlx.Ping, does not
-// gather metrics as it's uneccesary
+// Ping prometheus instrumentation implementation of sqlx.Ping
 func (db *DB) Ping() error {
-	return db.inner.Ping()
+	begin := time.Now()
+
+	err := db.inner.Ping()
+
+	db.observeOperation(context.TODO(), "ping", begin, err)
+
+	return err
 }
 
-// PingContext prometheus instrumentation implementation of sqlx.PingContext,
-// does not gather metrics as it's uneccesary
+// PingContext prometheus instrumentation implementation of sqlx.PingContext
 func (db *DB) PingContext(ctx context.Context) error {
-	return db.inner.PingContext(ctx)
+	begin := time.Now()
+
+	err := db.inner.PingContext(ctx)
+
+	db.observeOperation(ctx, "ping", begin, err)
+
+	return err
 }
 
-// Prepare prometheus instrumentation implementation of sqlx.Prepare, does not
-// gather metrics as it's uneccesary
+// Prepare prometheus instrumentation implementation of sqlx.Prepare
 func (db *DB) Prepare(query string) (*sql.Stmt, error) {
-	return db.inner.Prepare(query)
+	begin := time.Now()
+
+	stmt, err := db.inner.Prepare(query)
+
+	db.observeOperation(context.TODO(), "prepare", begin, err)
+
+	return stmt, err
 }
 
-// PrepareContext prometheus instrumentation implementation of sqlx.PrepareContext, does not
-// gather metrics as it's uneccesary
+// PrepareContext prometheus instrumentation implementation of sqlx.PrepareContext
 func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
-	return db.inner.PrepareContext(ctx, query)
+	begin := time.Now()
+
+	stmt, err := db.inner.PrepareContext(ctx, query)
+
+	db.observeOperation(ctx, "prepare", begin, err)
+
+	return stmt, err
 }
 
-// PrepareNamed prometheus instrumentation implementation of sqlx.PrepareNamed, does not
-// gather metrics as it's uneccesary
+// PrepareNamed prometheus instrumentation implementation of sqlx.PrepareNamed
 func (db *DB) PrepareNamed(query string) (*sqlx.NamedStmt, error) {
-	return db.inner.PrepareNamed(query)
+	begin := time.Now()
+
+	stmt, err := db.inner.PrepareNamed(query)
+
+	db.observeOperation(context.TODO(), "prepare", begin, err)
+
+	return stmt, err
 }
 
-// PrepareNamedContext prometheus instrumentation implementation of sqlx.PrepareNamedContext, does not
-// gather metrics as it's uneccesary
+// PrepareNamedContext prometheus instrumentation implementation of sqlx.PrepareNamedContext
 func (db *DB) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
-	return db.inner.PrepareNamedContext(ctx, query)
+	begin := time.Now()
+
+	stmt, err := db.inner.PrepareNamedContext(ctx, query)
+
+	db.observeOperation(ctx, "prepare", begin, err)
+
+	return stmt, err
 }
 
-// Preparex prometheus instrumentation implementation of sqlx.Preparex, does not
-// gather metrics as it's uneccesary
+// Preparex prometheus instrumentation implementation of sqlx.Preparex
 func (db *DB) Preparex(query string) (*sqlx.Stmt, error) {
-	return db.inner.Preparex(query)
+	begin := time.Now()
+
+	stmt, err := db.inner.Preparex(query)
+
+	db.observeOperation(context.TODO(), "prepare", begin, err)
+
+	return stmt, err
 }
 
-// PreparexContext prometheus instrumentation implementation of sqlx.PreparexContext, does not
-// gather metrics as it's uneccesary
+// PreparexContext prometheus instrumentation implementation of sqlx.PreparexContext
 func (db *DB) PreparexContext(ctx context.Context, query string) (*sqlx.Stmt, error) {
-	return db.inner.PreparexContext(ctx, query)
+	begin := time.Now()
+
+	stmt, err := db.inner.PreparexContext(ctx, query)
+
+	db.observeOperation(ctx, "prepare", begin, err)
+
+	return stmt, err
 }
 
 // Query prometheus instrumentation implementation of sqlx.Query
 func (db *DB) Query(query string, args ...any) (*sql.Rows, error) {
 	begin := time.Now()
 
-	defer func() {
-		execDuration.With(prometheus.Labels{
-			"query": query,
-		}).Observe(time.Since(begin).Seconds())
-	}()
-
-	execCount.With(prometheus.Labels{
-		"query": query,
-	}).Inc()
-
 	res, err := db.inner.Query(query, args...)
-	if err != nil {
-		execErrors.With(prometheus.Labels{
-			"query": query,
-		}).Inc()
-	}
+
+	db.observeQuery(context.TODO(), query, begin, err)
 
 	return res, err
 }
@@ -544,138 +387,62 @@ func (db *DB) Query(query string, args ...any) (*sql.Rows, error) {
 func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
 	begin := time.Now()
 
-	defer func() {
-		execDuration.With(prometheus.Labels{
-			"query": query,
-		}).Observe(time.Since(begin).Seconds())
-	}()
-
-	execCount.With(prometheus.Labels{
-		"query": query,
-	}).Inc()
-
 	res, err := db.inner.QueryContext(ctx, query, args...)
-	if err != nil {
-		execErrors.With(prometheus.Labels{
-			"query": query,
-		}).Inc()
-	}
+
+	db.observeQuery(ctx, query, begin, err)
 
 	return res, err
 }
 
 // QueryRow prometheus instrumentation implementation of sqlx.QueryRow
 func (db *DB) QueryRow(query string, args ...any) *sql.Row {
-	begin := time.Now()
-
-	defer func() {
-		execDuration.With(prometheus.Labels{
-			"query": query,
-		}).Observe(time.Since(begin).Seconds())
-	}()
-
-	execCount.With(prometheus.Labels{
-		"query": query,
-	}).Inc()
+	defer db.observeQuery(context.TODO(), query, time.Now(), nil)
 
 	return db.inner.QueryRow(query, args...)
 }
 
 // QueryRowContext prometheus instrumentation implementation of sqlx.QueryRowContext
 func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
-	begin := time.Now()
-
-	defer func() {
-		execDuration.With(prometheus.Labels{
-			"query": query,
-		}).Observe(time.Since(begin).Seconds())
-	}()
-
-	execCount.With(prometheus.Labels{
-		"query": query,
-	}).Inc()
+	defer db.observeQuery(ctx, query, time.Now(), nil)
 
 	return db.inner.QueryRowContext(ctx, query, args...)
 }
 
 // QueryRowx prometheus instrumentation implementation of sqlx.QueryRowx
 func (db *DB) QueryRowx(query string, args ...interface{}) *sqlx.Row {
-	begin := time.Now()
-
-	defer func() {
-		execDuration.With(prometheus.Labels{
-			"query": query,
-		}).Observe(time.Since(begin).Seconds())
-	}()
-
-	execCount.With(prometheus.Labels{
-		"query": query,
-	}).Inc()
+	defer db.observeQuery(context.TODO(), query, time.Now(), nil)
 
 	return db.inner.QueryRowx(query, args...)
 }
 
 // QueryRowxContext prometheus instrumentation implementation of sqlx.QueryRowxContext
 func (db *DB) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
-	begin := time.Now()
-
-	defer func() {
-		execDuration.With(prometheus.Labels{
-			"query": query,
-		}).Observe(time.Since(begin).Seconds())
-	}()
-
-	execCount.With(prometheus.Labels{
-		"query": query,
-	}).Inc()
+	defer db.observeQuery(ctx, query, time.Now(), nil)
 
 	return db.inner.QueryRowxContext(ctx, query, args...)
 }
 
-// Queryx prometheus instrumentation implementation of sqlx.Queryx
+// Queryx prometheus instrumentation implementation of sqlx.Queryx, without a
+// context the returned rows are not counted, even when the driver is wrapped
+// with WrapDriver, see QueryxContext
 func (db *DB) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
 	begin := time.Now()
 
-	defer func() {
-		execDuration.With(prometheus.Labels{
-			"query": query,
-		}).Observe(time.Since(begin).Seconds())
-	}()
-
-	execCount.With(prometheus.Labels{
-		"query": query,
-	}).Inc()
-
 	res, err := db.inner.Queryx(query, args...)
-	if err != nil {
-		execErrors.With(prometheus.Labels{
-			"query": query,
-		}).Inc()
-	}
+
+	db.observeQuery(context.TODO(), query, begin, err)
 
 	return res, err
 }
 
-// QueryxContext prometheus instrumentation implementation of sqlx.QueryxContext
+// QueryxContext prometheus instrumentation implementation of sqlx.QueryxContext, the returned
+// rows are counted and timed when the driver is wrapped with WrapDriver
 func (db *DB) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
 	begin := time.Now()
 
-	defer func() {
-		execDuration.With(prometheus.Labels{
-			"query": query,
-		}).Observe(time.Since(begin).Seconds())
-	}()
-
-	execCount.With(prometheus.Labels{
-		"query": query,
-	}).Inc()
-
-	res, err := db.inner.QueryxContext(ctx, query, args...)
-	if err != nil {
-		execErrors.With(prometheus.Labels{
-			"query": query,
-		}).Inc()
-	}
+	res, err := db.inner.QueryxContext(db.withRowsObserver(ctx, query), query, args...)
+
+	db.observeQuery(ctx, query, begin, err)
 
 	return res, err
 }
@@ -688,12 +455,32 @@ func (db *DB) Rebind(query string) string {
 
 // Select prometheus instrumentation implementation of sqlx.Select
 func (db *DB) Select(dest interface{}, query string, args ...interface{}) error {
-	return db.inner.Select(dest, query, args)
+	begin := time.Now()
+
+	err := db.inner.Select(dest, query, args...)
+
+	db.observeQuery(context.TODO(), query, begin, err)
+
+	if err == nil {
+		db.observeQueryRows(context.TODO(), query, rowCount(dest))
+	}
+
+	return err
 }
 
 // SelectContext prometheus instrumentation implementation of sqlx.SelectContext
 func (db *DB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	return db.inner.SelectContext(ctx, dest, query, args)
+	begin := time.Now()
+
+	err := db.inner.SelectContext(ctx, dest, query, args...)
+
+	db.observeQuery(ctx, query, begin, err)
+
+	if err == nil {
+		db.observeQueryRows(ctx, query, rowCount(dest))
+	}
+
+	return err
 }
 
 // SetConnMaxIdleTime prometheus instrumentation implementation of sqlx.SetConnMaxIdleTime, does not
@@ -0,0 +1,96 @@
+package prometheus
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// histogramSample the sample count and sum of the histogram of the labels
+func histogramSample(t *testing.T, h *prometheus.HistogramVec, labels ...string) (uint64, float64) {
+	t.Helper()
+
+	m := &dto.Metric{}
+	if err := h.WithLabelValues(labels...).(prometheus.Metric).Write(m); err != nil {
+		t.Fatal(err)
+	}
+
+	return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+}
+
+func TestQueryRows(t *testing.T) {
+	pool := sqlx.NewDb(sql.OpenDB(NewConnector(&sqlite3.SQLiteDriver{}, ":memory:")), "sqlite3")
+	defer pool.Close()
+
+	// a single connection, every connection to :memory: is its own database
+	pool.SetMaxOpenConns(1)
+
+	if _, err := pool.Exec(`CREATE TABLE t (id INTEGER); INSERT INTO t (id) VALUES (1), (2), (3)`); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	// without a context the rows are not counted, the calls are recorded all
+	// the same
+	queries := []struct {
+		name    string
+		query   func(db *DB) (*sqlx.Rows, error)
+		counted bool
+	}{
+		{"Queryx", func(db *DB) (*sqlx.Rows, error) {
+			return db.Queryx("SELECT id FROM t WHERE id > $1", 0)
+		}, false},
+		{"NamedQuery", func(db *DB) (*sqlx.Rows, error) {
+			return db.NamedQuery("SELECT id FROM t WHERE id > :id", map[string]any{"id": 0})
+		}, false},
+		{"QueryxContext", func(db *DB) (*sqlx.Rows, error) {
+			return db.QueryxContext(ctx, "SELECT id FROM t WHERE id > $1", 0)
+		}, true},
+		{"NamedQueryContext", func(db *DB) (*sqlx.Rows, error) {
+			return db.NamedQueryContext(ctx, "SELECT id FROM t WHERE id > :id", map[string]any{"id": 0})
+		}, true},
+	}
+
+	for _, q := range queries {
+		db := NewDB(
+			DBWithInnerDB(pool),
+			DBWithRegisterer(prometheus.NewRegistry()),
+		).(*DB)
+
+		rows, err := q.query(db)
+		if err != nil {
+			t.Fatalf("%s: %v", q.name, err)
+		}
+
+		n := 0
+		for rows.Next() {
+			n++
+		}
+
+		if err := rows.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if calls := testutil.ToFloat64(db.metrics.execCount.WithLabelValues("select:t", string(OutcomeOK))); calls != 1 {
+			t.Errorf("%s: %v calls recorded, want 1", q.name, calls)
+		}
+
+		count, sum := histogramSample(t, db.metrics.queryRows, "select:t")
+
+		want := uint64(0)
+		if q.counted {
+			want = 1
+		}
+
+		if n != 3 || count != want || sum != float64(3*want) {
+			t.Errorf("%s: %d rows iterated, rows histogram count = %d sum = %v, want 3, %d and %d", q.name, n, count, sum, want, 3*want)
+		}
+	}
+}
@@ -1,9 +1,91 @@
 package prometheus
 
-import "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+import (
+	"time"
+
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
 
 func DBWithInnerDB(db sqlx.DB) DBOption {
 	return func(d *DB) {
 		d.inner = db
 	}
 }
+
+// DBWithRegisterer sets the registerer the metrics are registered with,
+// defaults to prometheus.DefaultRegisterer
+func DBWithRegisterer(r prometheus.Registerer) DBOption {
+	return func(d *DB) {
+		d.registerer = r
+	}
+}
+
+// DBWithNamespace sets the namespace of the metrics, defaults to "copper"
+func DBWithNamespace(namespace string) DBOption {
+	return func(d *DB) {
+		d.namespace = namespace
+	}
+}
+
+// DBWithSubsystem sets the subsystem of the metrics
+func DBWithSubsystem(subsystem string) DBOption {
+	return func(d *DB) {
+		d.subsystem = subsystem
+	}
+}
+
+// DBWithConstLabels sets labels attached to every metric, such as the
+// database name and role, allowing several instrumented DBs to share a
+// registry
+func DBWithConstLabels(labels prometheus.Labels) DBOption {
+	return func(d *DB) {
+		d.constLabels = labels
+	}
+}
+
+// DBWithBuckets sets the buckets of the duration histograms, measured in
+// seconds, defaults to prometheus.DefBuckets
+func DBWithBuckets(buckets ...float64) DBOption {
+	return func(d *DB) {
+		d.buckets = buckets
+	}
+}
+
+// DBWithNativeHistograms enables native histograms alongside the classic
+// buckets, with the growth factor between buckets and the maximum number of
+// buckets before the histogram is reset
+func DBWithNativeHistograms(bucketFactor float64, maxBucketNumber uint32) DBOption {
+	return func(d *DB) {
+		d.native = &nativeHistograms{
+			bucketFactor:     bucketFactor,
+			maxBucketNumber:  maxBucketNumber,
+			minResetDuration: time.Hour,
+		}
+	}
+}
+
+// DBWithClassifier sets the classifier mapping errors to the outcome label of
+// the metrics, defaults to DefaultClassifier
+func DBWithClassifier(c Classifier) DBOption {
+	return func(d *DB) {
+		d.classifier = c
+	}
+}
+
+// DBWithQueryLabeler sets the function mapping queries to the query label of
+// the metrics, defaults to DefaultQueryLabeler
+func DBWithQueryLabeler(l QueryLabeler) DBOption {
+	return func(d *DB) {
+		d.labels.labeler = l
+	}
+}
+
+// DBWithMaxQueryLabels caps the number of distinct values of the query label,
+// queries labelled past the cap are labelled "other", defaults to 100
+func DBWithMaxQueryLabels(n int) DBOption {
+	return func(d *DB) {
+		d.labels.max = n
+	}
+}
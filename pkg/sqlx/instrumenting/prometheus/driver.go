@@ -0,0 +1,434 @@
+package prometheus
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// WrapDriver wraps d so that the rows of queries made through an instrumented
+// DB are counted and timed, the rows returned by the DB are concrete sqlx
+// types so they can only be observed from underneath database/sql
+func WrapDriver(d driver.Driver) driver.Driver {
+	return &wrappedDriver{inner: d}
+}
+
+// NewConnector returns a driver.Connector opening connections to dsn through
+// the wrapped d, for use with sql.OpenDB
+func NewConnector(d driver.Driver, dsn string) driver.Connector {
+	if dc, ok := d.(driver.DriverContext); ok {
+		if c, err := dc.OpenConnector(dsn); err == nil {
+			return &wrappedConnector{inner: c, driver: WrapDriver(d)}
+		}
+	}
+
+	return &dsnConnector{dsn: dsn, driver: WrapDriver(d)}
+}
+
+type wrappedDriver struct {
+	inner driver.Driver
+}
+
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.inner.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{inner: c}, nil
+}
+
+type wrappedConnector struct {
+	inner  driver.Connector
+	driver driver.Driver
+}
+
+func (c *wrappedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	inner, err := c.inner.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{inner: inner}, nil
+}
+
+func (c *wrappedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type conn struct {
+	inner driver.Conn
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	s, err := c.inner.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stmt{inner: s}, nil
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	pc, ok := c.inner.(driver.ConnPrepareContext)
+	if !ok {
+		return c.Prepare(query)
+	}
+
+	s, err := pc.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stmt{inner: s}, nil
+}
+
+func (c *conn) Close() error {
+	return c.inner.Close()
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.inner.Begin()
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var (
+		t   driver.Tx
+		err error
+	)
+
+	switch bt, ok := c.inner.(driver.ConnBeginTx); {
+	case ok:
+		t, err = bt.BeginTx(ctx, opts)
+	case opts.Isolation != 0 || opts.ReadOnly:
+		err = errors.New("sqlx/prometheus: driver does not support transaction options")
+	default:
+		t, err = c.Begin()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return observeTx(ctx, t), nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.inner.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	return e.ExecContext(ctx, query, args)
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.inner.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	r, err := q.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return observeRows(ctx, r), nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.inner.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.inner.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.inner.(driver.Validator); ok {
+		return v.IsValid()
+	}
+
+	return true
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := c.inner.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+
+	return driver.ErrSkip
+}
+
+type stmt struct {
+	inner driver.Stmt
+}
+
+func (s *stmt) Close() error {
+	return s.inner.Close()
+}
+
+func (s *stmt) NumInput() int {
+	return s.inner.NumInput()
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.inner.Exec(args)
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.inner.Query(args)
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := s.inner.(driver.StmtExecContext); ok {
+		return e.ExecContext(ctx, args)
+	}
+
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Exec(values)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	var (
+		r   driver.Rows
+		err error
+	)
+
+	if q, ok := s.inner.(driver.StmtQueryContext); ok {
+		r, err = q.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			r, err = s.Query(values)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return observeRows(ctx, r), nil
+}
+
+func (s *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := s.inner.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+
+	return driver.ErrSkip
+}
+
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("sqlx/prometheus: driver does not support named arguments")
+		}
+
+		values[i] = arg.Value
+	}
+
+	return values, nil
+}
+
+type txObserverContextKey struct{}
+
+// txObserver records the commit or rollback of a transaction begun through
+// an instrumented DB
+type txObserver struct {
+	observe func(operation string, begin time.Time, err error)
+}
+
+func contextWithTxObserver(ctx context.Context, o *txObserver) context.Context {
+	return context.WithValue(ctx, txObserverContextKey{}, o)
+}
+
+// observeTx wraps t when the transaction was begun through an instrumented
+// DB, leaving every other transaction untouched
+func observeTx(ctx context.Context, t driver.Tx) driver.Tx {
+	o, ok := ctx.Value(txObserverContextKey{}).(*txObserver)
+	if !ok {
+		return t
+	}
+
+	return &tx{
+		inner:    t,
+		observer: o,
+	}
+}
+
+type tx struct {
+	inner    driver.Tx
+	observer *txObserver
+}
+
+func (t *tx) Commit() error {
+	begin := time.Now()
+
+	err := t.inner.Commit()
+
+	t.observer.observe("commit", begin, err)
+
+	return err
+}
+
+func (t *tx) Rollback() error {
+	begin := time.Now()
+
+	err := t.inner.Rollback()
+
+	t.observer.observe("rollback", begin, err)
+
+	return err
+}
+
+type rowsObserverContextKey struct{}
+
+// rowsObserver records the number of rows iterated and the time taken to
+// iterate them for a query made through an instrumented DB
+type rowsObserver struct {
+	observe func(rows int, d time.Duration)
+}
+
+func contextWithRowsObserver(ctx context.Context, o *rowsObserver) context.Context {
+	return context.WithValue(ctx, rowsObserverContextKey{}, o)
+}
+
+// observeRows wraps r when the query was made through an instrumented DB,
+// leaving the rows of every other query untouched
+func observeRows(ctx context.Context, r driver.Rows) driver.Rows {
+	o, ok := ctx.Value(rowsObserverContextKey{}).(*rowsObserver)
+	if !ok {
+		return r
+	}
+
+	return &rows{
+		inner:    r,
+		observer: o,
+		begin:    time.Now(),
+	}
+}
+
+type rows struct {
+	inner    driver.Rows
+	observer *rowsObserver
+	begin    time.Time
+	count    int
+	once     sync.Once
+}
+
+func (r *rows) Columns() []string {
+	return r.inner.Columns()
+}
+
+func (r *rows) Close() error {
+	r.finish()
+
+	return r.inner.Close()
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	err := r.inner.Next(dest)
+
+	switch {
+	case err == nil:
+		r.count++
+	case errors.Is(err, io.EOF):
+		r.finish()
+	}
+
+	return err
+}
+
+func (r *rows) finish() {
+	r.once.Do(func() {
+		r.observer.observe(r.count, time.Since(r.begin))
+	})
+}
+
+func (r *rows) HasNextResultSet() bool {
+	if n, ok := r.inner.(driver.RowsNextResultSet); ok {
+		return n.HasNextResultSet()
+	}
+
+	return false
+}
+
+func (r *rows) NextResultSet() error {
+	if n, ok := r.inner.(driver.RowsNextResultSet); ok {
+		return n.NextResultSet()
+	}
+
+	return io.EOF
+}
+
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	if t, ok := r.inner.(driver.RowsColumnTypeScanType); ok {
+		return t.ColumnTypeScanType(index)
+	}
+
+	return reflect.TypeOf(new(any)).Elem()
+}
+
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	if t, ok := r.inner.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return t.ColumnTypeDatabaseTypeName(index)
+	}
+
+	return ""
+}
+
+func (r *rows) ColumnTypeLength(index int) (int64, bool) {
+	if t, ok := r.inner.(driver.RowsColumnTypeLength); ok {
+		return t.ColumnTypeLength(index)
+	}
+
+	return 0, false
+}
+
+func (r *rows) ColumnTypeNullable(index int) (bool, bool) {
+	if t, ok := r.inner.(driver.RowsColumnTypeNullable); ok {
+		return t.ColumnTypeNullable(index)
+	}
+
+	return false, false
+}
+
+func (r *rows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if t, ok := r.inner.(driver.RowsColumnTypePrecisionScale); ok {
+		return t.ColumnTypePrecisionScale(index)
+	}
+
+	return 0, 0, false
+}
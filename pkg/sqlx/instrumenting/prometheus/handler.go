@@ -0,0 +1,17 @@
+package prometheus
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Handler serves the metrics gathered by g, negotiating the OpenMetrics
+// exposition format so the trace ID exemplars attached to observations are
+// exposed to scrapers which request it
+func Handler(g prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(g, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	})
+}
@@ -0,0 +1,51 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/olireadcopper/sqlxprototype/pkg/telemetry"
+)
+
+func TestHandler(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	db := NewDB(DBWithRegisterer(registry))
+
+	ctx := telemetry.ContextWithTrace(context.Background(), "ea531ba71b31f9150d40727fee37a955")
+
+	if _, err := db.ExecContext(ctx, "DELETE FROM t"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		accept   string
+		exemplar bool
+	}{
+		{"text", "text/plain", false},
+		{"OpenMetrics", "application/openmetrics-text", true},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		r.Header.Set("Accept", tt.accept)
+
+		w := httptest.NewRecorder()
+		Handler(registry).ServeHTTP(w, r)
+
+		body := w.Body.String()
+
+		if !strings.Contains(body, "copper_sql_exec_total") {
+			t.Errorf("%s: copper_sql_exec_total missing from\n%s", tt.name, body)
+		}
+
+		if got := strings.Contains(body, `# {trace_id="ea531ba71b31f9150d40727fee37a955"}`); got != tt.exemplar {
+			t.Errorf("%s: exemplar exposed = %v, want %v\n%s", tt.name, got, tt.exemplar, body)
+		}
+	}
+}
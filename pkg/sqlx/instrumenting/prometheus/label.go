@@ -0,0 +1,78 @@
+package prometheus
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultMaxQueryLabels = 100
+	// otherQueryLabel the label of the queries past the most labels
+	otherQueryLabel = "other"
+)
+
+var (
+	queryVerb  = regexp.MustCompile(`^\s*(?:--[^\n]*\n\s*)*([A-Za-z]+)`)
+	queryTable = regexp.MustCompile(`(?i)\b(?:FROM|INTO|UPDATE|JOIN)\s+([A-Za-z_][A-Za-z0-9_.]*)`)
+)
+
+// QueryLabeler maps the text of a query to the value of the query label of
+// its metrics, queries which differ only in their parameters, filters or
+// pages should share a label
+type QueryLabeler func(query string) string
+
+// DefaultQueryLabeler labels a query with its statement and the first table
+// it reads or writes, e.g. "select:taxonomy", keeping the number of series
+// bounded by the schema rather than by the queries built at runtime
+func DefaultQueryLabeler(query string) string {
+	verb := queryVerb.FindStringSubmatch(query)
+	if verb == nil {
+		return otherQueryLabel
+	}
+
+	label := strings.ToLower(verb[1])
+
+	if table := queryTable.FindStringSubmatch(query); table != nil {
+		label += ":" + strings.ToLower(table[1])
+	}
+
+	return label
+}
+
+// queryLabels caps the number of distinct query labels, the labels seen past
+// the cap are replaced with "other"
+type queryLabels struct {
+	labeler QueryLabeler
+	max     int
+
+	mu   sync.RWMutex
+	seen map[string]struct{}
+}
+
+func (l *queryLabels) label(query string) string {
+	label := l.labeler(query)
+
+	l.mu.RLock()
+	_, ok := l.seen[label]
+	l.mu.RUnlock()
+
+	if ok {
+		return label
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.seen[label]; ok {
+		return label
+	}
+
+	if len(l.seen) >= l.max {
+		return otherQueryLabel
+	}
+
+	l.seen[label] = struct{}{}
+
+	return label
+}
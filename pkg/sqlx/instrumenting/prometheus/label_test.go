@@ -0,0 +1,47 @@
+package prometheus
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDefaultQueryLabeler(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT id, name FROM taxonomy WHERE id = $1", "select:taxonomy"},
+		{"SELECT id FROM taxonomy WHERE name = $1 AND parent_id IS NULL ORDER BY id LIMIT $2", "select:taxonomy"},
+		{"\n\t\tINSERT INTO taxonomy_history (taxonomy_id) VALUES ($1)", "insert:taxonomy_history"},
+		{"UPDATE taxonomy SET name = $1 WHERE id = $2", "update:taxonomy"},
+		{"delete from outbox where id = $1", "delete:outbox"},
+		{"-- the subtree\nWITH RECURSIVE subtree (id) AS (SELECT id FROM taxonomy) SELECT id FROM subtree", "with:taxonomy"},
+		{"SELECT EXISTS (SELECT 1 FROM taxonomy WHERE id = $1)", "select:taxonomy"},
+		{"SAVEPOINT batch", "savepoint"},
+		{"", "other"},
+	}
+
+	for _, tt := range tests {
+		if got := DefaultQueryLabeler(tt.query); got != tt.want {
+			t.Errorf("DefaultQueryLabeler(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestQueryLabelsCap(t *testing.T) {
+	l := &queryLabels{
+		labeler: func(query string) string { return query },
+		max:     2,
+		seen:    map[string]struct{}{},
+	}
+
+	for i, want := range []string{"q0", "q1", "other", "other"} {
+		if got := l.label(fmt.Sprintf("q%d", i)); got != want {
+			t.Errorf("label(q%d) = %q, want %q", i, got, want)
+		}
+	}
+
+	if got := l.label("q0"); got != "q0" {
+		t.Errorf("label(q0) after the cap = %q, want q0", got)
+	}
+}
@@ -0,0 +1,383 @@
+package prometheus
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+	"unicode/utf8"
+
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/sqlerr"
+	"github.com/olireadcopper/sqlxprototype/pkg/telemetry"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const defaultNamespace = "copper"
+
+// rowBuckets the buckets of the row count histograms, from a single row up to
+// runaway result sets of tens of thousands of rows
+var rowBuckets = prometheus.ExponentialBuckets(1, 4, 9)
+
+// nativeHistograms the layout of native histograms, see
+// prometheus.HistogramOpts for details of each field
+type nativeHistograms struct {
+	bucketFactor     float64
+	maxBucketNumber  uint32
+	minResetDuration time.Duration
+}
+
+type metrics struct {
+	beginCount        *prometheus.CounterVec
+	beginErrors       *prometheus.CounterVec
+	beginDuration     *prometheus.HistogramVec
+	execCount         *prometheus.CounterVec
+	execErrors        *prometheus.CounterVec
+	execDuration      *prometheus.HistogramVec
+	execRows          *prometheus.HistogramVec
+	queryRows         *prometheus.HistogramVec
+	rowsDuration      *prometheus.HistogramVec
+	operationCount    *prometheus.CounterVec
+	operationErrors   *prometheus.CounterVec
+	operationDuration *prometheus.HistogramVec
+}
+
+// newMetrics creates and registers the metrics of db, collectors already
+// registered by another DB with the same options are shared rather than
+// causing a registration panic
+func newMetrics(db *DB) *metrics {
+	return &metrics{
+		beginCount: Register(db.registerer, prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   db.namespace,
+				Subsystem:   db.subsystem,
+				Name:        "sql_begin_total",
+				Help:        "Number of calls to begin an SQL transaction",
+				ConstLabels: db.constLabels,
+			},
+			[]string{"outcome"},
+		)),
+		beginErrors: Register(db.registerer, prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   db.namespace,
+				Subsystem:   db.subsystem,
+				Name:        "sql_begin_errors_total",
+				Help:        "Number of errors from trying to begin an SQL transaction",
+				ConstLabels: db.constLabels,
+			},
+			[]string{"code"},
+		)),
+		beginDuration: Register(db.registerer, prometheus.NewHistogramVec(
+			db.histogramOpts(
+				"sql_begin_duration_seconds",
+				"Duration of calls to begin an SQL transaction, measured in seconds",
+			),
+			[]string{"outcome"},
+		)),
+		execCount: Register(db.registerer, prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   db.namespace,
+				Subsystem:   db.subsystem,
+				Name:        "sql_exec_total",
+				Help:        "Number of calls to execute an SQL query",
+				ConstLabels: db.constLabels,
+			},
+			[]string{"query", "outcome"},
+		)),
+		execErrors: Register(db.registerer, prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   db.namespace,
+				Subsystem:   db.subsystem,
+				Name:        "sql_exec_errors_total",
+				Help:        "Number of errors from trying to execute an SQL query",
+				ConstLabels: db.constLabels,
+			},
+			[]string{"query", "code"},
+		)),
+		execDuration: Register(db.registerer, prometheus.NewHistogramVec(
+			db.histogramOpts(
+				"sql_exec_duration_seconds",
+				"Duration of execution of an SQL query, measured in seconds",
+			),
+			[]string{"query", "outcome"},
+		)),
+		execRows: Register(db.registerer, prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   db.namespace,
+				Subsystem:   db.subsystem,
+				Name:        "sql_exec_rows_affected",
+				Help:        "Number of rows affected by the execution of an SQL statement",
+				ConstLabels: db.constLabels,
+				Buckets:     rowBuckets,
+			},
+			[]string{"query"},
+		)),
+		queryRows: Register(db.registerer, prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   db.namespace,
+				Subsystem:   db.subsystem,
+				Name:        "sql_query_rows",
+				Help:        "Number of rows returned by an SQL query",
+				ConstLabels: db.constLabels,
+				Buckets:     rowBuckets,
+			},
+			[]string{"query"},
+		)),
+		rowsDuration: Register(db.registerer, prometheus.NewHistogramVec(
+			db.histogramOpts(
+				"sql_query_rows_duration_seconds",
+				"Duration of iterating over the rows returned by an SQL query, measured in seconds",
+			),
+			[]string{"query"},
+		)),
+		operationCount: Register(db.registerer, prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   db.namespace,
+				Subsystem:   db.subsystem,
+				Name:        "sql_operation_total",
+				Help:        "Number of pings, prepared statements, commits and rollbacks",
+				ConstLabels: db.constLabels,
+			},
+			[]string{"operation", "outcome"},
+		)),
+		operationErrors: Register(db.registerer, prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   db.namespace,
+				Subsystem:   db.subsystem,
+				Name:        "sql_operation_errors_total",
+				Help:        "Number of errors from pings, prepared statements, commits and rollbacks",
+				ConstLabels: db.constLabels,
+			},
+			[]string{"operation", "code"},
+		)),
+		operationDuration: Register(db.registerer, prometheus.NewHistogramVec(
+			db.histogramOpts(
+				"sql_operation_duration_seconds",
+				"Duration of pings, prepared statements, commits and rollbacks, measured in seconds",
+			),
+			[]string{"operation", "outcome"},
+		)),
+	}
+}
+
+func (db *DB) histogramOpts(name, help string) prometheus.HistogramOpts {
+	opts := prometheus.HistogramOpts{
+		Namespace:   db.namespace,
+		Subsystem:   db.subsystem,
+		Name:        name,
+		Help:        help,
+		ConstLabels: db.constLabels,
+		Buckets:     db.buckets,
+	}
+
+	if db.native != nil {
+		opts.NativeHistogramBucketFactor = db.native.bucketFactor
+		opts.NativeHistogramMaxBucketNumber = db.native.maxBucketNumber
+		opts.NativeHistogramMinResetDuration = db.native.minResetDuration
+	}
+
+	return opts
+}
+
+// Register registers c with r, returning the existing collector instead when
+// an identical one has already been registered, so decorators built with the
+// same options share their metrics
+func Register[T prometheus.Collector](r prometheus.Registerer, c T) T {
+	if err := r.Register(c); err != nil {
+		are := prometheus.AlreadyRegisteredError{}
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing
+			}
+		}
+
+		panic(err)
+	}
+
+	return c
+}
+
+func (db *DB) observeBegin(ctx context.Context, begin time.Time, err error) {
+	outcome := db.classifier(err)
+	labels := prometheus.Labels{
+		"outcome": string(outcome),
+	}
+
+	Inc(ctx, db.metrics.beginCount.With(labels))
+	Observe(ctx, db.metrics.beginDuration.With(labels), time.Since(begin).Seconds())
+
+	if outcome.IsError() {
+		Inc(ctx, db.metrics.beginErrors.With(prometheus.Labels{
+			"code": sqlerr.Code(err),
+		}))
+	}
+}
+
+// observeMustBegin records a MustBegin or MustBeginTx, it must be deferred so
+// the panic of a failed begin is recovered, recorded with its error and
+// raised again
+func (db *DB) observeMustBegin(ctx context.Context, begin time.Time) {
+	r := recover()
+	if r == nil {
+		db.observeBegin(ctx, begin, nil)
+		return
+	}
+
+	err, ok := r.(error)
+	if !ok {
+		err = fmt.Errorf("%v", r)
+	}
+
+	db.observeBegin(ctx, begin, err)
+
+	panic(r)
+}
+
+func (db *DB) observeExec(ctx context.Context, query string, begin time.Time, err error) {
+	outcome := db.classifier(err)
+	labels := prometheus.Labels{
+		"query":   db.labels.label(query),
+		"outcome": string(outcome),
+	}
+
+	Inc(ctx, db.metrics.execCount.With(labels))
+	Observe(ctx, db.metrics.execDuration.With(labels), time.Since(begin).Seconds())
+
+	if outcome.IsError() {
+		Inc(ctx, db.metrics.execErrors.With(prometheus.Labels{
+			"query": db.labels.label(query),
+			"code":  sqlerr.Code(err),
+		}))
+	}
+}
+
+// observeQuery records the call of a query reading rows under the metrics of
+// the statements, the rows themselves are recorded once read, see
+// observeQueryRows and withRowsObserver
+func (db *DB) observeQuery(ctx context.Context, query string, begin time.Time, err error) {
+	db.observeExec(ctx, query, begin, err)
+}
+
+// observeOperation records the calls which are neither queries nor the
+// beginning of a transaction, such as pings, prepares, commits and rollbacks
+func (db *DB) observeOperation(ctx context.Context, operation string, begin time.Time, err error) {
+	outcome := db.classifier(err)
+	labels := prometheus.Labels{
+		"operation": operation,
+		"outcome":   string(outcome),
+	}
+
+	Inc(ctx, db.metrics.operationCount.With(labels))
+	Observe(ctx, db.metrics.operationDuration.With(labels), time.Since(begin).Seconds())
+
+	if outcome.IsError() {
+		Inc(ctx, db.metrics.operationErrors.With(prometheus.Labels{
+			"operation": operation,
+			"code":      sqlerr.Code(err),
+		}))
+	}
+}
+
+// withTxObserver marks ctx so that the commit or rollback of the transaction
+// begun with it is recorded by a driver wrapped with WrapDriver
+func (db *DB) withTxObserver(ctx context.Context) context.Context {
+	return contextWithTxObserver(ctx, &txObserver{
+		observe: func(operation string, begin time.Time, err error) {
+			db.observeOperation(ctx, operation, begin, err)
+		},
+	})
+}
+
+// ExemplarTraceLabel the label of exemplars holding the trace ID
+const ExemplarTraceLabel = "trace_id"
+
+// Exemplar the labels of the exemplar attached to observations made with
+// ctx, nil when ctx does not carry a trace ID or carries one prometheus
+// would panic on, invalid UTF-8 or over the exemplar rune limit
+func Exemplar(ctx context.Context) prometheus.Labels {
+	trace, ok := telemetry.TraceFromContext(ctx)
+	if !ok || !utf8.ValidString(trace) ||
+		utf8.RuneCountInString(trace) > prometheus.ExemplarMaxRunes-len(ExemplarTraceLabel) {
+		return nil
+	}
+
+	return prometheus.Labels{
+		ExemplarTraceLabel: trace,
+	}
+}
+
+// Observe observes v with o, along with the exemplar of ctx when o supports
+// exemplars
+func Observe(ctx context.Context, o prometheus.Observer, v float64) {
+	if labels := Exemplar(ctx); labels != nil {
+		if eo, ok := o.(prometheus.ExemplarObserver); ok {
+			eo.ObserveWithExemplar(v, labels)
+			return
+		}
+	}
+
+	o.Observe(v)
+}
+
+// Inc increments c, along with the exemplar of ctx when c supports exemplars
+func Inc(ctx context.Context, c prometheus.Counter) {
+	if labels := Exemplar(ctx); labels != nil {
+		if ea, ok := c.(prometheus.ExemplarAdder); ok {
+			ea.AddWithExemplar(1, labels)
+			return
+		}
+	}
+
+	c.Inc()
+}
+
+func (db *DB) observeRowsAffected(ctx context.Context, query string, res sql.Result) {
+	if res == nil {
+		return
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+
+	Observe(ctx, db.metrics.execRows.With(prometheus.Labels{
+		"query": db.labels.label(query),
+	}), float64(n))
+}
+
+func (db *DB) observeQueryRows(ctx context.Context, query string, n int) {
+	Observe(ctx, db.metrics.queryRows.With(prometheus.Labels{
+		"query": db.labels.label(query),
+	}), float64(n))
+}
+
+// withRowsObserver marks ctx so that the rows of the query are counted and
+// timed by a driver wrapped with WrapDriver once they are closed or fully
+// iterated
+func (db *DB) withRowsObserver(ctx context.Context, query string) context.Context {
+	return contextWithRowsObserver(ctx, &rowsObserver{
+		observe: func(rows int, d time.Duration) {
+			db.observeQueryRows(ctx, query, rows)
+			Observe(ctx, db.metrics.rowsDuration.With(prometheus.Labels{
+				"query": db.labels.label(query),
+			}), d.Seconds())
+		},
+	})
+}
+
+// rowCount the number of rows scanned into dest by Select
+func rowCount(dest any) int {
+	v := reflect.ValueOf(dest)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Slice {
+		return 0
+	}
+
+	return v.Len()
+}
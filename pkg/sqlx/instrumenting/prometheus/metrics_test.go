@@ -0,0 +1,194 @@
+package prometheus
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/olireadcopper/sqlxprototype/pkg/telemetry"
+)
+
+func TestExemplar(t *testing.T) {
+	tests := []struct {
+		name  string
+		trace string
+		want  bool
+	}{
+		{"none", "", false},
+		{"trace", "ea531ba71b31f9150d40727fee37a955", true},
+		{"longest", strings.Repeat("a", prometheus.ExemplarMaxRunes-len(ExemplarTraceLabel)), true},
+		{"too long", strings.Repeat("a", 125), false},
+		{"invalid UTF-8", "trace\xff", false},
+	}
+
+	for _, tt := range tests {
+		ctx := telemetry.ContextWithTrace(context.Background(), tt.trace)
+
+		if got := Exemplar(ctx) != nil; got != tt.want {
+			t.Errorf("%s: Exemplar() = %v, want %v", tt.name, got, tt.want)
+		}
+
+		// observations with the trace must not panic whether or not it is used
+		histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "h"})
+		counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "c"})
+
+		Observe(ctx, histogram, 1)
+		Inc(ctx, counter)
+	}
+}
+
+// family returns the gathered metric family of the name
+func family(t *testing.T, g prometheus.Gatherer, name string) *dto.MetricFamily {
+	t.Helper()
+
+	families, err := g.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range families {
+		if f.GetName() == name {
+			return f
+		}
+	}
+
+	t.Fatalf("%s was not gathered", name)
+
+	return nil
+}
+
+func TestSharedMetrics(t *testing.T) {
+	ctx := context.Background()
+	registry := prometheus.NewRegistry()
+
+	tests := []struct {
+		name   string
+		labels prometheus.Labels
+		shared bool
+	}{
+		{"same options", prometheus.Labels{"db": "main"}, true},
+		{"other const labels", prometheus.Labels{"db": "replica"}, false},
+	}
+
+	first := NewDB(
+		DBWithRegisterer(registry),
+		DBWithConstLabels(prometheus.Labels{"db": "main"}),
+	).(*DB)
+
+	if _, err := first.ExecContext(ctx, "DELETE FROM t"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		// a second DB on the registry must not panic on registration
+		db := NewDB(
+			DBWithRegisterer(registry),
+			DBWithConstLabels(tt.labels),
+		).(*DB)
+
+		if got := db.metrics.execCount == first.metrics.execCount; got != tt.shared {
+			t.Errorf("%s: shared = %v, want %v", tt.name, got, tt.shared)
+		}
+
+		if _, err := db.ExecContext(ctx, "DELETE FROM t"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// both DBs of the main database count into the same series
+	for _, m := range family(t, registry, "copper_sql_exec_total").GetMetric() {
+		want := 1.0
+		for _, l := range m.GetLabel() {
+			if l.GetName() == "db" && l.GetValue() == "main" {
+				want = 2
+			}
+		}
+
+		if got := m.GetCounter().GetValue(); got != want {
+			t.Errorf("copper_sql_exec_total%v = %v, want %v", m.GetLabel(), got, want)
+		}
+	}
+}
+
+func TestMetricOptions(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		opts    []DBOption
+		metric  string
+		labels  map[string]string
+		buckets []float64
+		native  bool
+	}{
+		{
+			name:    "defaults",
+			metric:  "copper_sql_exec_duration_seconds",
+			labels:  map[string]string{},
+			buckets: prometheus.DefBuckets,
+		},
+		{
+			name: "custom",
+			opts: []DBOption{
+				DBWithNamespace("app"),
+				DBWithSubsystem("orders"),
+				DBWithConstLabels(prometheus.Labels{"db": "main", "role": "primary"}),
+				DBWithBuckets(0.01, 0.1, 1),
+			},
+			metric:  "app_orders_sql_exec_duration_seconds",
+			labels:  map[string]string{"db": "main", "role": "primary"},
+			buckets: []float64{0.01, 0.1, 1},
+		},
+		{
+			name:    "native histograms",
+			opts:    []DBOption{DBWithNativeHistograms(1.1, 100)},
+			metric:  "copper_sql_exec_duration_seconds",
+			labels:  map[string]string{},
+			buckets: prometheus.DefBuckets,
+			native:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		registry := prometheus.NewRegistry()
+
+		db := NewDB(append(tt.opts, DBWithRegisterer(registry))...)
+
+		if _, err := db.ExecContext(ctx, "DELETE FROM t"); err != nil {
+			t.Fatal(err)
+		}
+
+		h := family(t, registry, tt.metric).GetMetric()[0]
+
+		for name, want := range tt.labels {
+			got := ""
+			for _, l := range h.GetLabel() {
+				if l.GetName() == name {
+					got = l.GetValue()
+				}
+			}
+
+			if got != want {
+				t.Errorf("%s: label %s = %q, want %q", tt.name, name, got, want)
+			}
+		}
+
+		buckets := []float64{}
+		for _, b := range h.GetHistogram().GetBucket() {
+			buckets = append(buckets, b.GetUpperBound())
+		}
+
+		if !slices.Equal(buckets, tt.buckets) {
+			t.Errorf("%s: buckets = %v, want %v", tt.name, buckets, tt.buckets)
+		}
+
+		// native histograms carry a schema, classic ones do not
+		if got := h.GetHistogram().Schema != nil; got != tt.native {
+			t.Errorf("%s: native = %v, want %v", tt.name, got, tt.native)
+		}
+	}
+}
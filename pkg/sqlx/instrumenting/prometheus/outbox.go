@@ -0,0 +1,138 @@
+package prometheus
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/outbox"
+)
+
+const defaultOutboxTimeout = 5 * time.Second
+
+// OutboxCollector prometheus.Collector exporting the backlog of an outbox and
+// the messages handled by its relay, the backlog is read from the outbox on
+// every scrape
+type OutboxCollector struct {
+	relay     *outbox.Relay
+	name      string
+	namespace string
+	subsystem string
+	timeout   time.Duration
+
+	pending   *prometheus.Desc
+	failed    *prometheus.Desc
+	lag       *prometheus.Desc
+	delivered *prometheus.Desc
+	retried   *prometheus.Desc
+	abandoned *prometheus.Desc
+}
+
+type OutboxCollectorOption func(*OutboxCollector)
+
+func NewOutboxCollector(opts ...OutboxCollectorOption) *OutboxCollector {
+	c := &OutboxCollector{
+		relay:     outbox.NewRelay(),
+		namespace: defaultNamespace,
+		timeout:   defaultOutboxTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(c.namespace, c.subsystem, name),
+			help,
+			nil,
+			prometheus.Labels{"outbox": c.name},
+		)
+	}
+
+	c.pending = desc("outbox_pending_messages", "Number of messages waiting for delivery")
+	c.failed = desc("outbox_failed_messages", "Number of messages which failed for good and will not be delivered")
+	c.lag = desc("outbox_lag_seconds", "Age of the oldest message waiting for delivery, 0 when none is")
+	c.delivered = desc("outbox_delivered_total", "Total number of messages delivered by the relay")
+	c.retried = desc("outbox_retried_total", "Total number of failed deliveries scheduled for another attempt")
+	c.abandoned = desc("outbox_failed_total", "Total number of messages the relay gave up on")
+
+	return c
+}
+
+// Describe implementation of prometheus.Collector
+func (c *OutboxCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.pending
+	ch <- c.failed
+	ch <- c.lag
+	ch <- c.delivered
+	ch <- c.retried
+	ch <- c.abandoned
+}
+
+// Collect implementation of prometheus.Collector, the backlog is reported as
+// invalid when it can not be read
+func (c *OutboxCollector) Collect(ch chan<- prometheus.Metric) {
+	relayed := c.relay.Stats()
+
+	ch <- prometheus.MustNewConstMetric(c.delivered, prometheus.CounterValue, float64(relayed.Delivered))
+	ch <- prometheus.MustNewConstMetric(c.retried, prometheus.CounterValue, float64(relayed.Retried))
+	ch <- prometheus.MustNewConstMetric(c.abandoned, prometheus.CounterValue, float64(relayed.Failed))
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	stats, err := c.relay.Outbox().Stats(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.pending, err)
+		return
+	}
+
+	lag := 0.0
+	if !stats.OldestPending.IsZero() {
+		lag = max(time.Since(stats.OldestPending).Seconds(), 0)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.pending, prometheus.GaugeValue, float64(stats.Pending))
+	ch <- prometheus.MustNewConstMetric(c.failed, prometheus.GaugeValue, float64(stats.Failed))
+	ch <- prometheus.MustNewConstMetric(c.lag, prometheus.GaugeValue, lag)
+}
+
+// OutboxCollectorWithRelay sets the relay the statistics are read from, along
+// with the backlog of its outbox
+func OutboxCollectorWithRelay(r *outbox.Relay) OutboxCollectorOption {
+	return func(c *OutboxCollector) {
+		c.relay = r
+	}
+}
+
+// OutboxCollectorWithName sets the value of the outbox label of the metrics
+func OutboxCollectorWithName(name string) OutboxCollectorOption {
+	return func(c *OutboxCollector) {
+		c.name = name
+	}
+}
+
+// OutboxCollectorWithNamespace sets the namespace of the metrics, defaults to
+// "copper"
+func OutboxCollectorWithNamespace(namespace string) OutboxCollectorOption {
+	return func(c *OutboxCollector) {
+		c.namespace = namespace
+	}
+}
+
+// OutboxCollectorWithSubsystem sets the subsystem of the metrics
+func OutboxCollectorWithSubsystem(subsystem string) OutboxCollectorOption {
+	return func(c *OutboxCollector) {
+		c.subsystem = subsystem
+	}
+}
+
+// OutboxCollectorWithTimeout sets the timeout of reading the backlog on a
+// scrape, defaults to 5 seconds
+func OutboxCollectorWithTimeout(d time.Duration) OutboxCollectorOption {
+	return func(c *OutboxCollector) {
+		c.timeout = d
+	}
+}
@@ -0,0 +1,159 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/migrate"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/outbox"
+)
+
+// enqueue enqueues a message with the payload in a transaction of its own
+func enqueue(t *testing.T, db *sqlx.DB, o *outbox.Outbox, key string, payload int) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	m, err := outbox.NewMessage("test", key, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	if err := o.Enqueue(ctx, tx, m); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// gather returns the values of the metrics of the registry by name
+func gather(t *testing.T, g prometheus.Gatherer) map[string]float64 {
+	t.Helper()
+
+	families, err := g.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]float64{}
+
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			switch {
+			case m.GetCounter() != nil:
+				values[f.GetName()] = m.GetCounter().GetValue()
+			case m.GetGauge() != nil:
+				values[f.GetName()] = m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	return values
+}
+
+func TestOutboxCollector(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sqlx.Open("sqlite3", "file:"+t.TempDir()+"/outbox.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := migrate.NewMigrator(
+		migrate.MigratorWithDB(db),
+		migrate.MigratorWithFS(outbox.SQLiteMigrations()),
+		migrate.MigratorWithTable(outbox.MigrationsTable),
+	).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	o := outbox.NewOutbox(outbox.OutboxWithDB(db))
+
+	// the message 1 fails both of its attempts, the others are delivered
+	relay := outbox.NewRelay(
+		outbox.RelayWithOutbox(o),
+		outbox.RelayWithPublisher(outbox.PublisherFunc(func(_ context.Context, m outbox.Message) error {
+			if string(m.Payload) == "1" {
+				return errors.New("broker unavailable")
+			}
+
+			return nil
+		})),
+		outbox.RelayWithPollInterval(time.Millisecond),
+		outbox.RelayWithMaxAttempts(2),
+		outbox.RelayWithBackoff(time.Millisecond, time.Millisecond),
+	)
+
+	enqueue(t, db, o, "a", 1)
+	enqueue(t, db, o, "b", 2)
+	enqueue(t, db, o, "", 3)
+
+	relayed, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+
+	go func() { done <- relay.Run(relayed) }()
+
+	for deadline := time.Now().Add(5 * time.Second); relay.Stats() != (outbox.RelayStats{Delivered: 2, Retried: 1, Failed: 1}); {
+		if time.Now().After(deadline) {
+			t.Fatalf("relay stats = %+v, want 2 delivered, 1 retried and 1 failed", relay.Stats())
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// a message enqueued once the relay stopped is pending
+	enqueue(t, db, o, "c", 4)
+	time.Sleep(10 * time.Millisecond)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewOutboxCollector(
+		OutboxCollectorWithRelay(relay),
+		OutboxCollectorWithName("test"),
+	))
+
+	values := gather(t, registry)
+
+	for name, want := range map[string]float64{
+		"copper_outbox_pending_messages": 1,
+		"copper_outbox_failed_messages":  1,
+		"copper_outbox_delivered_total":  2,
+		"copper_outbox_retried_total":    1,
+		"copper_outbox_failed_total":     1,
+	} {
+		if got, ok := values[name]; !ok || got != want {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+
+	if lag := values["copper_outbox_lag_seconds"]; lag < 0.01 || lag > 5 {
+		t.Errorf("copper_outbox_lag_seconds = %v, want the age of the pending message", lag)
+	}
+
+	// a backlog which can not be read fails the scrape
+	db.Close()
+
+	if _, err := registry.Gather(); err == nil {
+		t.Error("Gather() of a closed outbox error = nil, want an error")
+	}
+}
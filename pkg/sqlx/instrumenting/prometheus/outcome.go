@@ -0,0 +1,65 @@
+package prometheus
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/sqlerr"
+)
+
+// Outcome the result of a call recorded in the outcome label of the metrics
+type Outcome string
+
+const (
+	OutcomeOK               Outcome = "ok"
+	OutcomeNoRows           Outcome = "no_rows"
+	OutcomeCanceled         Outcome = "canceled"
+	OutcomeDeadlineExceeded Outcome = "deadline_exceeded"
+	OutcomeBusy             Outcome = "busy"
+	OutcomeConstraint       Outcome = "constraint"
+	OutcomeError            Outcome = "error"
+)
+
+// IsError reports whether the outcome counts towards the error metrics, calls
+// finding no rows or canceled by the client are not failures of the database
+func (o Outcome) IsError() bool {
+	switch o {
+	case OutcomeOK, OutcomeNoRows, OutcomeCanceled:
+		return false
+	}
+
+	return true
+}
+
+// Classifier maps the error returned by a call to its outcome
+type Classifier func(err error) Outcome
+
+// DefaultClassifier classifies errors using the sqlerr categories
+func DefaultClassifier(err error) Outcome {
+	switch {
+	case err == nil:
+		return OutcomeOK
+	case errors.Is(err, sql.ErrNoRows):
+		return OutcomeNoRows
+	case errors.Is(err, context.Canceled):
+		return OutcomeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return OutcomeDeadlineExceeded
+	}
+
+	switch sqlerr.Classify(err) {
+	case sqlerr.CategoryNotFound:
+		return OutcomeNoRows
+	case sqlerr.CategoryCanceled:
+		return OutcomeCanceled
+	case sqlerr.CategoryTimeout:
+		return OutcomeDeadlineExceeded
+	case sqlerr.CategoryBusy:
+		return OutcomeBusy
+	case sqlerr.CategoryUniqueViolation, sqlerr.CategoryForeignKeyViolation, sqlerr.CategoryConstraintViolation:
+		return OutcomeConstraint
+	}
+
+	return OutcomeError
+}
@@ -0,0 +1,171 @@
+package prometheus
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+)
+
+func TestDefaultClassifier(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		want  Outcome
+		error bool
+	}{
+		{"nil", nil, OutcomeOK, false},
+		{"no rows", fmt.Errorf("get: %w", sql.ErrNoRows), OutcomeNoRows, false},
+		{"canceled", fmt.Errorf("query: %w", context.Canceled), OutcomeCanceled, false},
+		{"interrupted", sqlite3.Error{Code: sqlite3.ErrInterrupt}, OutcomeCanceled, false},
+		{"deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), OutcomeDeadlineExceeded, true},
+		{"busy", sqlite3.Error{Code: sqlite3.ErrBusy}, OutcomeBusy, true},
+		{"unique", sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique}, OutcomeConstraint, true},
+		{"foreign key", sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintForeignKey}, OutcomeConstraint, true},
+		{"not null", sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintNotNull}, OutcomeConstraint, true},
+		{"unknown", errors.New("boom"), OutcomeError, true},
+	}
+
+	for _, tt := range tests {
+		got := DefaultClassifier(tt.err)
+		if got != tt.want {
+			t.Errorf("%s: DefaultClassifier() = %q, want %q", tt.name, got, tt.want)
+		}
+
+		if got.IsError() != tt.error {
+			t.Errorf("%s: IsError() = %v, want %v", tt.name, got.IsError(), tt.error)
+		}
+	}
+}
+
+func TestOperations(t *testing.T) {
+	pool := sqlx.NewDb(sql.OpenDB(NewConnector(&sqlite3.SQLiteDriver{}, ":memory:")), "sqlite3")
+	defer pool.Close()
+
+	// a single connection, every connection to :memory: is its own database
+	pool.SetMaxOpenConns(1)
+
+	db := NewDB(
+		DBWithInnerDB(pool),
+		DBWithRegisterer(prometheus.NewRegistry()),
+	).(*DB)
+
+	ctx := context.Background()
+
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, err := db.PreparexContext(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt.Close()
+
+	if _, err := db.PreparexContext(ctx, "SELEC 1"); err == nil {
+		t.Fatal("PreparexContext() of invalid SQL error = nil, want an error")
+	}
+
+	// the decorator records its own transactions, the deferred rollback of a
+	// committed one is not recorded
+	tx, err := kryptonsqlx.BeginTransaction(ctx, db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	tx.Rollback()
+
+	tx, err = kryptonsqlx.BeginTransaction(ctx, db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the driver records the transactions begun outside of the decorator
+	sqlTx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `
+# HELP copper_sql_operation_total Number of pings, prepared statements, commits and rollbacks
+# TYPE copper_sql_operation_total counter
+copper_sql_operation_total{operation="commit",outcome="ok"} 2
+copper_sql_operation_total{operation="ping",outcome="ok"} 1
+copper_sql_operation_total{operation="prepare",outcome="error"} 1
+copper_sql_operation_total{operation="prepare",outcome="ok"} 1
+copper_sql_operation_total{operation="rollback",outcome="ok"} 1
+# HELP copper_sql_operation_errors_total Number of errors from pings, prepared statements, commits and rollbacks
+# TYPE copper_sql_operation_errors_total counter
+copper_sql_operation_errors_total{code="unknown",operation="prepare"} 1
+`
+
+	if err := testutil.CollectAndCompare(
+		db.metrics.operationCount,
+		strings.NewReader(want),
+		"copper_sql_operation_total",
+	); err != nil {
+		t.Error(err)
+	}
+
+	if err := testutil.CollectAndCompare(
+		db.metrics.operationErrors,
+		strings.NewReader(want),
+		"copper_sql_operation_errors_total",
+	); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMustBegin(t *testing.T) {
+	pool, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// beginning on a closed pool panics
+	pool.Close()
+
+	db := NewDB(
+		DBWithInnerDB(pool),
+		DBWithRegisterer(prometheus.NewRegistry()),
+	).(*DB)
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("MustBegin() on a closed pool did not panic")
+			}
+		}()
+
+		db.MustBegin()
+	}()
+
+	if got := testutil.ToFloat64(db.metrics.beginCount.WithLabelValues(string(OutcomeError))); got != 1 {
+		t.Errorf("failed begins = %v, want 1", got)
+	}
+
+	if got := testutil.ToFloat64(db.metrics.beginCount.WithLabelValues(string(OutcomeOK))); got != 0 {
+		t.Errorf("successful begins = %v, want 0", got)
+	}
+}
@@ -0,0 +1,134 @@
+package prometheus
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/nop"
+)
+
+// PoolCollector prometheus.Collector exporting the connection pool statistics
+// of a DB, the statistics are read from DB.Stats on every scrape
+type PoolCollector struct {
+	db        kryptonsqlx.DB
+	name      string
+	namespace string
+	subsystem string
+
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxIdleTimeClosed *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+	saturation        *prometheus.Desc
+}
+
+type PoolCollectorOption func(*PoolCollector)
+
+func NewPoolCollector(opts ...PoolCollectorOption) *PoolCollector {
+	c := &PoolCollector{
+		db:        nop.NewDB(),
+		namespace: defaultNamespace,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(c.namespace, c.subsystem, name),
+			help,
+			nil,
+			prometheus.Labels{"db": c.name},
+		)
+	}
+
+	c.maxOpen = desc("sql_pool_max_open_connections", "Maximum number of open connections to the database")
+	c.open = desc("sql_pool_open_connections", "Number of established connections, both in use and idle")
+	c.inUse = desc("sql_pool_in_use_connections", "Number of connections currently in use")
+	c.idle = desc("sql_pool_idle_connections", "Number of idle connections")
+	c.waitCount = desc("sql_pool_wait_count_total", "Total number of connections waited for")
+	c.waitDuration = desc("sql_pool_wait_duration_seconds_total", "Total time blocked waiting for a new connection, measured in seconds")
+	c.maxIdleClosed = desc("sql_pool_max_idle_closed_total", "Total number of connections closed due to the maximum idle connections")
+	c.maxIdleTimeClosed = desc("sql_pool_max_idle_time_closed_total", "Total number of connections closed due to the maximum idle time")
+	c.maxLifetimeClosed = desc("sql_pool_max_lifetime_closed_total", "Total number of connections closed due to the maximum connection lifetime")
+	c.saturation = desc("sql_pool_saturation_ratio", "Ratio of connections in use to the maximum open connections, 0 when unlimited")
+
+	return c
+}
+
+// Describe implementation of prometheus.Collector
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxIdleTimeClosed
+	ch <- c.maxLifetimeClosed
+	ch <- c.saturation
+}
+
+// Collect implementation of prometheus.Collector
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.Stats()
+
+	ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.maxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxIdleTimeClosed, prometheus.CounterValue, float64(stats.MaxIdleTimeClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed))
+	ch <- prometheus.MustNewConstMetric(c.saturation, prometheus.GaugeValue, Saturation(stats))
+}
+
+// Saturation the ratio of connections in use to the maximum number of open
+// connections, a pool without a maximum is never saturated and returns 0
+func Saturation(stats sql.DBStats) float64 {
+	if stats.MaxOpenConnections <= 0 {
+		return 0
+	}
+
+	return float64(stats.InUse) / float64(stats.MaxOpenConnections)
+}
+
+// PoolCollectorWithDB sets the DB the statistics are read from
+func PoolCollectorWithDB(db kryptonsqlx.DB) PoolCollectorOption {
+	return func(c *PoolCollector) {
+		c.db = db
+	}
+}
+
+// PoolCollectorWithName sets the value of the db label of the metrics
+func PoolCollectorWithName(name string) PoolCollectorOption {
+	return func(c *PoolCollector) {
+		c.name = name
+	}
+}
+
+// PoolCollectorWithNamespace sets the namespace of the metrics, defaults to
+// "copper"
+func PoolCollectorWithNamespace(namespace string) PoolCollectorOption {
+	return func(c *PoolCollector) {
+		c.namespace = namespace
+	}
+}
+
+// PoolCollectorWithSubsystem sets the subsystem of the metrics
+func PoolCollectorWithSubsystem(subsystem string) PoolCollectorOption {
+	return func(c *PoolCollector) {
+		c.subsystem = subsystem
+	}
+}
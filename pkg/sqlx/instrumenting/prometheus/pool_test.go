@@ -0,0 +1,98 @@
+package prometheus
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/nop"
+)
+
+// statsDB a database reporting fixed pool statistics
+type statsDB struct {
+	*nop.Nop
+	stats sql.DBStats
+}
+
+func (db *statsDB) Stats() sql.DBStats {
+	return db.stats
+}
+
+func TestPoolCollector(t *testing.T) {
+	c := NewPoolCollector(
+		PoolCollectorWithDB(&statsDB{
+			Nop: nop.NewDB(),
+			stats: sql.DBStats{
+				MaxOpenConnections: 10,
+				OpenConnections:    6,
+				InUse:              4,
+				Idle:               2,
+				WaitCount:          3,
+				WaitDuration:       1500 * time.Millisecond,
+				MaxIdleClosed:      5,
+				MaxIdleTimeClosed:  7,
+				MaxLifetimeClosed:  8,
+			},
+		}),
+		PoolCollectorWithName("main"),
+	)
+
+	want := `
+# HELP copper_sql_pool_idle_connections Number of idle connections
+# TYPE copper_sql_pool_idle_connections gauge
+copper_sql_pool_idle_connections{db="main"} 2
+# HELP copper_sql_pool_in_use_connections Number of connections currently in use
+# TYPE copper_sql_pool_in_use_connections gauge
+copper_sql_pool_in_use_connections{db="main"} 4
+# HELP copper_sql_pool_max_idle_closed_total Total number of connections closed due to the maximum idle connections
+# TYPE copper_sql_pool_max_idle_closed_total counter
+copper_sql_pool_max_idle_closed_total{db="main"} 5
+# HELP copper_sql_pool_max_idle_time_closed_total Total number of connections closed due to the maximum idle time
+# TYPE copper_sql_pool_max_idle_time_closed_total counter
+copper_sql_pool_max_idle_time_closed_total{db="main"} 7
+# HELP copper_sql_pool_max_lifetime_closed_total Total number of connections closed due to the maximum connection lifetime
+# TYPE copper_sql_pool_max_lifetime_closed_total counter
+copper_sql_pool_max_lifetime_closed_total{db="main"} 8
+# HELP copper_sql_pool_max_open_connections Maximum number of open connections to the database
+# TYPE copper_sql_pool_max_open_connections gauge
+copper_sql_pool_max_open_connections{db="main"} 10
+# HELP copper_sql_pool_open_connections Number of established connections, both in use and idle
+# TYPE copper_sql_pool_open_connections gauge
+copper_sql_pool_open_connections{db="main"} 6
+# HELP copper_sql_pool_saturation_ratio Ratio of connections in use to the maximum open connections, 0 when unlimited
+# TYPE copper_sql_pool_saturation_ratio gauge
+copper_sql_pool_saturation_ratio{db="main"} 0.4
+# HELP copper_sql_pool_wait_count_total Total number of connections waited for
+# TYPE copper_sql_pool_wait_count_total counter
+copper_sql_pool_wait_count_total{db="main"} 3
+# HELP copper_sql_pool_wait_duration_seconds_total Total time blocked waiting for a new connection, measured in seconds
+# TYPE copper_sql_pool_wait_duration_seconds_total counter
+copper_sql_pool_wait_duration_seconds_total{db="main"} 1.5
+`
+
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSaturation(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats sql.DBStats
+		want  float64
+	}{
+		{"unlimited", sql.DBStats{MaxOpenConnections: 0, InUse: 5}, 0},
+		{"idle", sql.DBStats{MaxOpenConnections: 10}, 0},
+		{"half", sql.DBStats{MaxOpenConnections: 10, InUse: 5}, 0.5},
+		{"fully in use", sql.DBStats{MaxOpenConnections: 10, InUse: 10}, 1},
+	}
+
+	for _, tt := range tests {
+		if got := Saturation(tt.stats); got != tt.want {
+			t.Errorf("%s: Saturation() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
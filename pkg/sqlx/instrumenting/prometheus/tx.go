@@ -0,0 +1,162 @@
+package prometheus
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+)
+
+// Tx a transaction recording the metrics of its statements like the DB which
+// began it
+type Tx struct {
+	db    *DB
+	inner kryptonsqlx.Tx
+
+	// ctx the context the transaction was begun with, Commit and Rollback
+	// take none so they are recorded with its exemplar
+	ctx context.Context
+}
+
+// BeginTransaction implementation of sqlx.TxBeginner, the transaction is
+// begun by the inner DB and its statements, commit and rollback are recorded.
+// The driver hook of WrapDriver is left out, it would record the commit or
+// rollback a second time
+func (db *DB) BeginTransaction(ctx context.Context, opts *sql.TxOptions) (kryptonsqlx.Tx, error) {
+	begin := time.Now()
+
+	inner, err := kryptonsqlx.BeginTransaction(ctx, db.inner, opts)
+
+	db.observeBegin(ctx, begin, err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tx{
+		db:    db,
+		inner: inner,
+		ctx:   ctx,
+	}, nil
+}
+
+// BindNamed prometheus instrumentation implementation of sqlx.Tx.BindNamed, does not
+// gather metrics as it's uneccesary
+func (tx *Tx) BindNamed(query string, arg interface{}) (string, []interface{}, error) {
+	return tx.inner.BindNamed(query, arg)
+}
+
+// Commit prometheus instrumentation implementation of sqlx.Tx.Commit
+func (tx *Tx) Commit() error {
+	begin := time.Now()
+
+	err := tx.inner.Commit()
+
+	tx.db.observeOperation(tx.ctx, "commit", begin, err)
+
+	return err
+}
+
+// DriverName prometheus instrumentation implementation of sqlx.Tx.DriverName, does not
+// gather metrics as it's uneccesary
+func (tx *Tx) DriverName() string {
+	return tx.inner.DriverName()
+}
+
+// ExecContext prometheus instrumentation implementation of sqlx.Tx.ExecContext
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	begin := time.Now()
+
+	res, err := tx.inner.ExecContext(ctx, query, args...)
+
+	tx.db.observeExec(ctx, query, begin, err)
+	tx.db.observeRowsAffected(ctx, query, res)
+
+	return res, err
+}
+
+// GetContext prometheus instrumentation implementation of sqlx.Tx.GetContext
+func (tx *Tx) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	begin := time.Now()
+
+	err := tx.inner.GetContext(ctx, dest, query, args...)
+
+	tx.db.observeQuery(ctx, query, begin, err)
+
+	if err == nil {
+		tx.db.observeQueryRows(ctx, query, 1)
+	}
+
+	return err
+}
+
+// NamedExecContext prometheus instrumentation implementation of sqlx.Tx.NamedExecContext
+func (tx *Tx) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	begin := time.Now()
+
+	res, err := tx.inner.NamedExecContext(ctx, query, arg)
+
+	tx.db.observeExec(ctx, query, begin, err)
+	tx.db.observeRowsAffected(ctx, query, res)
+
+	return res, err
+}
+
+// QueryRowxContext prometheus instrumentation implementation of sqlx.Tx.QueryRowxContext
+func (tx *Tx) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	defer tx.db.observeQuery(ctx, query, time.Now(), nil)
+
+	return tx.inner.QueryRowxContext(ctx, query, args...)
+}
+
+// QueryxContext prometheus instrumentation implementation of sqlx.Tx.QueryxContext, the returned
+// rows are counted and timed when the driver is wrapped with WrapDriver
+func (tx *Tx) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	begin := time.Now()
+
+	res, err := tx.inner.QueryxContext(tx.db.withRowsObserver(ctx, query), query, args...)
+
+	tx.db.observeQuery(ctx, query, begin, err)
+
+	return res, err
+}
+
+// Rebind prometheus instrumentation implementation of sqlx.Tx.Rebind, does not
+// gather metrics as it's uneccesary
+func (tx *Tx) Rebind(query string) string {
+	return tx.inner.Rebind(query)
+}
+
+// Rollback prometheus instrumentation implementation of sqlx.Tx.Rollback, rolling
+// back a transaction already committed is not recorded so it can be deferred
+func (tx *Tx) Rollback() error {
+	begin := time.Now()
+
+	err := tx.inner.Rollback()
+	if errors.Is(err, sql.ErrTxDone) {
+		return err
+	}
+
+	tx.db.observeOperation(tx.ctx, "rollback", begin, err)
+
+	return err
+}
+
+// SelectContext prometheus instrumentation implementation of sqlx.Tx.SelectContext
+func (tx *Tx) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	begin := time.Now()
+
+	err := tx.inner.SelectContext(ctx, dest, query, args...)
+
+	tx.db.observeQuery(ctx, query, begin, err)
+
+	if err == nil {
+		tx.db.observeQueryRows(ctx, query, rowCount(dest))
+	}
+
+	return err
+}
@@ -0,0 +1,61 @@
+package prometheus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+)
+
+func TestBeginTransaction(t *testing.T) {
+	pool, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	db := NewDB(
+		DBWithInnerDB(pool),
+		DBWithRegisterer(prometheus.NewRegistry()),
+	).(*DB)
+
+	ctx := context.Background()
+
+	tx, err := kryptonsqlx.BeginTransaction(ctx, db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "CREATE TABLE t (id INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO t (id) VALUES ($1)", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []int{}
+	if err := tx.SelectContext(ctx, &ids, "SELECT id FROM t"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := testutil.ToFloat64(db.metrics.beginCount.WithLabelValues("ok")); got != 1 {
+		t.Errorf("begins = %v, want 1", got)
+	}
+
+	for _, label := range []string{"create", "insert:t", "select:t"} {
+		if got := testutil.ToFloat64(db.metrics.execCount.WithLabelValues(label, "ok")); got != 1 {
+			t.Errorf("statements labelled %s = %v, want 1", label, got)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
+
+	kryptonsqlxprometheus "github.com/olireadcopper/sqlxprototype/pkg/sqlx/instrumenting/prometheus"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/logging"
+)
+
+// the test lives outside of the logging package, whose frames are skipped
+// when looking for the caller
+func TestCaller(t *testing.T) {
+	pool, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	buf := &bytes.Buffer{}
+
+	// the metrics decorator wraps the logging one, its frames are skipped too
+	db := kryptonsqlxprometheus.NewDB(
+		kryptonsqlxprometheus.DBWithRegisterer(prometheus.NewRegistry()),
+		kryptonsqlxprometheus.DBWithInnerDB(logging.NewDB(
+			logging.DBWithLogger(slog.New(slog.NewJSONHandler(buf, nil))),
+			logging.DBWithInnerDB(pool),
+		)),
+	)
+
+	var ids []int
+
+	if err := db.SelectContext(context.Background(), &ids, "SELECT 1 UNION ALL SELECT 2"); err != nil {
+		t.Fatal(err)
+	}
+
+	record := map[string]any{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+
+	if caller, _ := record["caller"].(string); !strings.HasPrefix(filepath.Base(caller), "caller_test.go:") {
+		t.Errorf("caller = %v, want caller_test.go", record["caller"])
+	}
+
+	if function, _ := record["function"].(string); !strings.HasSuffix(function, ".TestCaller") {
+		t.Errorf("function = %v, want TestCaller", record["function"])
+	}
+
+	if _, ok := record["duration"]; !ok {
+		t.Errorf("duration is missing from %v", record)
+	}
+
+	if record["row_count"] != float64(2) {
+		t.Errorf("row_count = %v, want 2", record["row_count"])
+	}
+
+	if record["in_transaction"] != false {
+		t.Errorf("in_transaction = %v, want false", record["in_transaction"])
+	}
+}
@@ -4,21 +4,29 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
-	"errors"
+	"fmt"
 	"log/slog"
+	"math/rand/v2"
+	"sync/atomic"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 
 	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
 	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/nop"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/sqlerr"
 	"github.com/olireadcopper/sqlxprototype/pkg/telemetry"
 	"github.com/olireadcopper/sqlxprototype/pkg/telemetry/logging"
 )
 
 type DB struct {
-	logger *slog.Logger
-	inner  kryptonsqlx.DB
+	logger  *slog.Logger
+	inner   kryptonsqlx.DB
+	level   slog.Leveler
+	levels  map[string]slog.Level
+	sample  float64
+	limiter *limiter
+	dropped atomic.Uint64
 }
 
 type DBOption func(db *DB)
@@ -27,6 +35,9 @@ func NewDB(opts ...DBOption) kryptonsqlx.DB {
 	db := &DB{
 		logger: logging.NopLogger,
 		inner:  nop.NewDB(),
+		level:  slog.LevelInfo,
+		levels: defaultLevels(),
+		sample: 1,
 	}
 
 	for _, opt := range opts {
@@ -38,10 +49,12 @@ func NewDB(opts ...DBOption) kryptonsqlx.DB {
 
 // Begin logging implmentation of sqlx.Begin
 func (db *DB) Begin() (*sql.Tx, error) {
+	begin := time.Now()
+
 	tx, err := db.inner.Begin()
 
-	db.log(context.TODO(), "", err,
-		logging.FieldMethod, "Begin",
+	db.log(context.TODO(), "Begin", err,
+		fieldDuration, time.Since(begin),
 	)
 
 	return tx, err
@@ -49,10 +62,12 @@ func (db *DB) Begin() (*sql.Tx, error) {
 
 // BeginTx logging implmentation of sqlx.BeginTx
 func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	begin := time.Now()
+
 	tx, err := db.inner.BeginTx(ctx, opts)
 
-	db.log(ctx, "", err,
-		logging.FieldMethod, "BeginTx",
+	db.log(ctx, "BeginTx", err,
+		fieldDuration, time.Since(begin),
 		fieldTransaction, tx,
 	)
 
@@ -61,10 +76,12 @@ func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
 
 // BeginTxx logging implmentation of sqlx.BeginTxx
 func (db *DB) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error) {
+	begin := time.Now()
+
 	tx, err := db.inner.BeginTxx(ctx, opts)
 
-	db.log(ctx, "", err,
-		logging.FieldMethod, "BeginTxx",
+	db.log(ctx, "BeginTxx", err,
+		fieldDuration, time.Since(begin),
 		fieldTransaction, tx,
 	)
 
@@ -73,10 +90,12 @@ func (db *DB) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, erro
 
 // Beginx logging implmentation of sqlx.Beginx
 func (db *DB) Beginx() (*sqlx.Tx, error) {
+	begin := time.Now()
+
 	tx, err := db.inner.Beginx()
 
-	db.log(context.TODO(), "", err,
-		logging.FieldMethod, "Beginx",
+	db.log(context.TODO(), "Beginx", err,
+		fieldDuration, time.Since(begin),
 		fieldTransaction, tx,
 	)
 
@@ -87,8 +106,7 @@ func (db *DB) Beginx() (*sqlx.Tx, error) {
 func (db *DB) BindNamed(query string, arg interface{}) (string, []interface{}, error) {
 	res, args, err := db.inner.BindNamed(query, arg)
 
-	db.log(context.TODO(), "", err,
-		logging.FieldMethod, "BindNamed",
+	db.log(context.TODO(), "BindNamed", err,
 		fieldQuery, query,
 		fieldArgs, arg,
 		fieldResult, res,
@@ -101,19 +119,19 @@ func (db *DB) BindNamed(query string, arg interface{}) (string, []interface{}, e
 func (db *DB) Close() error {
 	err := db.inner.Close()
 
-	db.log(context.TODO(), "", err,
-		logging.FieldMethod, "Close",
-	)
+	db.log(context.TODO(), "Close", err)
 
 	return nil
 }
 
 // Conn logging implmentation of sqlx.Conn
 func (db *DB) Conn(ctx context.Context) (*sql.Conn, error) {
+	begin := time.Now()
+
 	conn, err := db.inner.Conn(ctx)
 
-	db.log(ctx, "", err,
-		logging.FieldMethod, "Conn",
+	db.log(ctx, "Conn", err,
+		fieldDuration, time.Since(begin),
 		fieldConn, conn,
 	)
 
@@ -122,10 +140,12 @@ func (db *DB) Conn(ctx context.Context) (*sql.Conn, error) {
 
 // Connx logging implmentation of sqlx.Connx
 func (db *DB) Connx(ctx context.Context) (*sqlx.Conn, error) {
+	begin := time.Now()
+
 	conn, err := db.inner.Connx(ctx)
 
-	db.log(ctx, "", err,
-		logging.FieldMethod, "Connx",
+	db.log(ctx, "Connx", err,
+		fieldDuration, time.Since(begin),
 		fieldConn, conn,
 	)
 	return conn, err
@@ -135,8 +155,7 @@ func (db *DB) Connx(ctx context.Context) (*sqlx.Conn, error) {
 func (db *DB) Driver() driver.Driver {
 	driver := db.inner.Driver()
 
-	db.log(context.TODO(), "", nil,
-		logging.FieldMethod, "Driver",
+	db.log(context.TODO(), "Driver", nil,
 		"driver", driver,
 	)
 
@@ -147,8 +166,7 @@ func (db *DB) Driver() driver.Driver {
 func (db *DB) DriverName() string {
 	driver := db.inner.DriverName()
 
-	db.log(context.TODO(), "", nil,
-		logging.FieldMethod, "DriverName",
+	db.log(context.TODO(), "DriverName", nil,
 		"driverName", driver,
 	)
 
@@ -157,13 +175,15 @@ func (db *DB) DriverName() string {
 
 // Exec logging implmentation of sqlx.Exec
 func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	begin := time.Now()
+
 	res, err := db.inner.Exec(query, args...)
 
-	db.log(context.TODO(), "", err,
-		logging.FieldMethod, "Exec",
+	db.log(context.TODO(), "Exec", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, args,
-		fieldResult, res,
+		resultAttr(res),
 	)
 
 	return res, err
@@ -171,13 +191,15 @@ func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
 
 // ExecContext logging implmentation of sqlx.ExecContext
 func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	begin := time.Now()
+
 	res, err := db.inner.ExecContext(ctx, query, args...)
 
-	db.log(ctx, "", err,
-		logging.FieldMethod, "ExecContext",
+	db.log(ctx, "ExecContext", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, args,
-		fieldResult, res,
+		resultAttr(res),
 	)
 
 	return res, err
@@ -185,10 +207,12 @@ func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.R
 
 // Get logging implmentation of sqlx.Get
 func (db *DB) Get(dest interface{}, query string, args ...interface{}) error {
+	begin := time.Now()
+
 	err := db.inner.Get(dest, query, args...)
 
-	db.log(context.TODO(), "", err,
-		logging.FieldMethod, "Get",
+	db.log(context.TODO(), "Get", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, args,
 		fieldDest, dest,
@@ -199,10 +223,12 @@ func (db *DB) Get(dest interface{}, query string, args ...interface{}) error {
 
 // GetContext logging implmentation of sqlx.GetContext
 func (db *DB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	begin := time.Now()
+
 	err := db.inner.GetContext(ctx, dest, query, args...)
 
-	db.log(ctx, "", err,
-		logging.FieldMethod, "GetContext",
+	db.log(ctx, "GetContext", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, args,
 		fieldDest, dest,
@@ -215,17 +241,17 @@ func (db *DB) GetContext(ctx context.Context, dest interface{}, query string, ar
 func (db *DB) MapperFunc(mf func(string) string) {
 	db.inner.MapperFunc(mf)
 
-	db.log(context.TODO(), "", nil,
-		logging.FieldMethod, "MapperFunc",
-	)
+	db.log(context.TODO(), "MapperFunc", nil)
 }
 
 // MustBegin logging implmentation of sqlx.MustBegin
 func (db *DB) MustBegin() *sqlx.Tx {
+	begin := time.Now()
+
 	tx := db.inner.MustBegin()
 
-	db.log(context.TODO(), "", nil,
-		logging.FieldMethod, "MustBegin",
+	db.log(context.TODO(), "MustBegin", nil,
+		fieldDuration, time.Since(begin),
 		fieldTransaction, tx,
 	)
 
@@ -234,10 +260,12 @@ func (db *DB) MustBegin() *sqlx.Tx {
 
 // MustBeginTx logging implmentation of sqlx.MustBeginTx
 func (db *DB) MustBeginTx(ctx context.Context, opts *sql.TxOptions) *sqlx.Tx {
+	begin := time.Now()
+
 	tx := db.MustBegin()
 
-	db.log(ctx, "", nil,
-		logging.FieldMethod, "MustBeginTx",
+	db.log(ctx, "MustBeginTx", nil,
+		fieldDuration, time.Since(begin),
 		fieldTransaction, tx,
 	)
 
@@ -246,13 +274,15 @@ func (db *DB) MustBeginTx(ctx context.Context, opts *sql.TxOptions) *sqlx.Tx {
 
 // MustExec logging implmentation of sqlx.MustExec
 func (db *DB) MustExec(query string, args ...interface{}) sql.Result {
+	begin := time.Now()
+
 	res := db.inner.MustExec(query, args...)
 
-	db.log(context.TODO(), "", nil,
-		logging.FieldMethod, "MustExec",
+	db.log(context.TODO(), "MustExec", nil,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, args,
-		fieldResult, res,
+		resultAttr(res),
 	)
 
 	return res
@@ -260,13 +290,15 @@ func (db *DB) MustExec(query string, args ...interface{}) sql.Result {
 
 // MustExecContext logging implmentation of sqlx.MustExecContext
 func (db *DB) MustExecContext(ctx context.Context, query string, args ...interface{}) sql.Result {
+	begin := time.Now()
+
 	res := db.inner.MustExecContext(ctx, query, args...)
 
-	db.log(ctx, "", nil,
-		logging.FieldMethod, "MustExecContext",
+	db.log(ctx, "MustExecContext", nil,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, args,
-		fieldResult, res,
+		resultAttr(res),
 	)
 
 	return res
@@ -274,13 +306,15 @@ func (db *DB) MustExecContext(ctx context.Context, query string, args ...interfa
 
 // NamedExec logging implmentation of sqlx.NamedExec
 func (db *DB) NamedExec(query string, arg interface{}) (sql.Result, error) {
+	begin := time.Now()
+
 	res, err := db.inner.NamedExec(query, arg)
 
-	db.log(context.TODO(), "", err,
-		logging.FieldMethod, "NamedExec",
+	db.log(context.TODO(), "NamedExec", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, arg,
-		fieldResult, res,
+		resultAttr(res),
 	)
 
 	return res, err
@@ -288,13 +322,15 @@ func (db *DB) NamedExec(query string, arg interface{}) (sql.Result, error) {
 
 // NamedExecContext logging implmentation of sqlx.NamedExecContext
 func (db *DB) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	begin := time.Now()
+
 	res, err := db.inner.NamedExecContext(ctx, query, arg)
 
-	db.log(ctx, "", err,
-		logging.FieldMethod, "NamedExecContext",
+	db.log(ctx, "NamedExecContext", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, arg,
-		fieldResult, res,
+		resultAttr(res),
 	)
 
 	return res, err
@@ -302,10 +338,12 @@ func (db *DB) NamedExecContext(ctx context.Context, query string, arg interface{
 
 // NamedQuery logging implmentation of sqlx.NamedQuery
 func (db *DB) NamedQuery(query string, arg interface{}) (*sqlx.Rows, error) {
+	begin := time.Now()
+
 	rows, err := db.inner.NamedQuery(query, arg)
 
-	db.log(context.TODO(), "", err,
-		logging.FieldMethod, "NamedQuery",
+	db.log(context.TODO(), "NamedQuery", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, arg,
 		fieldRows, rows,
@@ -316,10 +354,12 @@ func (db *DB) NamedQuery(query string, arg interface{}) (*sqlx.Rows, error) {
 
 // NamedQueryContext logging implmentation of sqlx.NamedQueryContext
 func (db *DB) NamedQueryContext(ctx context.Context, query string, arg interface{}) (*sqlx.Rows, error) {
+	begin := time.Now()
+
 	rows, err := db.inner.NamedQueryContext(ctx, query, arg)
 
-	db.log(ctx, "", err,
-		logging.FieldMethod, "NamedQueryContext",
+	db.log(ctx, "NamedQueryContext", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, arg,
 		fieldRows, rows,
@@ -330,10 +370,12 @@ func (db *DB) NamedQueryContext(ctx context.Context, query string, arg interface
 
 // Ping logging implmentation of sqlx.Ping
 func (db *DB) Ping() error {
+	begin := time.Now()
+
 	err := db.inner.Ping()
 
-	db.log(context.TODO(), "", err,
-		logging.FieldMethod, "Ping",
+	db.log(context.TODO(), "Ping", err,
+		fieldDuration, time.Since(begin),
 	)
 
 	return err
@@ -341,10 +383,12 @@ func (db *DB) Ping() error {
 
 // PingContext logging implmentation of sqlx.PingContext
 func (db *DB) PingContext(ctx context.Context) error {
+	begin := time.Now()
+
 	err := db.inner.PingContext(ctx)
 
-	db.log(ctx, "", err,
-		logging.FieldMethod, "Ping",
+	db.log(ctx, "PingContext", err,
+		fieldDuration, time.Since(begin),
 	)
 
 	return err
@@ -352,10 +396,12 @@ func (db *DB) PingContext(ctx context.Context) error {
 
 // Prepare logging implmentation of sqlx.Prepare
 func (db *DB) Prepare(query string) (*sql.Stmt, error) {
+	begin := time.Now()
+
 	stmt, err := db.inner.Prepare(query)
 
-	db.log(context.TODO(), "", err,
-		logging.FieldMethod, "Prepare",
+	db.log(context.TODO(), "Prepare", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldStatement, stmt,
 	)
@@ -365,10 +411,12 @@ func (db *DB) Prepare(query string) (*sql.Stmt, error) {
 
 // PrepareContext logging implmentation of sqlx.PrepareContext
 func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	begin := time.Now()
+
 	stmt, err := db.inner.PrepareContext(ctx, query)
 
-	db.log(ctx, "", err,
-		logging.FieldMethod, "PrepareContext",
+	db.log(ctx, "PrepareContext", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldStatement, stmt,
 	)
@@ -378,10 +426,12 @@ func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, erro
 
 // PrepareNamed logging implmentation of sqlx.PrepareNamed
 func (db *DB) PrepareNamed(query string) (*sqlx.NamedStmt, error) {
+	begin := time.Now()
+
 	stmt, err := db.inner.PrepareNamed(query)
 
-	db.log(context.TODO(), "", err,
-		logging.FieldMethod, "PrepareNamed",
+	db.log(context.TODO(), "PrepareNamed", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldStatement, stmt,
 	)
@@ -391,10 +441,12 @@ func (db *DB) PrepareNamed(query string) (*sqlx.NamedStmt, error) {
 
 // PrepareNamedContext logging implmentation of sqlx.PrepareNamedContext
 func (db *DB) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	begin := time.Now()
+
 	stmt, err := db.inner.PrepareNamedContext(ctx, query)
 
-	db.log(ctx, "", err,
-		logging.FieldMethod, "PrepareNamedContext",
+	db.log(ctx, "PrepareNamedContext", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldStatement, stmt,
 	)
@@ -404,10 +456,12 @@ func (db *DB) PrepareNamedContext(ctx context.Context, query string) (*sqlx.Name
 
 // Preparex logging implmentation of sqlx.Preparex
 func (db *DB) Preparex(query string) (*sqlx.Stmt, error) {
+	begin := time.Now()
+
 	stmt, err := db.inner.Preparex(query)
 
-	db.log(context.TODO(), "", err,
-		logging.FieldMethod, "Preparex",
+	db.log(context.TODO(), "Preparex", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldStatement, stmt,
 	)
@@ -417,10 +471,12 @@ func (db *DB) Preparex(query string) (*sqlx.Stmt, error) {
 
 // PreparexContext logging implmentation of sqlx.PreparexContext
 func (db *DB) PreparexContext(ctx context.Context, query string) (*sqlx.Stmt, error) {
+	begin := time.Now()
+
 	stmt, err := db.inner.PreparexContext(ctx, query)
 
-	db.log(ctx, "", err,
-		logging.FieldMethod, "PreparexContext",
+	db.log(ctx, "PreparexContext", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldStatement, stmt,
 	)
@@ -430,10 +486,12 @@ func (db *DB) PreparexContext(ctx context.Context, query string) (*sqlx.Stmt, er
 
 // Query logging implmentation of sqlx.Query
 func (db *DB) Query(query string, args ...any) (*sql.Rows, error) {
-	rows, err := db.inner.Query(query, args)
+	begin := time.Now()
+
+	rows, err := db.inner.Query(query, args...)
 
-	db.log(context.TODO(), "", err,
-		logging.FieldMethod, "Query",
+	db.log(context.TODO(), "Query", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, args,
 		fieldRows, rows,
@@ -444,10 +502,12 @@ func (db *DB) Query(query string, args ...any) (*sql.Rows, error) {
 
 // QueryContext logging implmentation of sqlx.QueryContext
 func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
-	rows, err := db.inner.QueryContext(ctx, query, args)
+	begin := time.Now()
+
+	rows, err := db.inner.QueryContext(ctx, query, args...)
 
-	db.log(ctx, "", err,
-		logging.FieldMethod, "QueryContext",
+	db.log(ctx, "QueryContext", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, args,
 		fieldRows, rows,
@@ -458,10 +518,12 @@ func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql
 
 // QueryRow logging implmentation of sqlx.QueryRow
 func (db *DB) QueryRow(query string, args ...any) *sql.Row {
-	row := db.inner.QueryRow(query, args)
+	begin := time.Now()
 
-	db.log(context.TODO(), "", nil,
-		logging.FieldMethod, "QueryRow",
+	row := db.inner.QueryRow(query, args...)
+
+	db.log(context.TODO(), "QueryRow", nil,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, args,
 		fieldRows, row,
@@ -472,10 +534,12 @@ func (db *DB) QueryRow(query string, args ...any) *sql.Row {
 
 // QueryRowContext logging implmentation of sqlx.QueryRowContext
 func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
-	row := db.inner.QueryRowContext(ctx, query, args)
+	begin := time.Now()
+
+	row := db.inner.QueryRowContext(ctx, query, args...)
 
-	db.log(ctx, "", nil,
-		logging.FieldMethod, "QueryRowContext",
+	db.log(ctx, "QueryRowContext", nil,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, args,
 		fieldRows, row,
@@ -486,10 +550,12 @@ func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *s
 
 // QueryRowx logging implmentation of sqlx.QueryRowx
 func (db *DB) QueryRowx(query string, args ...interface{}) *sqlx.Row {
-	row := db.inner.QueryRowx(query, args)
+	begin := time.Now()
 
-	db.log(context.TODO(), "", nil,
-		logging.FieldMethod, "QueryRowx",
+	row := db.inner.QueryRowx(query, args...)
+
+	db.log(context.TODO(), "QueryRowx", nil,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, args,
 		fieldRows, row,
@@ -500,10 +566,12 @@ func (db *DB) QueryRowx(query string, args ...interface{}) *sqlx.Row {
 
 // QueryRowxContext logging implmentation of sqlx.QueryRowxContext
 func (db *DB) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
-	row := db.inner.QueryRowxContext(ctx, query, args)
+	begin := time.Now()
+
+	row := db.inner.QueryRowxContext(ctx, query, args...)
 
-	db.log(ctx, "", nil,
-		logging.FieldMethod, "QueryRowxContext",
+	db.log(ctx, "QueryRowxContext", nil,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, args,
 		fieldRows, row,
@@ -514,10 +582,12 @@ func (db *DB) QueryRowxContext(ctx context.Context, query string, args ...interf
 
 // Queryx logging implmentation of sqlx.Queryx
 func (db *DB) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
-	rows, err := db.inner.Queryx(query, args)
+	begin := time.Now()
 
-	db.log(context.TODO(), "", err,
-		logging.FieldMethod, "Queryx",
+	rows, err := db.inner.Queryx(query, args...)
+
+	db.log(context.TODO(), "Queryx", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, args,
 		fieldRows, rows,
@@ -528,10 +598,12 @@ func (db *DB) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
 
 // QueryxContext logging implmentation of sqlx.QueryxContext
 func (db *DB) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
-	rows, err := db.inner.QueryxContext(ctx, query, args)
+	begin := time.Now()
+
+	rows, err := db.inner.QueryxContext(ctx, query, args...)
 
-	db.log(ctx, "", err,
-		logging.FieldMethod, "QueryxContext",
+	db.log(ctx, "QueryxContext", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, args,
 		fieldRows, rows,
@@ -543,8 +615,7 @@ func (db *DB) QueryxContext(ctx context.Context, query string, args ...interface
 func (db *DB) Rebind(query string) string {
 	rebind := db.inner.Rebind(query)
 
-	db.log(context.TODO(), "", nil,
-		logging.FieldMethod, "Rebind",
+	db.log(context.TODO(), "Rebind", nil,
 		fieldQuery, query,
 		"rebind", rebind,
 	)
@@ -554,13 +625,16 @@ func (db *DB) Rebind(query string) string {
 
 // Select logging implmentation of sqlx.Select
 func (db *DB) Select(dest interface{}, query string, args ...interface{}) error {
+	begin := time.Now()
+
 	err := db.inner.Select(dest, query, args...)
 
-	db.log(context.TODO(), "", err,
-		logging.FieldMethod, "Select",
+	db.log(context.TODO(), "Select", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, args,
 		fieldDest, dest,
+		rowCountAttr(dest),
 	)
 
 	return err
@@ -568,13 +642,16 @@ func (db *DB) Select(dest interface{}, query string, args ...interface{}) error
 
 // SelectContext logging implmentation of sqlx.SelectContext
 func (db *DB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	begin := time.Now()
+
 	err := db.inner.SelectContext(ctx, dest, query, args...)
 
-	db.log(ctx, "", err,
-		logging.FieldMethod, "Select",
+	db.log(ctx, "SelectContext", err,
+		fieldDuration, time.Since(begin),
 		fieldQuery, query,
 		fieldArgs, args,
 		fieldDest, dest,
+		rowCountAttr(dest),
 	)
 
 	return err
@@ -584,8 +661,7 @@ func (db *DB) SelectContext(ctx context.Context, dest interface{}, query string,
 func (db *DB) SetConnMaxIdleTime(d time.Duration) {
 	db.inner.SetConnMaxIdleTime(d)
 
-	db.log(context.TODO(), "", nil,
-		logging.FieldMethod, "SetConnMaxIdleTime",
+	db.log(context.TODO(), "SetConnMaxIdleTime", nil,
 		fieldDuration, d,
 	)
 }
@@ -594,8 +670,7 @@ func (db *DB) SetConnMaxIdleTime(d time.Duration) {
 func (db *DB) SetConnMaxLifetime(d time.Duration) {
 	db.inner.SetConnMaxLifetime(d)
 
-	db.log(context.TODO(), "", nil,
-		logging.FieldMethod, "SetConnMaxLifetime",
+	db.log(context.TODO(), "SetConnMaxLifetime", nil,
 		fieldDuration, d,
 	)
 }
@@ -604,8 +679,7 @@ func (db *DB) SetConnMaxLifetime(d time.Duration) {
 func (db *DB) SetMaxIdleConns(n int) {
 	db.inner.SetMaxIdleConns(n)
 
-	db.log(context.TODO(), "", nil,
-		logging.FieldMethod, "SetMaxIdleConns",
+	db.log(context.TODO(), "SetMaxIdleConns", nil,
 		fieldConnections, n,
 	)
 }
@@ -614,8 +688,7 @@ func (db *DB) SetMaxIdleConns(n int) {
 func (db *DB) SetMaxOpenConns(n int) {
 	db.inner.SetMaxOpenConns(n)
 
-	db.log(context.TODO(), "", nil,
-		logging.FieldMethod, "SetMaxOpenConns",
+	db.log(context.TODO(), "SetMaxOpenConns", nil,
 		fieldConnections, n,
 	)
 }
@@ -624,8 +697,7 @@ func (db *DB) SetMaxOpenConns(n int) {
 func (db *DB) Stats() sql.DBStats {
 	stats := db.inner.Stats()
 
-	db.log(context.TODO(), "", nil,
-		logging.FieldMethod, "Stats",
+	db.log(context.TODO(), "Stats", nil,
 		"stats", stats,
 	)
 
@@ -636,32 +708,94 @@ func (db *DB) Stats() sql.DBStats {
 func (db *DB) Unsafe() *sqlx.DB {
 	unsafe := db.inner.Unsafe()
 
-	db.log(context.TODO(), "", nil,
-		logging.FieldMethod, "Unsafe",
-	)
+	db.log(context.TODO(), "Unsafe", nil)
 
 	return unsafe
 }
 
-func (db *DB) log(ctx context.Context, msg string, err error, args ...any) {
+func (db *DB) log(ctx context.Context, method string, err error, args ...any) {
+	level := slog.LevelError
+	if err == nil {
+		level = db.methodLevel(method)
+	}
+
+	if level < db.level.Level() || !db.logger.Enabled(ctx, level) {
+		return
+	}
+
+	// errors are never sampled, only successful calls are
+	if err == nil && db.sample < 1 && rand.Float64() >= db.sample {
+		return
+	}
+
+	// nor are errors rate limited, the limit only sheds successful calls
+	if err == nil && db.limiter != nil && !db.limiter.allow() {
+		db.dropped.Add(1)
+		return
+	}
+
+	args = append([]any{logging.FieldMethod, method}, args...)
+
+	if dropped := db.dropped.Swap(0); dropped > 0 {
+		args = append(args, fieldDropped, dropped)
+	}
+
 	if trace := ctx.Value(telemetry.ContextKeyTrace); trace != nil {
 		args = append(args, logging.FieldTrace, trace)
 	}
 
+	if frame, ok := caller(); ok {
+		args = append(args,
+			fieldCaller, fmt.Sprintf("%s:%d", frame.File, frame.Line),
+			fieldFunction, frame.Function,
+		)
+	}
+
+	_, inTx := kryptonsqlx.TxFromContext(ctx)
+	args = append(args, fieldInTransaction, inTx)
+
 	if err != nil {
-		db.logError(err, args...)
+		db.logError(ctx, err, args...)
 		return
 	}
 
-	db.logInfo(msg, args...)
+	db.logger.Log(ctx, level, "", args...)
 }
 
-func (db *DB) logInfo(msg string, args ...any) {
-	db.logger.Info(msg, args...)
+func (db *DB) logError(ctx context.Context, err error, args ...any) {
+	args = append(args, logging.FieldErrorCode, sqlerr.Code(err))
+
+	db.logger.Log(ctx, slog.LevelError, err.Error(), args...)
 }
 
-func (db *DB) logError(err error, args ...any) {
-	args = append(args, logging.FieldErrorCode, errors.Unwrap(err).Error())
+func (db *DB) methodLevel(method string) slog.Level {
+	if level, ok := db.levels[method]; ok {
+		return level
+	}
+
+	return slog.LevelInfo
+}
+
+// defaultLevels demotes the methods which are called frequently but carry no
+// information about the queries being run
+func defaultLevels() map[string]slog.Level {
+	levels := map[string]slog.Level{}
+
+	for _, method := range []string{
+		"BindNamed",
+		"Driver",
+		"DriverName",
+		"MapperFunc",
+		"Rebind",
+		"SetConnMaxIdleTime",
+		"SetConnMaxLifetime",
+		"SetMaxIdleConns",
+		"SetMaxOpenConns",
+		"Stats",
+		"Unsafe",
+	} {
+		levels[method] = slog.LevelDebug
+	}
 
-	db.logger.Error(err.Error(), args...)
+	return levels
 }
@@ -0,0 +1,163 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogRateLimitKeepsErrors(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	db := NewDB(
+		DBWithLogger(slog.New(slog.NewJSONHandler(buf, nil))),
+		DBWithRateLimit(0, 1),
+	).(*DB)
+
+	ctx := context.Background()
+
+	db.log(ctx, "ExecContext", nil)
+	db.log(ctx, "ExecContext", nil)
+	db.log(ctx, "ExecContext", errors.New("boom"))
+	db.log(ctx, "ExecContext", errors.New("bang"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("wrote %d records, want the first success and both errors:\n%s", len(lines), buf)
+	}
+
+	for _, want := range []string{"boom", "bang"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("error %q was not logged:\n%s", want, buf)
+		}
+	}
+
+	if !strings.Contains(lines[1], `"dropped":1`) {
+		t.Errorf("record after the dropped success = %s, want dropped=1", lines[1])
+	}
+}
+
+// records returns the records written to buf, one per line
+func records(buf *bytes.Buffer) []string {
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSpace(buf.String()), "\n")
+}
+
+func TestLogSampling(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		rate     float64
+		min, max int
+	}{
+		{"none", 0, 0, 0},
+		{"half", 0.5, 350, 650},
+		{"all", 1, 1000, 1000},
+	}
+
+	for _, tt := range tests {
+		buf := &bytes.Buffer{}
+
+		db := NewDB(
+			DBWithLogger(slog.New(slog.NewJSONHandler(buf, nil))),
+			DBWithSampling(tt.rate),
+		).(*DB)
+
+		for range 1000 {
+			db.log(ctx, "ExecContext", nil)
+		}
+
+		if n := len(records(buf)); n < tt.min || n > tt.max {
+			t.Errorf("%s: %d of 1000 successes logged, want between %d and %d", tt.name, n, tt.min, tt.max)
+		}
+
+		// errors are never sampled
+		buf.Reset()
+
+		for range 10 {
+			db.log(ctx, "ExecContext", errors.New("boom"))
+		}
+
+		if n := len(records(buf)); n != 10 {
+			t.Errorf("%s: %d of 10 errors logged, want all of them", tt.name, n)
+		}
+	}
+}
+
+func TestLogMethodLevels(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		levels map[string]slog.Level
+		level  slog.Level
+		call   func(db *DB)
+		want   int
+	}{
+		{"Rebind defaults to debug", nil, slog.LevelInfo, func(db *DB) { db.Rebind("SELECT 1") }, 0},
+		{"Stats defaults to debug", nil, slog.LevelInfo, func(db *DB) { db.Stats() }, 0},
+		{"Rebind at debug", nil, slog.LevelDebug, func(db *DB) { db.Rebind("SELECT 1") }, 1},
+		{"queries default to info", nil, slog.LevelInfo, func(db *DB) { db.ExecContext(ctx, "SELECT 1") }, 1},
+		{"raised", map[string]slog.Level{"Rebind": slog.LevelInfo}, slog.LevelInfo, func(db *DB) { db.Rebind("SELECT 1") }, 1},
+		{"lowered", map[string]slog.Level{"ExecContext": slog.LevelDebug}, slog.LevelInfo, func(db *DB) { db.ExecContext(ctx, "SELECT 1") }, 0},
+		{"others kept", map[string]slog.Level{"ExecContext": slog.LevelDebug}, slog.LevelInfo, func(db *DB) { db.Stats() }, 0},
+		{"errors at error", map[string]slog.Level{"ExecContext": slog.LevelDebug}, slog.LevelInfo, func(db *DB) { db.log(ctx, "ExecContext", errors.New("boom")) }, 1},
+	}
+
+	for _, tt := range tests {
+		buf := &bytes.Buffer{}
+
+		db := NewDB(
+			DBWithLogger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+			DBWithLevel(tt.level),
+			DBWithMethodLevels(tt.levels),
+		).(*DB)
+
+		tt.call(db)
+
+		if n := len(records(buf)); n != tt.want {
+			t.Errorf("%s: %d records, want %d", tt.name, n, tt.want)
+		}
+	}
+}
+
+func TestLogLevelVar(t *testing.T) {
+	ctx := context.Background()
+	buf := &bytes.Buffer{}
+	level := &slog.LevelVar{}
+
+	db := NewDB(
+		DBWithLogger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+		DBWithLevel(level),
+	).(*DB)
+
+	tests := []struct {
+		name  string
+		level slog.Level
+		want  int
+	}{
+		{"info", slog.LevelInfo, 1},
+		{"debug", slog.LevelDebug, 2},
+		{"error", slog.LevelError, 0},
+	}
+
+	// the level is read on every call, so changes apply to the next one
+	for _, tt := range tests {
+		buf.Reset()
+		level.Set(tt.level)
+
+		db.Rebind("SELECT 1")
+		db.ExecContext(ctx, "SELECT 1")
+
+		if n := len(records(buf)); n != tt.want {
+			t.Errorf("%s: %d records, want %d", tt.name, n, tt.want)
+		}
+	}
+}
@@ -22,3 +22,39 @@ func DBWithInnerDB(db sqlx.DB) DBOption {
 		d.inner = db
 	}
 }
+
+// DBWithLevel sets the minimum level a call must be logged at to be written,
+// passing a *slog.LevelVar allows the verbosity to be changed at runtime
+func DBWithLevel(l slog.Leveler) DBOption {
+	return func(d *DB) {
+		d.level = l
+	}
+}
+
+// DBWithMethodLevels overrides the level successful calls are logged at per
+// method name, e.g. "QueryxContext", methods not present keep their default
+func DBWithMethodLevels(levels map[string]slog.Level) DBOption {
+	return func(d *DB) {
+		for method, level := range levels {
+			d.levels[method] = level
+		}
+	}
+}
+
+// DBWithSampling logs only the given fraction, between 0 and 1, of successful
+// calls, calls returning an error are always logged
+func DBWithSampling(rate float64) DBOption {
+	return func(d *DB) {
+		d.sample = rate
+	}
+}
+
+// DBWithRateLimit caps the number of records of successful calls written per
+// second, allowing bursts of up to burst records, calls returning an error
+// are always logged, the number of records dropped is attached to the next
+// record written
+func DBWithRateLimit(perSecond float64, burst int) DBOption {
+	return func(d *DB) {
+		d.limiter = newLimiter(perSecond, burst)
+	}
+}
@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"database/sql"
+	"log/slog"
+	"path"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// decoratorPackages the packages whose frames are skipped when looking for
+// the caller of a query, so decorators stacked on one another are reported
+// as the code which called the outermost one
+var decoratorPackages = func() []string {
+	pkg := reflect.TypeOf(DB{}).PkgPath()
+	base := path.Dir(pkg)
+
+	return []string{
+		pkg + ".",
+		base + "/instrumenting/",
+		base + "/nop.",
+		base + ".BeginTransaction",
+	}
+}()
+
+// caller walks the stack past the decorator frames and returns the first
+// frame outside of them
+func caller() (runtime.Frame, bool) {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+
+	frames := runtime.CallersFrames(pcs[:n])
+
+	for {
+		frame, more := frames.Next()
+		if !isDecoratorFrame(frame.Function) {
+			return frame, frame.Function != ""
+		}
+
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
+
+func isDecoratorFrame(function string) bool {
+	for _, pkg := range decoratorPackages {
+		if strings.HasPrefix(function, pkg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resultAttr groups the rows affected and last insert id of an exec result,
+// values the driver does not support are left out
+func resultAttr(res sql.Result) slog.Attr {
+	if res == nil {
+		return slog.Attr{}
+	}
+
+	args := []any{}
+
+	if n, err := res.RowsAffected(); err == nil {
+		args = append(args, fieldRowsAffected, n)
+	}
+
+	if id, err := res.LastInsertId(); err == nil {
+		args = append(args, fieldLastInsertID, id)
+	}
+
+	return slog.Group(fieldResult, args...)
+}
+
+// rowCountAttr the number of rows scanned into the destination slice of a
+// Select
+func rowCountAttr(dest any) slog.Attr {
+	v := reflect.ValueOf(dest)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Slice {
+		return slog.Attr{}
+	}
+
+	return slog.Int(fieldRowCount, v.Len())
+}
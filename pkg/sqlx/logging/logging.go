@@ -1,14 +1,23 @@
 package logging
 
+import "github.com/olireadcopper/sqlxprototype/pkg/telemetry/logging"
+
 const (
-	fieldArgs        = "args"
-	fieldConn        = "conn"
-	fieldConnections = "connections"
-	fieldDest        = "dest"
-	fieldDuration    = "duration"
-	fieldQuery       = "query"
-	fieldResult      = "result"
-	fieldRows        = "rows"
-	fieldTransaction = "transaction"
-	fieldStatement   = "statement"
+	fieldArgs          = "args"
+	fieldCaller        = "caller"
+	fieldConn          = "conn"
+	fieldConnections   = "connections"
+	fieldDest          = "dest"
+	fieldDropped       = "dropped"
+	fieldDuration      = logging.FieldDuration
+	fieldFunction      = "function"
+	fieldInTransaction = "in_transaction"
+	fieldLastInsertID  = "last_insert_id"
+	fieldQuery         = "query"
+	fieldResult        = "result"
+	fieldRows          = "rows"
+	fieldRowsAffected  = "rows_affected"
+	fieldRowCount      = "row_count"
+	fieldTransaction   = "transaction"
+	fieldStatement     = "statement"
 )
@@ -0,0 +1,213 @@
+package logging
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+)
+
+// Tx a transaction logging its statements like the DB which began it, with
+// in_transaction set
+type Tx struct {
+	db    *DB
+	inner kryptonsqlx.Tx
+	// ctx the context the transaction was begun with, Commit and Rollback
+	// take none so they are logged with its trace
+	ctx context.Context
+}
+
+// BeginTransaction implementation of sqlx.TxBeginner, the transaction is
+// begun by the inner DB and its statements are logged
+func (db *DB) BeginTransaction(ctx context.Context, opts *sql.TxOptions) (kryptonsqlx.Tx, error) {
+	begin := time.Now()
+
+	inner, err := kryptonsqlx.BeginTransaction(ctx, db.inner, opts)
+
+	db.log(ctx, "BeginTransaction", err,
+		fieldDuration, time.Since(begin),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tx{
+		db:    db,
+		inner: inner,
+		ctx:   ctx,
+	}, nil
+}
+
+// BindNamed logging implementation of sqlx.Tx.BindNamed
+func (tx *Tx) BindNamed(query string, arg interface{}) (string, []interface{}, error) {
+	res, args, err := tx.inner.BindNamed(query, arg)
+
+	tx.db.log(tx.context(tx.ctx), "BindNamed", err,
+		fieldQuery, query,
+		fieldArgs, arg,
+		fieldResult, res,
+	)
+
+	return res, args, err
+}
+
+// Commit logging implementation of sqlx.Tx.Commit
+func (tx *Tx) Commit() error {
+	begin := time.Now()
+
+	err := tx.inner.Commit()
+
+	tx.db.log(tx.context(tx.ctx), "Commit", err,
+		fieldDuration, time.Since(begin),
+	)
+
+	return err
+}
+
+// DriverName logging implementation of sqlx.Tx.DriverName
+func (tx *Tx) DriverName() string {
+	driver := tx.inner.DriverName()
+
+	tx.db.log(tx.context(tx.ctx), "DriverName", nil,
+		"driverName", driver,
+	)
+
+	return driver
+}
+
+// ExecContext logging implementation of sqlx.Tx.ExecContext
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	begin := time.Now()
+
+	res, err := tx.inner.ExecContext(ctx, query, args...)
+
+	tx.db.log(tx.context(ctx), "ExecContext", err,
+		fieldDuration, time.Since(begin),
+		fieldQuery, query,
+		fieldArgs, args,
+		resultAttr(res),
+	)
+
+	return res, err
+}
+
+// GetContext logging implementation of sqlx.Tx.GetContext
+func (tx *Tx) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	begin := time.Now()
+
+	err := tx.inner.GetContext(ctx, dest, query, args...)
+
+	tx.db.log(tx.context(ctx), "GetContext", err,
+		fieldDuration, time.Since(begin),
+		fieldQuery, query,
+		fieldArgs, args,
+		fieldDest, dest,
+	)
+
+	return err
+}
+
+// NamedExecContext logging implementation of sqlx.Tx.NamedExecContext
+func (tx *Tx) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	begin := time.Now()
+
+	res, err := tx.inner.NamedExecContext(ctx, query, arg)
+
+	tx.db.log(tx.context(ctx), "NamedExecContext", err,
+		fieldDuration, time.Since(begin),
+		fieldQuery, query,
+		fieldArgs, arg,
+		resultAttr(res),
+	)
+
+	return res, err
+}
+
+// QueryRowxContext logging implementation of sqlx.Tx.QueryRowxContext
+func (tx *Tx) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	begin := time.Now()
+
+	row := tx.inner.QueryRowxContext(ctx, query, args...)
+
+	tx.db.log(tx.context(ctx), "QueryRowxContext", nil,
+		fieldDuration, time.Since(begin),
+		fieldQuery, query,
+		fieldArgs, args,
+		fieldRows, row,
+	)
+
+	return row
+}
+
+// QueryxContext logging implementation of sqlx.Tx.QueryxContext
+func (tx *Tx) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	begin := time.Now()
+
+	rows, err := tx.inner.QueryxContext(ctx, query, args...)
+
+	tx.db.log(tx.context(ctx), "QueryxContext", err,
+		fieldDuration, time.Since(begin),
+		fieldQuery, query,
+		fieldArgs, args,
+		fieldRows, rows,
+	)
+
+	return rows, err
+}
+
+// Rebind logging implementation of sqlx.Tx.Rebind
+func (tx *Tx) Rebind(query string) string {
+	res := tx.inner.Rebind(query)
+
+	tx.db.log(tx.context(tx.ctx), "Rebind", nil,
+		fieldQuery, query,
+		fieldResult, res,
+	)
+
+	return res
+}
+
+// Rollback logging implementation of sqlx.Tx.Rollback, rolling back a
+// transaction already committed is not logged so it can be deferred
+func (tx *Tx) Rollback() error {
+	begin := time.Now()
+
+	err := tx.inner.Rollback()
+	if errors.Is(err, sql.ErrTxDone) {
+		return err
+	}
+
+	tx.db.log(tx.context(tx.ctx), "Rollback", err,
+		fieldDuration, time.Since(begin),
+	)
+
+	return err
+}
+
+// SelectContext logging implementation of sqlx.Tx.SelectContext
+func (tx *Tx) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	begin := time.Now()
+
+	err := tx.inner.SelectContext(ctx, dest, query, args...)
+
+	tx.db.log(tx.context(ctx), "SelectContext", err,
+		fieldDuration, time.Since(begin),
+		fieldQuery, query,
+		fieldArgs, args,
+		fieldDest, dest,
+		rowCountAttr(dest),
+	)
+
+	return err
+}
+
+// context marks ctx as running inside the transaction, so its records have
+// in_transaction set
+func (tx *Tx) context(ctx context.Context) context.Context {
+	return kryptonsqlx.ContextWithTx(ctx, tx)
+}
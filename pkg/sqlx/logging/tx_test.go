@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+)
+
+func TestBeginTransaction(t *testing.T) {
+	pool, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	buf := &bytes.Buffer{}
+
+	db := NewDB(
+		DBWithLogger(slog.New(slog.NewJSONHandler(buf, nil))),
+		DBWithInnerDB(pool),
+	)
+
+	ctx := context.Background()
+
+	tx, err := kryptonsqlx.BeginTransaction(ctx, db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "CREATE TABLE t (id INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// deferred rollbacks of committed transactions are not logged
+	tx.Rollback()
+
+	records := map[string]map[string]any{}
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		record := map[string]any{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+
+		records[record["method"].(string)] = record
+	}
+
+	if len(records) != 3 {
+		t.Errorf("logged %v, want BeginTransaction, ExecContext and Commit", buf)
+	}
+
+	for _, method := range []string{"ExecContext", "Commit"} {
+		record, ok := records[method]
+		if !ok {
+			t.Errorf("%s was not logged", method)
+			continue
+		}
+
+		if record["in_transaction"] != true {
+			t.Errorf("%s in_transaction = %v, want true", method, record["in_transaction"])
+		}
+	}
+}
+
+func TestOutsideTransaction(t *testing.T) {
+	pool, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	buf := &bytes.Buffer{}
+
+	db := NewDB(
+		DBWithLogger(slog.New(slog.NewJSONHandler(buf, nil))),
+		DBWithInnerDB(pool),
+	)
+
+	if _, err := db.ExecContext(context.Background(), "SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	record := map[string]any{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+
+	if record["in_transaction"] != false {
+		t.Errorf("in_transaction = %v, want false", record["in_transaction"])
+	}
+}
@@ -0,0 +1,345 @@
+// Package migrate applies versioned SQL migrations read from a file system,
+// usually an embed.FS, recording the applied migrations and their checksums
+// in a table of the database
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"time"
+
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/nop"
+)
+
+const defaultTable = "schema_migrations"
+
+var identifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+var (
+	// ErrModified returned when a migration was edited after it was applied
+	ErrModified = errors.New("migrate: applied migration was modified")
+	// ErrUnknownVersion returned when an applied migration, or the target of
+	// a migration, is not found in the file system
+	ErrUnknownVersion = errors.New("migrate: unknown version")
+	// ErrIrreversible returned when reverting a migration without a down
+	// migration
+	ErrIrreversible = errors.New("migrate: migration can not be reverted")
+)
+
+type MigratorOption func(*Migrator)
+
+// Migrator applies the migrations of a file system to a database, each
+// migration runs in its own transaction together with its record
+type Migrator struct {
+	db    kryptonsqlx.DB
+	fsys  fs.FS
+	table string
+	skip  map[uint64]bool
+}
+
+// Status the state of a migration, Missing reports an applied migration no
+// longer found in the file system, Skipped one left alone by the migrator
+type Status struct {
+	Version   uint64    `json:"version"`
+	Name      string    `json:"name"`
+	Applied   bool      `json:"applied"`
+	AppliedAt time.Time `json:"applied_at,omitempty"`
+	Modified  bool      `json:"modified"`
+	Missing   bool      `json:"missing"`
+	Skipped   bool      `json:"skipped"`
+}
+
+type record struct {
+	Version   uint64    `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// NewMigrator constructor for a migrator, requires the database and file
+// system to be set with MigratorWithDB and MigratorWithFS
+func NewMigrator(opts ...MigratorOption) *Migrator {
+	m := Migrator{
+		db:    nop.NewDB(),
+		table: defaultTable,
+		skip:  map[uint64]bool{},
+	}
+
+	for _, opt := range opts {
+		opt(&m)
+	}
+
+	return &m
+}
+
+// Up applies every migration not applied yet
+func (m *Migrator) Up(ctx context.Context) error {
+	migrations, applied, err := m.prepare(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(migrations) == 0 {
+		return nil
+	}
+
+	return m.to(ctx, migrations, applied, migrations[len(migrations)-1].Version)
+}
+
+// Down reverts the latest applied migration
+func (m *Migrator) Down(ctx context.Context) error {
+	migrations, applied, err := m.prepare(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if _, ok := applied[migrations[i].Version]; ok && !m.skip[migrations[i].Version] {
+			return m.down(ctx, migrations[i])
+		}
+	}
+
+	return nil
+}
+
+// To migrates the database to version, applying the migrations up to and
+// including it and reverting those after it, version 0 reverts every
+// migration
+func (m *Migrator) To(ctx context.Context, version uint64) error {
+	migrations, applied, err := m.prepare(ctx)
+	if err != nil {
+		return err
+	}
+
+	if version != 0 && !contains(migrations, version) {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	return m.to(ctx, migrations, applied, version)
+}
+
+// Status reports the state of every migration known to either the file
+// system or the database, it does not refuse modified or missing migrations
+// so they can be inspected
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.createTable(ctx); err != nil {
+		return nil, err
+	}
+
+	migrations, err := load(m.fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(migrations))
+
+	for _, migration := range migrations {
+		s := Status{
+			Version: migration.Version,
+			Name:    migration.Name,
+			Skipped: m.skip[migration.Version],
+		}
+
+		if r, ok := applied[migration.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = r.AppliedAt
+			s.Modified = r.Checksum != migration.Checksum
+		}
+
+		statuses = append(statuses, s)
+	}
+
+	for _, r := range applied {
+		if contains(migrations, r.Version) {
+			continue
+		}
+
+		statuses = append(statuses, Status{
+			Version:   r.Version,
+			Name:      r.Name,
+			Applied:   true,
+			AppliedAt: r.AppliedAt,
+			Missing:   true,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+func (m *Migrator) to(ctx context.Context, migrations []Migration, applied map[uint64]record, version uint64) error {
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if migrations[i].Version <= version {
+			break
+		}
+
+		if _, ok := applied[migrations[i].Version]; !ok || m.skip[migrations[i].Version] {
+			continue
+		}
+
+		if err := m.down(ctx, migrations[i]); err != nil {
+			return err
+		}
+	}
+
+	for _, migration := range migrations {
+		if migration.Version > version {
+			break
+		}
+
+		if _, ok := applied[migration.Version]; ok || m.skip[migration.Version] {
+			continue
+		}
+
+		if err := m.up(ctx, migration); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prepare creates the migrations table and loads the migrations, refusing
+// to continue when an applied migration was modified or is missing
+func (m *Migrator) prepare(ctx context.Context) ([]Migration, map[uint64]record, error) {
+	if err := m.createTable(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	migrations, err := load(m.fsys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byVersion := make(map[uint64]Migration, len(migrations))
+	for _, migration := range migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	for _, r := range applied {
+		migration, ok := byVersion[r.Version]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %d_%s is applied", ErrUnknownVersion, r.Version, r.Name)
+		}
+
+		if migration.Checksum != r.Checksum {
+			return nil, nil, fmt.Errorf("%w: %d_%s", ErrModified, r.Version, r.Name)
+		}
+	}
+
+	return migrations, applied, nil
+}
+
+func (m *Migrator) createTable(ctx context.Context) error {
+	if m.fsys == nil {
+		return errors.New("migrate: no file system to read migrations from")
+	}
+
+	if !identifier.MatchString(m.table) {
+		return fmt.Errorf("migrate: invalid table name %q", m.table)
+	}
+
+	_, err := m.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS `+m.table+` (
+			version    BIGINT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			checksum   TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`)
+
+	return err
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[uint64]record, error) {
+	records := []record{}
+
+	if err := m.db.SelectContext(ctx, &records, `
+		SELECT version, name, checksum, applied_at FROM `+m.table); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[uint64]record, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) up(ctx context.Context, migration Migration) error {
+	return m.inTx(ctx, migration, "up", func(tx kryptonsqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO `+m.table+` (version, name, checksum) VALUES ($1, $2, $3)`,
+			migration.Version, migration.Name, migration.Checksum,
+		)
+
+		return err
+	})
+}
+
+func (m *Migrator) down(ctx context.Context, migration Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("%w: %d_%s", ErrIrreversible, migration.Version, migration.Name)
+	}
+
+	return m.inTx(ctx, migration, "down", func(tx kryptonsqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			DELETE FROM `+m.table+` WHERE version = $1`,
+			migration.Version,
+		)
+
+		return err
+	})
+}
+
+// inTx runs fn in a transaction, rolling it back when fn fails so that a
+// migration is either applied and recorded or not at all
+func (m *Migrator) inTx(ctx context.Context, migration Migration, direction string, fn func(tx kryptonsqlx.Tx) error) error {
+	tx, err := kryptonsqlx.BeginTransaction(ctx, m.db, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migrate: %s %d_%s: %w", direction, migration.Version, migration.Name, err)
+	}
+
+	return tx.Commit()
+}
+
+func contains(migrations []Migration, version uint64) bool {
+	for _, migration := range migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+
+	return false
+}
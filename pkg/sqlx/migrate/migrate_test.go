@@ -0,0 +1,316 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	db, err := sqlx.Open("sqlite3", "file:"+t.TempDir()+"/migrate.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(`CREATE TABLE steps (step TEXT NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// migrations returns a file system of n migrations, each creating a table
+// and logging its up and down migrations in the steps table
+func migrations(n int) fstest.MapFS {
+	fsys := fstest.MapFS{}
+
+	for _, name := range []string{"0001_a", "0002_b", "0003_c", "0004_d"}[:n] {
+		table := name[len(name)-1:]
+
+		fsys[name+".up.sql"] = &fstest.MapFile{
+			Data: []byte(`CREATE TABLE ` + table + ` (id INTEGER); INSERT INTO steps VALUES ('up ` + table + `');`),
+		}
+		fsys[name+".down.sql"] = &fstest.MapFile{
+			Data: []byte(`DROP TABLE ` + table + `; INSERT INTO steps VALUES ('down ` + table + `');`),
+		}
+	}
+
+	return fsys
+}
+
+func steps(t *testing.T, db *sqlx.DB) []string {
+	t.Helper()
+
+	steps := []string{}
+
+	if err := db.Select(&steps, `SELECT step FROM steps ORDER BY rowid`); err != nil {
+		t.Fatal(err)
+	}
+
+	return steps
+}
+
+func applied(t *testing.T, m *Migrator) []uint64 {
+	t.Helper()
+
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions := []uint64{}
+	for _, s := range statuses {
+		if s.Applied {
+			versions = append(versions, s.Version)
+		}
+	}
+
+	return versions
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+
+	m := NewMigrator(MigratorWithDB(db), MigratorWithFS(migrations(3)))
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// applying again is a no-op
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Down(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.To(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.To(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"up a", "up b", "up c",
+		"down c",
+		"down b", "down a",
+		"up a", "up b",
+	}
+
+	if got := steps(t, db); !slices.Equal(got, want) {
+		t.Errorf("steps = %v, want %v", got, want)
+	}
+
+	if got := applied(t, m); !slices.Equal(got, []uint64{1, 2}) {
+		t.Errorf("applied = %v, want [1 2]", got)
+	}
+
+	if err := m.To(ctx, 5); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("To(5) error = %v, want %v", err, ErrUnknownVersion)
+	}
+}
+
+func TestMigratorModified(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+
+	fsys := migrations(2)
+
+	if err := NewMigrator(MigratorWithDB(db), MigratorWithFS(fsys)).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys["0001_a.up.sql"] = &fstest.MapFile{Data: []byte(`CREATE TABLE a (id INTEGER, name TEXT);`)}
+	fsys["0003_c.up.sql"] = &fstest.MapFile{Data: []byte(`CREATE TABLE c (id INTEGER);`)}
+
+	m := NewMigrator(MigratorWithDB(db), MigratorWithFS(fsys))
+
+	for name, migrate := range map[string]func(context.Context) error{
+		"Up":   m.Up,
+		"Down": m.Down,
+		"To":   func(ctx context.Context) error { return m.To(ctx, 0) },
+	} {
+		if err := migrate(ctx); !errors.Is(err, ErrModified) {
+			t.Errorf("%s() error = %v, want %v", name, err, ErrModified)
+		}
+	}
+
+	// nothing was migrated
+	if got := steps(t, db); !slices.Equal(got, []string{"up a", "up b"}) {
+		t.Errorf("steps = %v, want [up a up b]", got)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !statuses[0].Modified || statuses[1].Modified || statuses[2].Applied {
+		t.Errorf("Status() = %+v, want 1 modified and 3 pending", statuses)
+	}
+
+	// a down migration is checksummed too
+	fsys = migrations(2)
+	fsys["0002_b.down.sql"] = &fstest.MapFile{Data: []byte(`DROP TABLE b;`)}
+
+	if err := NewMigrator(MigratorWithDB(db), MigratorWithFS(fsys)).Up(ctx); !errors.Is(err, ErrModified) {
+		t.Errorf("Up() error = %v, want %v", err, ErrModified)
+	}
+
+	// an applied migration removed from the file system is refused too
+	fsys = migrations(1)
+
+	if err := NewMigrator(MigratorWithDB(db), MigratorWithFS(fsys)).Up(ctx); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Up() error = %v, want %v", err, ErrUnknownVersion)
+	}
+}
+
+func TestMigratorPartialFailure(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+
+	fsys := migrations(3)
+	good := fsys["0002_b.up.sql"]
+
+	// the tables are dropped and created before the statements failing
+	fsys["0003_c.down.sql"] = &fstest.MapFile{Data: []byte(`DROP TABLE c; DROP TABLE missing;`)}
+	fsys["0002_b.up.sql"] = &fstest.MapFile{Data: []byte(`CREATE TABLE b (id INTEGER); INSERT INTO missing VALUES (1);`)}
+
+	m := NewMigrator(MigratorWithDB(db), MigratorWithFS(fsys))
+
+	if err := m.Up(ctx); err == nil {
+		t.Fatal("Up() error = nil")
+	}
+
+	if got := applied(t, m); !slices.Equal(got, []uint64{1}) {
+		t.Errorf("applied = %v, want [1]", got)
+	}
+
+	// the failed migration was rolled back whole
+	exists := false
+
+	if err := db.Get(&exists, `SELECT COUNT(*) > 0 FROM sqlite_master WHERE name = 'b'`); err != nil {
+		t.Fatal(err)
+	}
+
+	if exists {
+		t.Error("table b of the failed migration exists")
+	}
+
+	// once fixed the remaining migrations are applied
+	fsys["0002_b.up.sql"] = good
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := steps(t, db); !slices.Equal(got, []string{"up a", "up b", "up c"}) {
+		t.Errorf("steps = %v, want [up a up b up c]", got)
+	}
+
+	// a failed down migration leaves the migration applied
+	if err := m.Down(ctx); err == nil {
+		t.Fatal("Down() error = nil")
+	}
+
+	if got := applied(t, m); !slices.Equal(got, []uint64{1, 2, 3}) {
+		t.Errorf("applied = %v, want [1 2 3]", got)
+	}
+}
+
+func TestMigratorIrreversible(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+
+	fsys := migrations(2)
+	delete(fsys, "0002_b.down.sql")
+
+	m := NewMigrator(MigratorWithDB(db), MigratorWithFS(fsys))
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Down(ctx); !errors.Is(err, ErrIrreversible) {
+		t.Errorf("Down() error = %v, want %v", err, ErrIrreversible)
+	}
+}
+
+func TestMigratorSkip(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+
+	fsys := migrations(3)
+
+	m := NewMigrator(MigratorWithDB(db), MigratorWithFS(fsys), MigratorWithSkip(2))
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := applied(t, m); !slices.Equal(got, []uint64{1, 3}) {
+		t.Errorf("applied = %v, want [1 3]", got)
+	}
+
+	// a migrator not skipping it applies it out of order
+	if err := NewMigrator(MigratorWithDB(db), MigratorWithFS(fsys)).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// and a skipping migrator leaves it applied
+	if err := m.To(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"up a", "up c", "up b", "down c"}
+	if got := steps(t, db); !slices.Equal(got, want) {
+		t.Errorf("steps = %v, want %v", got, want)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"version zero", fstest.MapFS{"0000_a.up.sql": {Data: []byte("SELECT 1")}}},
+		{"no up", fstest.MapFS{"0001_a.down.sql": {Data: []byte("SELECT 1")}}},
+		{"names differ", fstest.MapFS{
+			"0001_a.up.sql": {Data: []byte("SELECT 1")},
+			"0001_b.up.sql": {Data: []byte("SELECT 1")},
+		}},
+	}
+
+	for _, tt := range tests {
+		if _, err := load(tt.fsys); err == nil {
+			t.Errorf("%s: load() error = nil", tt.name)
+		}
+	}
+
+	// other files are ignored
+	migrations, err := load(fstest.MapFS{
+		"0002_b.up.sql": {Data: []byte("SELECT 2")},
+		"0001_a.up.sql": {Data: []byte("SELECT 1")},
+		"README.md":     {Data: []byte("migrations")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrations) != 2 || migrations[0].Version != 1 || migrations[1].Version != 2 {
+		t.Errorf("load() = %+v, want versions 1 and 2", migrations)
+	}
+}
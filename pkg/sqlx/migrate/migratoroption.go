@@ -0,0 +1,40 @@
+package migrate
+
+import (
+	"io/fs"
+
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+)
+
+func MigratorWithDB(db sqlx.DB) MigratorOption {
+	return func(m *Migrator) {
+		m.db = db
+	}
+}
+
+// MigratorWithFS sets the file system the migrations are read from, the
+// migration files are expected at its root, see fs.Sub for nested directories
+func MigratorWithFS(fsys fs.FS) MigratorOption {
+	return func(m *Migrator) {
+		m.fsys = fsys
+	}
+}
+
+// MigratorWithTable sets the table recording the applied migrations,
+// defaults to schema_migrations
+func MigratorWithTable(table string) MigratorOption {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// MigratorWithSkip leaves the migrations with the versions alone, neither
+// applied nor reverted, for migrations the database can not run, they stay
+// pending and are applied out of order by a migrator not skipping them
+func MigratorWithSkip(versions ...uint64) MigratorOption {
+	return func(m *Migrator) {
+		for _, version := range versions {
+			m.skip[version] = true
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package nop
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+)
+
+type Tx struct{}
+
+// BeginTransaction no-operation implementation of sqlx.TxBeginner
+func (*Nop) BeginTransaction(ctx context.Context, opts *sql.TxOptions) (kryptonsqlx.Tx, error) {
+	return &Tx{}, nil
+}
+
+// BindNamed no-operation implementation of sqlx.Tx.BindNamed
+func (*Tx) BindNamed(query string, arg interface{}) (string, []interface{}, error) {
+	return "", nil, nil
+}
+
+// Commit no-operation implementation of sqlx.Tx.Commit
+func (*Tx) Commit() error {
+	return nil
+}
+
+// DriverName no-operation implementation of sqlx.Tx.DriverName
+func (*Tx) DriverName() string {
+	return ""
+}
+
+// ExecContext no-operation implementation of sqlx.Tx.ExecContext
+func (*Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return nil, nil
+}
+
+// GetContext no-operation implementation of sqlx.Tx.GetContext
+func (*Tx) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return nil
+}
+
+// NamedExecContext no-operation implementation of sqlx.Tx.NamedExecContext
+func (*Tx) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	return nil, nil
+}
+
+// QueryRowxContext no-operation implementation of sqlx.Tx.QueryRowxContext
+func (*Tx) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	return nil
+}
+
+// QueryxContext no-operation implementation of sqlx.Tx.QueryxContext
+func (*Tx) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	return nil, nil
+}
+
+// Rebind no-operation implementation of sqlx.Tx.Rebind
+func (*Tx) Rebind(query string) string {
+	return ""
+}
+
+// Rollback no-operation implementation of sqlx.Tx.Rollback
+func (*Tx) Rollback() error {
+	return nil
+}
+
+// SelectContext no-operation implementation of sqlx.Tx.SelectContext
+func (*Tx) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return nil
+}
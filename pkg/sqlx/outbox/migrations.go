@@ -0,0 +1,40 @@
+package outbox
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// MigrationsTable the table the migrate package records the applied
+// migrations of the outbox in, apart from those of the schema it is used with
+const MigrationsTable = "outbox_schema_migrations"
+
+var (
+	//go:embed migrations/sqlite/*.sql
+	sqliteMigrations embed.FS
+	//go:embed migrations/postgres/*.sql
+	postgresMigrations embed.FS
+)
+
+// SQLiteMigrations the schema migrations creating the default outbox table
+// in SQLite, to be applied with the migrate package recording them in
+// MigrationsTable
+func SQLiteMigrations() fs.FS {
+	return sub(sqliteMigrations, "migrations/sqlite")
+}
+
+// PostgresMigrations the schema migrations creating the default outbox table
+// in PostgreSQL, to be applied with the migrate package recording them in
+// MigrationsTable
+func PostgresMigrations() fs.FS {
+	return sub(postgresMigrations, "migrations/postgres")
+}
+
+func sub(fsys embed.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+
+	return sub
+}
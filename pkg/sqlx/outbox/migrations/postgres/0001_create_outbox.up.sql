@@ -0,0 +1,17 @@
+-- available_at delays the next attempt of a message which failed
+CREATE TABLE outbox (
+    id           BIGSERIAL PRIMARY KEY,
+    topic        TEXT NOT NULL,
+    key          TEXT NOT NULL,
+    payload      TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL,
+    available_at TIMESTAMPTZ NOT NULL,
+    attempts     INTEGER NOT NULL DEFAULT 0,
+    last_error   TEXT,
+    delivered_at TIMESTAMPTZ,
+    failed_at    TIMESTAMPTZ
+);
+
+CREATE INDEX outbox_pending_idx ON outbox (available_at) WHERE delivered_at IS NULL AND failed_at IS NULL;
+
+CREATE INDEX outbox_key_idx ON outbox (key, id) WHERE delivered_at IS NULL AND failed_at IS NULL;
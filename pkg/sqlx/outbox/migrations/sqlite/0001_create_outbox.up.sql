@@ -0,0 +1,19 @@
+-- available_at delays the next attempt of a message which failed, the table
+-- may have been created by the schema the outbox was part of before it had
+-- migrations of its own
+CREATE TABLE IF NOT EXISTS outbox (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    topic        TEXT NOT NULL,
+    key          TEXT NOT NULL,
+    payload      TEXT NOT NULL,
+    created_at   TIMESTAMP NOT NULL,
+    available_at TIMESTAMP NOT NULL,
+    attempts     INTEGER NOT NULL DEFAULT 0,
+    last_error   TEXT,
+    delivered_at TIMESTAMP,
+    failed_at    TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (available_at) WHERE delivered_at IS NULL AND failed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS outbox_key_idx ON outbox (key, id) WHERE delivered_at IS NULL AND failed_at IS NULL;
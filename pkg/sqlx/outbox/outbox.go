@@ -0,0 +1,214 @@
+// Package outbox publishes messages written in the same transaction as the
+// data they describe, so a message is published if and only if its
+// transaction commits. Messages are enqueued into an outbox table and
+// delivered by a Relay, at least once and in order among the messages sharing
+// a key. The table is created by the migrations of SQLiteMigrations and
+// PostgresMigrations, other tables are expected to have the columns
+//
+//	id           INTEGER PRIMARY KEY AUTOINCREMENT
+//	topic        TEXT NOT NULL
+//	key          TEXT NOT NULL
+//	payload      TEXT NOT NULL
+//	created_at   TIMESTAMP NOT NULL
+//	available_at TIMESTAMP NOT NULL
+//	attempts     INTEGER NOT NULL DEFAULT 0
+//	last_error   TEXT
+//	delivered_at TIMESTAMP
+//	failed_at    TIMESTAMP
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/nop"
+)
+
+const defaultTable = "outbox"
+
+var identifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+type OutboxOption func(*Outbox)
+
+// Outbox the table messages are enqueued into and relayed from
+type Outbox struct {
+	db    kryptonsqlx.DB
+	table string
+}
+
+// Message a message of the outbox, Key orders the messages sharing it, an
+// empty key leaves the message unordered, the Payload is a JSON document
+type Message struct {
+	ID        int64           `db:"id" json:"id"`
+	Topic     string          `db:"topic" json:"topic"`
+	Key       string          `db:"key" json:"key,omitempty"`
+	Payload   json.RawMessage `db:"payload" json:"payload"`
+	Attempts  int             `db:"attempts" json:"attempts"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+// Stats the backlog of an outbox, OldestPending is the creation time of the
+// oldest message waiting for delivery, zero when none is
+type Stats struct {
+	Pending       int
+	Failed        int
+	OldestPending time.Time
+}
+
+// NewOutbox constructor for an outbox, the database must be set with
+// OutboxWithDB for the relay and the statistics
+func NewOutbox(opts ...OutboxOption) *Outbox {
+	o := Outbox{
+		db:    nop.NewDB(),
+		table: defaultTable,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &o
+}
+
+// NewMessage creates a message with v encoded as its payload
+func NewMessage(topic, key string, v any) (Message, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return Message{}, fmt.Errorf("outbox: encoding payload of %s: %w", topic, err)
+	}
+
+	return Message{
+		Topic:   topic,
+		Key:     key,
+		Payload: payload,
+	}, nil
+}
+
+// Enqueue writes the messages to the outbox with tx, the transaction writing
+// the data the messages describe
+func (o *Outbox) Enqueue(ctx context.Context, tx sqlx.ExecerContext, messages ...Message) error {
+	if err := o.validate(); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	for _, m := range messages {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO `+o.table+` (topic, key, payload, created_at, available_at)
+			VALUES ($1, $2, $3, $4, $4)`,
+			m.Topic, m.Key, string(m.Payload), now,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Stats reads the backlog of the outbox
+func (o *Outbox) Stats(ctx context.Context) (Stats, error) {
+	if err := o.validate(); err != nil {
+		return Stats{}, err
+	}
+
+	stats := Stats{}
+
+	if err := o.db.QueryRowxContext(ctx, `
+		SELECT
+			COALESCE(SUM(CASE WHEN failed_at IS NULL THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN failed_at IS NULL THEN 0 ELSE 1 END), 0)
+		FROM `+o.table+` WHERE delivered_at IS NULL`,
+	).Scan(&stats.Pending, &stats.Failed); err != nil {
+		return Stats{}, err
+	}
+
+	if stats.Pending == 0 {
+		return stats, nil
+	}
+
+	// an aggregate would lose the type of the column
+	if err := o.db.GetContext(ctx, &stats.OldestPending, `
+		SELECT created_at FROM `+o.table+`
+		WHERE delivered_at IS NULL AND failed_at IS NULL ORDER BY id LIMIT 1`,
+	); err != nil {
+		return Stats{}, err
+	}
+
+	return stats, nil
+}
+
+// row a message as read, drivers return the payload column as text
+type row struct {
+	Message
+	Payload string `db:"payload"`
+}
+
+// pending reads up to limit messages available at now, a message waits for
+// the earlier messages of its key which are neither delivered nor failed
+func (o *Outbox) pending(ctx context.Context, now time.Time, limit int) ([]Message, error) {
+	rows := []row{}
+
+	err := o.db.SelectContext(ctx, &rows, `
+		SELECT id, topic, key, payload, attempts, created_at FROM `+o.table+` m
+		WHERE delivered_at IS NULL AND failed_at IS NULL AND available_at <= $1
+		AND (key = '' OR NOT EXISTS (
+			SELECT 1 FROM `+o.table+` e
+			WHERE e.key = m.key AND e.id < m.id AND e.delivered_at IS NULL AND e.failed_at IS NULL
+		))
+		ORDER BY id LIMIT $2`,
+		now, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]Message, len(rows))
+
+	for i, r := range rows {
+		messages[i] = r.Message
+		messages[i].Payload = json.RawMessage(r.Payload)
+	}
+
+	return messages, nil
+}
+
+func (o *Outbox) delivered(ctx context.Context, id int64, now time.Time) error {
+	_, err := o.db.ExecContext(ctx, `
+		UPDATE `+o.table+` SET delivered_at = $1, attempts = attempts + 1 WHERE id = $2`,
+		now, id,
+	)
+
+	return err
+}
+
+// retry records a failed attempt, the message is available again at next or
+// failed for good when next is zero
+func (o *Outbox) retry(ctx context.Context, id int64, cause error, now, next time.Time) error {
+	failedAt := any(nil)
+	if next.IsZero() {
+		next, failedAt = now, now
+	}
+
+	_, err := o.db.ExecContext(ctx, `
+		UPDATE `+o.table+` SET attempts = attempts + 1, last_error = $1, available_at = $2, failed_at = $3
+		WHERE id = $4`,
+		cause.Error(), next, failedAt, id,
+	)
+
+	return err
+}
+
+func (o *Outbox) validate() error {
+	if !identifier.MatchString(o.table) {
+		return fmt.Errorf("outbox: invalid table name %q", o.table)
+	}
+
+	return nil
+}
@@ -0,0 +1,316 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/migrate"
+)
+
+func newDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	db, err := sqlx.Open("sqlite3", "file:"+t.TempDir()+"/outbox.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := migrate.NewMigrator(
+		migrate.MigratorWithDB(db),
+		migrate.MigratorWithFS(SQLiteMigrations()),
+		migrate.MigratorWithTable(MigrationsTable),
+	).Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// recorder a publisher recording the payloads published, failing the
+// attempts for which fail returns an error
+type recorder struct {
+	mu        sync.Mutex
+	published []string
+	attempts  int
+	fail      func(attempt int, m Message) error
+}
+
+func (p *recorder) Publish(_ context.Context, m Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.attempts++
+
+	if p.fail != nil {
+		if err := p.fail(p.attempts, m); err != nil {
+			return err
+		}
+	}
+
+	p.published = append(p.published, string(m.Payload))
+
+	return nil
+}
+
+func enqueue(t *testing.T, db *sqlx.DB, o *Outbox, commit bool, messages ...Message) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	if err := o.Enqueue(ctx, tx, messages...); err != nil {
+		t.Fatal(err)
+	}
+
+	if commit {
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func message(t *testing.T, key string, v any) Message {
+	t.Helper()
+
+	m, err := NewMessage("test", key, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func TestRelayRolledBack(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+
+	o := NewOutbox(OutboxWithDB(db))
+	p := &recorder{}
+	r := NewRelay(RelayWithOutbox(o), RelayWithPublisher(p))
+
+	enqueue(t, db, o, false, message(t, "a", 1))
+	enqueue(t, db, o, true, message(t, "a", 2))
+	enqueue(t, db, o, false, message(t, "", 3), message(t, "b", 4))
+
+	for range 2 {
+		if _, err := r.relay(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := []string{"2"}; !slices.Equal(p.published, want) {
+		t.Errorf("published %v, want %v", p.published, want)
+	}
+
+	stats, err := o.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Pending != 0 || stats.Failed != 0 {
+		t.Errorf("Stats() = %+v, want nothing pending", stats)
+	}
+}
+
+func TestRelayRetry(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+
+	o := NewOutbox(OutboxWithDB(db))
+	p := &recorder{
+		fail: func(attempt int, m Message) error {
+			if attempt == 1 {
+				return errors.New("broker unavailable")
+			}
+
+			return nil
+		},
+	}
+	r := NewRelay(
+		RelayWithOutbox(o),
+		RelayWithPublisher(p),
+		RelayWithBackoff(10*time.Millisecond, 10*time.Millisecond),
+	)
+
+	enqueue(t, db, o, true, message(t, "a", 1), message(t, "a", 2), message(t, "b", 3))
+
+	if _, err := r.relay(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the messages of the key of the failed one wait for it
+	if want := []string{"3"}; !slices.Equal(p.published, want) {
+		t.Errorf("published %v, want %v", p.published, want)
+	}
+
+	// the failed message is not retried before its backoff elapsed
+	if _, err := r.relay(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"3"}; !slices.Equal(p.published, want) {
+		t.Errorf("published %v before the backoff, want %v", p.published, want)
+	}
+
+	time.Sleep(15 * time.Millisecond)
+
+	for range 2 {
+		if _, err := r.relay(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := []string{"3", "1", "2"}; !slices.Equal(p.published, want) {
+		t.Errorf("published %v, want %v", p.published, want)
+	}
+
+	if want := (RelayStats{Delivered: 3, Retried: 1}); r.Stats() != want {
+		t.Errorf("Stats() = %+v, want %+v", r.Stats(), want)
+	}
+
+	attempts, lastError := 0, ""
+
+	if err := db.QueryRowx(`SELECT attempts, last_error FROM outbox WHERE payload = '1'`).Scan(&attempts, &lastError); err != nil {
+		t.Fatal(err)
+	}
+
+	if attempts != 2 || lastError != "broker unavailable" {
+		t.Errorf("attempts = %d and last error = %q, want 2 and broker unavailable", attempts, lastError)
+	}
+}
+
+func TestRelayMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+
+	o := NewOutbox(OutboxWithDB(db))
+	p := &recorder{
+		fail: func(int, Message) error {
+			return errors.New("rejected")
+		},
+	}
+	r := NewRelay(
+		RelayWithOutbox(o),
+		RelayWithPublisher(p),
+		RelayWithMaxAttempts(2),
+		RelayWithBackoff(time.Millisecond, time.Millisecond),
+	)
+
+	enqueue(t, db, o, true, message(t, "a", 1), message(t, "a", 2))
+
+	for range 3 {
+		if _, err := r.relay(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	// once the first message failed for good the next of its key is tried
+	if want := (RelayStats{Retried: 2, Failed: 1}); r.Stats() != want {
+		t.Errorf("Stats() = %+v, want %+v", r.Stats(), want)
+	}
+
+	stats, err := o.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Pending != 1 || stats.Failed != 1 {
+		t.Errorf("Stats() = %+v, want 1 pending and 1 failed", stats)
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sqlx.Open("sqlite3", "file:"+t.TempDir()+"/outbox.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// a table created by the schema the outbox was part of is kept with its
+	// messages
+	if _, err := db.Exec(`
+		CREATE TABLE outbox (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			topic        TEXT NOT NULL,
+			key          TEXT NOT NULL,
+			payload      TEXT NOT NULL,
+			created_at   TIMESTAMP NOT NULL,
+			available_at TIMESTAMP NOT NULL,
+			attempts     INTEGER NOT NULL DEFAULT 0,
+			last_error   TEXT,
+			delivered_at TIMESTAMP,
+			failed_at    TIMESTAMP
+		);
+		INSERT INTO outbox (topic, key, payload, created_at, available_at) VALUES ('t', '', '{}', 0, 0)`,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	m := migrate.NewMigrator(
+		migrate.MigratorWithDB(db),
+		migrate.MigratorWithFS(SQLiteMigrations()),
+		migrate.MigratorWithTable(MigrationsTable),
+	)
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, indexes := 0, 0
+
+	if err := db.Get(&messages, `SELECT COUNT(*) FROM outbox`); err != nil {
+		t.Fatal(err)
+	}
+
+	if messages != 1 {
+		t.Errorf("%d messages after the migrations, want 1", messages)
+	}
+
+	if err := db.Get(&indexes, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = 'outbox' AND name LIKE 'outbox_%_idx'`); err != nil {
+		t.Fatal(err)
+	}
+
+	if indexes != 2 {
+		t.Errorf("%d indexes of the outbox, want 2", indexes)
+	}
+
+	if err := m.Down(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tables := 0
+
+	if err := db.Get(&tables, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'outbox'`); err != nil {
+		t.Fatal(err)
+	}
+
+	if tables != 0 {
+		t.Errorf("the outbox table remains after the migrations were reverted")
+	}
+
+	// the migrations of both dialects are embedded
+	for name, fsys := range map[string]fs.FS{"sqlite": SQLiteMigrations(), "postgres": PostgresMigrations()} {
+		files, err := fs.Glob(fsys, "*.sql")
+		if err != nil || len(files) != 2 {
+			t.Errorf("%s migrations = %v %v, want an up and a down migration", name, files, err)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package outbox
+
+import (
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+)
+
+func OutboxWithDB(db sqlx.DB) OutboxOption {
+	return func(o *Outbox) {
+		o.db = db
+	}
+}
+
+// OutboxWithTable sets the table of the outbox, defaults to outbox
+func OutboxWithTable(table string) OutboxOption {
+	return func(o *Outbox) {
+		o.table = table
+	}
+}
@@ -0,0 +1,105 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Publisher delivers the messages of an outbox, a message is marked delivered
+// once Publish returns without error and retried otherwise, so publishers
+// must tolerate receiving a message more than once
+type Publisher interface {
+	Publish(ctx context.Context, m Message) error
+}
+
+// PublisherFunc adapts a function to a Publisher
+type PublisherFunc func(ctx context.Context, m Message) error
+
+// Publish implementation of Publisher
+func (f PublisherFunc) Publish(ctx context.Context, m Message) error {
+	return f(ctx, m)
+}
+
+// InProcess Publisher dispatching messages to the handlers subscribed to
+// their topic within the process, a message fails when one of its handlers
+// fails and is then delivered again to every handler
+type InProcess struct {
+	mu       sync.RWMutex
+	handlers map[string][]PublisherFunc
+}
+
+// NewInProcess constructor for an in-process publisher without subscribers,
+// messages of topics nobody subscribed to are dropped
+func NewInProcess() *InProcess {
+	return &InProcess{
+		handlers: map[string][]PublisherFunc{},
+	}
+}
+
+// Subscribe registers a handler for the messages of the topic
+func (p *InProcess) Subscribe(topic string, handler PublisherFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.handlers[topic] = append(p.handlers[topic], handler)
+}
+
+// Publish implementation of Publisher
+func (p *InProcess) Publish(ctx context.Context, m Message) error {
+	p.mu.RLock()
+	handlers := p.handlers[m.Topic]
+	p.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// File Publisher appending messages to a file as JSON lines, each message is
+// synced to disk before it is reported delivered
+type File struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFile constructor for a file publisher, the file is created when it does
+// not exist
+func NewFile(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("outbox: opening %s: %w", path, err)
+	}
+
+	return &File{
+		file: f,
+	}, nil
+}
+
+// Publish implementation of Publisher
+func (p *File) Publish(_ context.Context, m Message) error {
+	line, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := p.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	return p.file.Sync()
+}
+
+// Close closes the file
+func (p *File) Close() error {
+	return p.file.Close()
+}
@@ -0,0 +1,202 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"github.com/olireadcopper/sqlxprototype/pkg/telemetry/logging"
+)
+
+const (
+	defaultPollInterval = time.Second
+	defaultBatchSize    = 100
+	defaultMaxAttempts  = 10
+	defaultMinBackoff   = time.Second
+	defaultMaxBackoff   = 5 * time.Minute
+)
+
+type RelayOption func(*Relay)
+
+// Relay delivers the messages of an outbox to a publisher, one relay runs per
+// outbox as concurrent relays would deliver the same messages
+type Relay struct {
+	outbox      *Outbox
+	publisher   Publisher
+	interval    time.Duration
+	batch       int
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	logger      *slog.Logger
+
+	delivered atomic.Uint64
+	retried   atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// RelayStats the messages handled by a relay since it was created
+type RelayStats struct {
+	Delivered uint64
+	Retried   uint64
+	Failed    uint64
+}
+
+// NewRelay constructor for a relay, the outbox must be set with
+// RelayWithOutbox
+func NewRelay(opts ...RelayOption) *Relay {
+	r := &Relay{
+		outbox:      NewOutbox(),
+		publisher:   NewInProcess(),
+		interval:    defaultPollInterval,
+		batch:       defaultBatchSize,
+		maxAttempts: defaultMaxAttempts,
+		minBackoff:  defaultMinBackoff,
+		maxBackoff:  defaultMaxBackoff,
+		logger:      logging.NopLogger,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	// the relay would spin without a batch or an interval
+	if r.batch <= 0 {
+		r.batch = defaultBatchSize
+	}
+
+	if r.interval <= 0 {
+		r.interval = defaultPollInterval
+	}
+
+	return r
+}
+
+// Run relays the messages of the outbox until ctx is done, failures to read
+// or update the outbox are logged and retried at the next poll
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		n, err := r.relay(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger.ErrorContext(ctx, err.Error())
+		}
+
+		// a full batch is followed by more messages
+		if err == nil && n == r.batch {
+			continue
+		}
+
+		t := time.NewTimer(r.interval)
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil
+		}
+	}
+}
+
+// Stats reads the messages handled by the relay
+func (r *Relay) Stats() RelayStats {
+	return RelayStats{
+		Delivered: r.delivered.Load(),
+		Retried:   r.retried.Load(),
+		Failed:    r.failed.Load(),
+	}
+}
+
+// Outbox the outbox the relay delivers
+func (r *Relay) Outbox() *Outbox {
+	return r.outbox
+}
+
+// relay publishes a batch of pending messages, returning their number, the
+// messages following a failed one of the same key wait for the next batch
+func (r *Relay) relay(ctx context.Context) (int, error) {
+	if err := r.outbox.validate(); err != nil {
+		return 0, err
+	}
+
+	messages, err := r.outbox.pending(ctx, time.Now().UTC(), r.batch)
+	if err != nil {
+		return 0, err
+	}
+
+	blocked := map[string]bool{}
+
+	for _, m := range messages {
+		if m.Key != "" && blocked[m.Key] {
+			continue
+		}
+
+		if err := r.publisher.Publish(ctx, m); err != nil {
+			if ctx.Err() != nil {
+				return len(messages), ctx.Err()
+			}
+
+			blocked[m.Key] = true
+
+			if err := r.fail(ctx, m, err); err != nil {
+				return len(messages), err
+			}
+
+			continue
+		}
+
+		if err := r.outbox.delivered(ctx, m.ID, time.Now().UTC()); err != nil {
+			return len(messages), err
+		}
+
+		r.delivered.Add(1)
+	}
+
+	return len(messages), nil
+}
+
+// fail schedules the next attempt of the message after a backoff growing
+// exponentially with its attempts, the message fails for good once it
+// reaches the maximum attempts
+func (r *Relay) fail(ctx context.Context, m Message, cause error) error {
+	attempts := m.Attempts + 1
+	now := time.Now().UTC()
+	next := time.Time{}
+
+	if r.maxAttempts <= 0 || attempts < r.maxAttempts {
+		next = now.Add(r.backoff(attempts))
+	}
+
+	r.logger.WarnContext(ctx, cause.Error(), "message", m.ID, "topic", m.Topic, "attempts", attempts, "final", next.IsZero())
+
+	if err := r.outbox.retry(ctx, m.ID, cause, now, next); err != nil {
+		return err
+	}
+
+	if next.IsZero() {
+		r.failed.Add(1)
+	} else {
+		r.retried.Add(1)
+	}
+
+	return nil
+}
+
+// backoff the wait before the next attempt, doubling with each attempt up to
+// the maximum, jittered by up to a fifth so failed messages spread out
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := r.minBackoff
+
+	for i := 1; i < attempts && d < r.maxBackoff; i++ {
+		d *= 2
+	}
+
+	d = min(d, r.maxBackoff)
+
+	if jitter := int64(d / 5); jitter > 0 {
+		d += time.Duration(rand.Int64N(jitter))
+	}
+
+	return d
+}
@@ -0,0 +1,63 @@
+package outbox
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/olireadcopper/sqlxprototype/pkg/telemetry/logging"
+)
+
+func RelayWithOutbox(o *Outbox) RelayOption {
+	return func(r *Relay) {
+		r.outbox = o
+	}
+}
+
+// RelayWithPublisher sets the publisher messages are delivered to, defaults
+// to an InProcess publisher without subscribers
+func RelayWithPublisher(p Publisher) RelayOption {
+	return func(r *Relay) {
+		r.publisher = p
+	}
+}
+
+// RelayWithPollInterval sets how long the relay waits for new messages once
+// it delivered every pending message
+func RelayWithPollInterval(d time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.interval = d
+	}
+}
+
+// RelayWithBatchSize sets the most messages read from the outbox at once
+func RelayWithBatchSize(n int) RelayOption {
+	return func(r *Relay) {
+		r.batch = n
+	}
+}
+
+// RelayWithMaxAttempts sets the attempts after which a message fails for
+// good, 0 retries messages forever, defaults to 10
+func RelayWithMaxAttempts(n int) RelayOption {
+	return func(r *Relay) {
+		r.maxAttempts = n
+	}
+}
+
+// RelayWithBackoff sets the wait before the second attempt of a message,
+// doubled for every attempt after it up to ceiling, defaults to a second up
+// to five minutes
+func RelayWithBackoff(initial, ceiling time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.minBackoff = initial
+		r.maxBackoff = ceiling
+	}
+}
+
+func RelayWithLogger(l *slog.Logger) RelayOption {
+	return func(r *Relay) {
+		r.logger = l.With(
+			logging.FieldComponent, "outbox",
+		)
+	}
+}
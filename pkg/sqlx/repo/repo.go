@@ -0,0 +1,452 @@
+// Package repo provides a generic repository for structs mapped with db tags,
+// the way sqlx maps them. The table and its columns are derived from the
+// struct, so an entity is read and written without hand-written queries, and
+// the SQL of any single operation can be replaced by a named query binding
+// the fields of the entity as :column, for writes the database fills or
+// rules the entity needs
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+
+	kryptonsqlx "github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/dialect"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/filter"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/nop"
+)
+
+// ErrNotFound returned when no entity has the key, or the key is outside the
+// scope of the repository, it wraps sql.ErrNoRows
+var ErrNotFound = fmt.Errorf("repo: entity not found: %w", sql.ErrNoRows)
+
+var mapper = reflectx.NewMapperFunc("db", strings.ToLower)
+
+// Operation an operation whose SQL can be replaced with RepositoryWithQuery
+type Operation string
+
+const (
+	// OperationCreate binds the entity, the query returns the created row
+	OperationCreate Operation = "create"
+	// OperationGet binds the key, the query returns the row
+	OperationGet Operation = "get"
+	// OperationUpdate binds the entity, the query affects the updated row
+	OperationUpdate Operation = "update"
+	// OperationDelete binds the key, the query affects the deleted row
+	OperationDelete Operation = "delete"
+	// OperationUpsert binds the entity, the query returns the written row
+	OperationUpsert Operation = "upsert"
+)
+
+// ListQuery selects the entities listed, ordered by Sort then by the key, a
+// zero Limit lists all of them
+type ListQuery struct {
+	Filter filter.Expr
+	Sort   []filter.Sort
+	Limit  uint
+	Offset uint
+}
+
+type RepositoryOption func(*config)
+
+// config the settings of a repository, shared by every entity type
+type config struct {
+	db        kryptonsqlx.DB
+	dialect   dialect.Dialect
+	table     string
+	key       string
+	generated []string
+	conflict  []string
+	scope     []string
+	queries   map[Operation]string
+	filters   *filter.Compiler
+}
+
+// Repository reads and writes the entities T in a table, T is a struct whose
+// fields are mapped to columns by their db tags, fields tagged db:"-",
+// nested structs and slices are not columns
+type Repository[T any] struct {
+	config
+	tx      kryptonsqlx.Tx
+	columns []string
+}
+
+// queryer the calls the repository makes, on the database or in a
+// transaction
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+}
+
+// NewRepository constructor for a repository of the entities T, the table
+// defaults to the name of T in snake case and the key to the id column,
+// which the database generates
+func NewRepository[T any](opts ...RepositoryOption) *Repository[T] {
+	t := reflect.TypeFor[T]()
+
+	r := Repository[T]{
+		config: config{
+			db:      nop.NewDB(),
+			dialect: dialect.NewSQLite(),
+			table:   snake(t.Name()),
+			key:     "id",
+			queries: map[Operation]string{},
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&r.config)
+	}
+
+	columns := filter.ColumnsOf(reflect.Zero(t).Interface())
+
+	// the columns in the order of the fields, the filter only knows their set
+	for _, field := range mapper.TypeMap(t).Index {
+		if _, ok := columns[field.Path]; ok {
+			r.columns = append(r.columns, field.Path)
+		}
+	}
+
+	if r.generated == nil {
+		r.generated = []string{r.key}
+	}
+
+	if r.conflict == nil {
+		r.conflict = []string{r.key}
+	}
+
+	if r.filters == nil {
+		r.filters = filter.NewCompiler(
+			filter.CompilerWithColumns(columns),
+			filter.CompilerWithValuer(r.dialect.Value),
+		)
+	}
+
+	defaults := map[Operation]string{
+		OperationCreate: r.createQuery(),
+		OperationGet:    r.getQuery(),
+		OperationUpdate: r.updateQuery(),
+		OperationDelete: r.deleteQuery(),
+		OperationUpsert: r.upsertQuery(),
+	}
+
+	queries := make(map[Operation]string, len(defaults))
+
+	for op, q := range defaults {
+		if custom, ok := r.queries[op]; ok {
+			q = custom
+		}
+
+		queries[op] = q
+	}
+
+	r.queries = queries
+
+	return &r
+}
+
+// Table the table of the entities
+func (r *Repository[T]) Table() string {
+	return r.table
+}
+
+// Columns the columns of the entities, in the order of the fields of T
+func (r *Repository[T]) Columns() []string {
+	return append([]string(nil), r.columns...)
+}
+
+// Tx returns a copy of the repository running its queries in tx, so entities
+// are written along with the other statements of the transaction
+func (r *Repository[T]) Tx(tx kryptonsqlx.Tx) *Repository[T] {
+	c := *r
+	c.tx = tx
+
+	return &c
+}
+
+// Create inserts v and returns the entity as created, with the columns the
+// database generated
+func (r *Repository[T]) Create(ctx context.Context, v T) (T, error) {
+	return r.write(ctx, OperationCreate, v)
+}
+
+// Get returns the entity with the key, ErrNotFound when there is none
+func (r *Repository[T]) Get(ctx context.Context, key any) (T, error) {
+	var v T
+
+	q, args, err := r.bind(r.queries[OperationGet], map[string]any{r.key: key})
+	if err != nil {
+		return v, err
+	}
+
+	if err := r.queryer().GetContext(ctx, &v, q, args...); err != nil {
+		return v, notFound(err)
+	}
+
+	return v, nil
+}
+
+// List returns the page of the entities matching the filter of the query,
+// only the columns of T can be filtered and sorted on
+func (r *Repository[T]) List(ctx context.Context, query ListQuery) ([]T, error) {
+	clause, err := r.filters.Compile(query.Filter, query.Sort, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	orderBy := r.key
+	if clause.OrderBy != "" {
+		orderBy = clause.OrderBy + ", " + r.key
+	}
+
+	page, pageArgs := r.dialect.Paginate(query.Limit, query.Offset, len(clause.Args)+1)
+
+	q := "SELECT " + strings.Join(r.columns, ", ") + " FROM " + r.table + r.where(clause.Where) + " ORDER BY " + orderBy + page
+	args := append(clause.Args, pageArgs...)
+
+	entities := []T{}
+
+	if err := r.queryer().SelectContext(ctx, &entities, q, args...); err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+// Count counts the entities matching e
+func (r *Repository[T]) Count(ctx context.Context, e filter.Expr) (uint, error) {
+	clause, err := r.filters.Compile(e, nil, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	var count uint
+
+	if err := r.queryer().GetContext(ctx, &count, "SELECT COUNT(*) FROM "+r.table+r.where(clause.Where), clause.Args...); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Update writes the columns of v to the entity with its key, the generated
+// columns are left as they are, returns ErrNotFound when there is none
+func (r *Repository[T]) Update(ctx context.Context, v T) error {
+	return r.exec(ctx, OperationUpdate, v)
+}
+
+// Delete deletes the entity with the key, returns ErrNotFound when there is
+// none
+func (r *Repository[T]) Delete(ctx context.Context, key any) error {
+	return r.exec(ctx, OperationDelete, map[string]any{r.key: key})
+}
+
+// Upsert inserts v or, when it conflicts with an entity on the conflict
+// columns, updates that entity, and returns the entity as written
+func (r *Repository[T]) Upsert(ctx context.Context, v T) (T, error) {
+	return r.write(ctx, OperationUpsert, v)
+}
+
+func (r *Repository[T]) write(ctx context.Context, op Operation, v T) (T, error) {
+	var written T
+
+	q, args, err := r.bind(r.queries[op], v)
+	if err != nil {
+		return written, err
+	}
+
+	if err := r.queryer().GetContext(ctx, &written, q, args...); err != nil {
+		return written, notFound(err)
+	}
+
+	return written, nil
+}
+
+func (r *Repository[T]) exec(ctx context.Context, op Operation, arg any) error {
+	q, args, err := r.bind(r.queries[op], arg)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.queryer().ExecContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository[T]) queryer() queryer {
+	if r.tx != nil {
+		return r.tx
+	}
+
+	return r.db
+}
+
+func (r *Repository[T]) createQuery() string {
+	columns := r.writable()
+	if len(columns) == 0 {
+		return "INSERT INTO " + r.table + " DEFAULT VALUES" + r.dialect.Returning(r.columns...)
+	}
+
+	return "INSERT INTO " + r.table + " (" + strings.Join(columns, ", ") + ") VALUES (" + named(columns) + ")" +
+		r.dialect.Returning(r.columns...)
+}
+
+func (r *Repository[T]) getQuery() string {
+	return "SELECT " + strings.Join(r.columns, ", ") + " FROM " + r.table + r.where(r.key+" = :"+r.key)
+}
+
+func (r *Repository[T]) updateQuery() string {
+	set := []string{}
+
+	for _, c := range r.writable() {
+		if c != r.key {
+			set = append(set, c+" = :"+c)
+		}
+	}
+
+	if len(set) == 0 {
+		set = append(set, r.key+" = :"+r.key)
+	}
+
+	return "UPDATE " + r.table + " SET " + strings.Join(set, ", ") + r.where(r.key+" = :"+r.key)
+}
+
+func (r *Repository[T]) deleteQuery() string {
+	return "DELETE FROM " + r.table + r.where(r.key+" = :"+r.key)
+}
+
+// upsertQuery inserts the writable columns, the conflict columns are among
+// them unless generated, and updates the others
+func (r *Repository[T]) upsertQuery() string {
+	columns := r.writable()
+
+	for _, c := range r.conflict {
+		if !contains(columns, c) {
+			columns = append(columns, c)
+		}
+	}
+
+	update := []string{}
+
+	for _, c := range columns {
+		if !contains(r.conflict, c) {
+			update = append(update, c)
+		}
+	}
+
+	return "INSERT INTO " + r.table + " (" + strings.Join(columns, ", ") + ") VALUES (" + named(columns) + ")" +
+		r.dialect.Upsert(r.conflict, "", update...) + r.dialect.Returning(r.columns...)
+}
+
+// writable the columns written from the fields of the entity
+func (r *Repository[T]) writable() []string {
+	columns := []string{}
+
+	for _, c := range r.columns {
+		if !contains(r.generated, c) {
+			columns = append(columns, c)
+		}
+	}
+
+	return columns
+}
+
+// where the WHERE clause of the condition and the scope of the repository,
+// either may be empty
+func (r *Repository[T]) where(condition string) string {
+	conditions := []string{}
+
+	if condition != "" {
+		conditions = append(conditions, "("+condition+")")
+	}
+
+	for _, s := range r.scope {
+		conditions = append(conditions, "("+s+")")
+	}
+
+	if len(conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// bind binds the named parameters of query to arg, in the order they appear
+// and with the placeholders of the dialect
+func (r *Repository[T]) bind(query string, arg any) (string, []any, error) {
+	q, args, err := sqlx.Named(query, arg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return r.dialect.Rebind(q), args, nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+func named(columns []string) string {
+	params := make([]string, len(columns))
+
+	for i, c := range columns {
+		params[i] = ":" + c
+	}
+
+	return strings.Join(params, ", ")
+}
+
+func contains(columns []string, column string) bool {
+	for _, c := range columns {
+		if c == column {
+			return true
+		}
+	}
+
+	return false
+}
+
+// snake converts the name of a type to snake case, TaxonomyLink becomes
+// taxonomy_link
+func snake(name string) string {
+	b := strings.Builder{}
+
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+
+			r = unicode.ToLower(r)
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,284 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/dialect"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/filter"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/nop"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the SQL statements")
+
+// tagLink an entity whose table defaults to the snake case of its name
+type tagLink struct {
+	ID        int64      `db:"id"`
+	Name      string     `db:"name"`
+	Slug      string     `db:"slug"`
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+// recorder a database recording the statements run on it, every statement
+// succeeds affecting a row
+type recorder struct {
+	*nop.Nop
+	statements []string
+}
+
+func (r *recorder) record(query string, args []any) {
+	r.statements = append(r.statements, strings.Join(strings.Fields(query), " "), fmt.Sprintf("-- %v", args))
+}
+
+func (r *recorder) ExecContext(_ context.Context, query string, args ...any) (sql.Result, error) {
+	r.record(query, args)
+
+	return driver.RowsAffected(1), nil
+}
+
+func (r *recorder) GetContext(_ context.Context, _ any, query string, args ...any) error {
+	r.record(query, args)
+
+	return nil
+}
+
+func (r *recorder) SelectContext(_ context.Context, _ any, query string, args ...any) error {
+	r.record(query, args)
+
+	return nil
+}
+
+// golden compares the statements to the golden file of the name, or rewrites
+// it with -update
+func golden(t *testing.T, name string, statements []string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden.sql")
+	got := strings.Join(statements, "\n") + "\n"
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != string(want) {
+		t.Errorf("statements of %s differ from %s, run go test -update to accept them\ngot:\n%s\nwant:\n%s", name, path, got, want)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	ctx := context.Background()
+	tag := tagLink{ID: 1, Name: "Go", Slug: "go"}
+
+	operations := []struct {
+		name string
+		run  func(r *Repository[tagLink]) error
+	}{
+		{"create", func(r *Repository[tagLink]) error {
+			_, err := r.Create(ctx, tag)
+			return err
+		}},
+		{"get", func(r *Repository[tagLink]) error {
+			_, err := r.Get(ctx, 1)
+			return err
+		}},
+		{"list", func(r *Repository[tagLink]) error {
+			_, err := r.List(ctx, ListQuery{
+				Filter: filter.And(filter.Like("name", "g%"), filter.In("slug", "go", "golang")),
+				Sort:   []filter.Sort{filter.Desc("name")},
+				Limit:  10,
+				Offset: 20,
+			})
+			return err
+		}},
+		{"count", func(r *Repository[tagLink]) error {
+			_, err := r.Count(ctx, filter.Eq("name", "Go"))
+			return err
+		}},
+		{"update", func(r *Repository[tagLink]) error {
+			return r.Update(ctx, tag)
+		}},
+		{"delete", func(r *Repository[tagLink]) error {
+			return r.Delete(ctx, 1)
+		}},
+		{"upsert", func(r *Repository[tagLink]) error {
+			_, err := r.Upsert(ctx, tag)
+			return err
+		}},
+	}
+
+	dialects := []struct {
+		name    string
+		dialect dialect.Dialect
+	}{
+		{"sqlite", dialect.NewSQLite()},
+		{"postgres", dialect.NewPostgres()},
+	}
+
+	for _, d := range dialects {
+		for _, op := range operations {
+			db := &recorder{Nop: nop.NewDB()}
+
+			r := NewRepository[tagLink](
+				RepositoryWithDB(db),
+				RepositoryWithDialect(d.dialect),
+				RepositoryWithConflict("slug"),
+				RepositoryWithScope("deleted_at IS NULL"),
+			)
+
+			if err := op.run(r); err != nil {
+				t.Errorf("%s %s: error = %v", d.name, op.name, err)
+				continue
+			}
+
+			golden(t, d.name+"_"+op.name, db.statements)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sqlx.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "repo.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`
+		CREATE TABLE tag_link (
+			id         INTEGER PRIMARY KEY AUTOINCREMENT,
+			name       TEXT NOT NULL,
+			slug       TEXT NOT NULL UNIQUE,
+			deleted_at TIMESTAMP
+		)`,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	// deletes are soft, the scope hides the deleted entities, creates
+	// normalize the slug
+	r := NewRepository[tagLink](
+		RepositoryWithDB(db),
+		RepositoryWithConflict("slug"),
+		RepositoryWithScope("deleted_at IS NULL"),
+		RepositoryWithQuery(OperationCreate, `INSERT INTO tag_link (name, slug) VALUES (:name, lower(:slug)) RETURNING id, name, slug, deleted_at`),
+		RepositoryWithQuery(OperationDelete, `UPDATE tag_link SET deleted_at = CURRENT_TIMESTAMP WHERE id = :id AND deleted_at IS NULL`),
+	)
+
+	goTag, err := r.Create(ctx, tagLink{Name: "Go", Slug: "GO"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if goTag.ID == 0 || goTag.Slug != "go" {
+		t.Errorf("Create() = %+v, want a generated id and the slug go", goTag)
+	}
+
+	rust, err := r.Create(ctx, tagLink{Name: "Rust", Slug: "rust"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	goTag.Name = "Golang"
+
+	if err := r.Update(ctx, goTag); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := r.Get(ctx, goTag.ID); err != nil || got.Name != "Golang" {
+		t.Errorf("Get() after Update() = %+v %v, want Golang", got, err)
+	}
+
+	// the upsert conflicts on the slug and keeps the id
+	upserted, err := r.Upsert(ctx, tagLink{Name: "Rust lang", Slug: "rust"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if upserted.ID != rust.ID || upserted.Name != "Rust lang" {
+		t.Errorf("Upsert() = %+v, want the name of %d updated", upserted, rust.ID)
+	}
+
+	if err := r.Delete(ctx, goTag.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if deletedAt(t, db, goTag.ID) == nil {
+		t.Errorf("the deleted entity is gone, want it kept with deleted_at set")
+	}
+
+	if _, err := r.Get(ctx, goTag.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of a deleted entity error = %v, want ErrNotFound", err)
+	}
+
+	if err := r.Delete(ctx, goTag.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() of a deleted entity error = %v, want ErrNotFound", err)
+	}
+
+	if err := r.Update(ctx, goTag); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update() of a deleted entity error = %v, want ErrNotFound", err)
+	}
+
+	listed, err := r.List(ctx, ListQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(listed) != 1 || listed[0].ID != rust.ID {
+		t.Errorf("List() = %+v, want only %d", listed, rust.ID)
+	}
+
+	tests := []struct {
+		name string
+		expr filter.Expr
+		want uint
+	}{
+		{"all", filter.Expr{}, 1},
+		{"deleted", filter.Eq("slug", "go"), 0},
+		{"filtered", filter.Like("name", "Rust%"), 1},
+	}
+
+	for _, tt := range tests {
+		if got, err := r.Count(ctx, tt.expr); err != nil || got != tt.want {
+			t.Errorf("%s: Count() = %d %v, want %d", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+// deletedAt returns the deletion time of the entity, whatever the scope
+func deletedAt(t *testing.T, db *sqlx.DB, id int64) *time.Time {
+	t.Helper()
+
+	var at *time.Time
+
+	if err := db.Get(&at, `SELECT deleted_at FROM tag_link WHERE id = ?`, id); err != nil {
+		t.Fatal(err)
+	}
+
+	return at
+}
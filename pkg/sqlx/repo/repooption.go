@@ -0,0 +1,79 @@
+package repo
+
+import (
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/dialect"
+	"github.com/olireadcopper/sqlxprototype/pkg/sqlx/filter"
+)
+
+func RepositoryWithDB(db sqlx.DB) RepositoryOption {
+	return func(c *config) {
+		c.db = db
+	}
+}
+
+// RepositoryWithDialect sets the dialect the queries are built for, defaults
+// to SQLite
+func RepositoryWithDialect(d dialect.Dialect) RepositoryOption {
+	return func(c *config) {
+		c.dialect = d
+	}
+}
+
+// RepositoryWithTable sets the table of the entities, defaults to the name of
+// the entity type in snake case
+func RepositoryWithTable(table string) RepositoryOption {
+	return func(c *config) {
+		c.table = table
+	}
+}
+
+// RepositoryWithKey sets the column identifying an entity, defaults to id
+func RepositoryWithKey(column string) RepositoryOption {
+	return func(c *config) {
+		c.key = column
+	}
+}
+
+// RepositoryWithGenerated sets the columns the database fills, such as the
+// key and timestamps, they are returned by writes but never written,
+// defaults to the key
+func RepositoryWithGenerated(columns ...string) RepositoryOption {
+	return func(c *config) {
+		c.generated = columns
+	}
+}
+
+// RepositoryWithConflict sets the columns an upsert conflicts on, defaults to
+// the key
+func RepositoryWithConflict(columns ...string) RepositoryOption {
+	return func(c *config) {
+		c.conflict = columns
+	}
+}
+
+// RepositoryWithScope restricts the entities got, listed, counted, updated
+// and deleted to those matching the SQL conditions, deleted_at IS NULL for
+// example, the queries set with RepositoryWithQuery are left as they are
+func RepositoryWithScope(conditions ...string) RepositoryOption {
+	return func(c *config) {
+		c.scope = append(c.scope, conditions...)
+	}
+}
+
+// RepositoryWithQuery replaces the SQL of the operation with query, its named
+// parameters are bound to the entity, or to the key under its column name
+// for gets and deletes
+func RepositoryWithQuery(op Operation, query string) RepositoryOption {
+	return func(c *config) {
+		c.queries[op] = query
+	}
+}
+
+// RepositoryWithCompiler sets the compiler of the filters of lists and
+// counts, defaults to one allowing every column of the entity
+func RepositoryWithCompiler(compiler *filter.Compiler) RepositoryOption {
+	return func(c *config) {
+		c.filters = compiler
+	}
+}
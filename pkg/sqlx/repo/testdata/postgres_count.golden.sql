@@ -0,0 +1,2 @@
+SELECT COUNT(*) FROM tag_link WHERE (name = $1) AND (deleted_at IS NULL)
+-- [Go]
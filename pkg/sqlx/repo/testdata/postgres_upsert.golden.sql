@@ -0,0 +1,2 @@
+INSERT INTO tag_link (name, slug, deleted_at) VALUES ($1, $2, $3) ON CONFLICT (slug) DO UPDATE SET name = excluded.name, deleted_at = excluded.deleted_at RETURNING id, name, slug, deleted_at
+-- [Go go <nil>]
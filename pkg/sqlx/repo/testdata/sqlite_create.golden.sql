@@ -0,0 +1,2 @@
+INSERT INTO tag_link (name, slug, deleted_at) VALUES ($1, $2, $3) RETURNING id, name, slug, deleted_at
+-- [Go go <nil>]
@@ -0,0 +1,2 @@
+DELETE FROM tag_link WHERE (id = $1) AND (deleted_at IS NULL)
+-- [1]
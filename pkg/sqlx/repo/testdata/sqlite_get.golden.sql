@@ -0,0 +1,2 @@
+SELECT id, name, slug, deleted_at FROM tag_link WHERE (id = $1) AND (deleted_at IS NULL)
+-- [1]
@@ -0,0 +1,2 @@
+SELECT id, name, slug, deleted_at FROM tag_link WHERE ((name LIKE $1 ESCAPE '\' AND slug IN ($2, $3))) AND (deleted_at IS NULL) ORDER BY name DESC, id LIMIT $4 OFFSET $5
+-- [g% go golang 10 20]
@@ -0,0 +1,2 @@
+UPDATE tag_link SET name = $1, slug = $2, deleted_at = $3 WHERE (id = $4) AND (deleted_at IS NULL)
+-- [Go go <nil> 1]
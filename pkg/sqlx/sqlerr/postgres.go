@@ -0,0 +1,46 @@
+package sqlerr
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+func init() {
+	Register(classifyPostgres)
+}
+
+// classifyPostgres classifies lib/pq errors by their SQLSTATE code first,
+// falling back to the class of the code
+func classifyPostgres(err error) (Category, bool) {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr == nil {
+		return CategoryNone, false
+	}
+
+	switch pqErr.Code {
+	case "23505":
+		return CategoryUniqueViolation, true
+	case "23503":
+		return CategoryForeignKeyViolation, true
+	case "40001", "40P01", "55P03":
+		return CategoryBusy, true
+	case "25006", "42501":
+		return CategoryReadOnly, true
+	case "57014":
+		return CategoryCanceled, true
+	case "57P01", "57P02", "57P03":
+		return CategoryBadConnection, true
+	case "XX001", "XX002":
+		return CategoryCorrupt, true
+	}
+
+	switch pqErr.Code.Class() {
+	case "23":
+		return CategoryConstraintViolation, true
+	case "08":
+		return CategoryBadConnection, true
+	}
+
+	return CategoryUnknown, true
+}
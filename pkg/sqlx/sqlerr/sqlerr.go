@@ -0,0 +1,147 @@
+// Package sqlerr classifies the errors returned by database drivers into a
+// stable set of categories, allowing callers, logs and metrics to reason
+// about them without depending on a specific driver
+package sqlerr
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sync"
+)
+
+// Category the driver independent classification of an SQL error
+type Category string
+
+const (
+	CategoryNone                Category = ""
+	CategoryUnknown             Category = "unknown"
+	CategoryNotFound            Category = "not_found"
+	CategoryUniqueViolation     Category = "unique_violation"
+	CategoryForeignKeyViolation Category = "foreign_key_violation"
+	CategoryConstraintViolation Category = "constraint_violation"
+	CategoryBusy                Category = "busy"
+	CategoryReadOnly            Category = "read_only"
+	CategoryCorrupt             Category = "corrupt"
+	CategoryTimeout             Category = "timeout"
+	CategoryCanceled            Category = "canceled"
+	CategoryBadConnection       Category = "bad_connection"
+)
+
+// Classifier maps a driver specific error to a category, reporting false
+// when it does not recognise the error
+type Classifier func(err error) (Category, bool)
+
+var (
+	mu          sync.RWMutex
+	classifiers []Classifier
+)
+
+// Register adds a driver specific classifier, classifiers are consulted in
+// the order they were registered before the generic classification
+func Register(c Classifier) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	classifiers = append(classifiers, c)
+}
+
+// Classify returns the category of err, CategoryNone when err is nil and
+// CategoryUnknown when it could not be classified
+func Classify(err error) Category {
+	if err == nil {
+		return CategoryNone
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, c := range classifiers {
+		if category, ok := c(err); ok {
+			return category
+		}
+	}
+
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return CategoryNotFound
+	case errors.Is(err, context.Canceled):
+		return CategoryCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return CategoryTimeout
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone):
+		return CategoryBadConnection
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return CategoryTimeout
+	}
+
+	return CategoryUnknown
+}
+
+// Code returns the category of err as a string suitable for log fields and
+// metric labels, an empty string when err is nil
+func Code(err error) string {
+	return string(Classify(err))
+}
+
+// IsNotFound reports whether err is the result of no rows being found
+func IsNotFound(err error) bool {
+	return Classify(err) == CategoryNotFound
+}
+
+// IsUniqueViolation reports whether err is a unique or primary key violation
+func IsUniqueViolation(err error) bool {
+	return Classify(err) == CategoryUniqueViolation
+}
+
+// IsForeignKeyViolation reports whether err is a foreign key violation
+func IsForeignKeyViolation(err error) bool {
+	return Classify(err) == CategoryForeignKeyViolation
+}
+
+// IsConstraintViolation reports whether err is a violation of any constraint,
+// including unique and foreign key constraints
+func IsConstraintViolation(err error) bool {
+	switch Classify(err) {
+	case CategoryUniqueViolation, CategoryForeignKeyViolation, CategoryConstraintViolation:
+		return true
+	}
+
+	return false
+}
+
+// IsBusy reports whether err is the result of the database or a table being
+// locked by another connection
+func IsBusy(err error) bool {
+	return Classify(err) == CategoryBusy
+}
+
+// IsReadOnly reports whether err is the result of writing to a read-only
+// database
+func IsReadOnly(err error) bool {
+	return Classify(err) == CategoryReadOnly
+}
+
+// IsCorrupt reports whether err is the result of a corrupt database
+func IsCorrupt(err error) bool {
+	return Classify(err) == CategoryCorrupt
+}
+
+// IsTimeout reports whether err is the result of a deadline being exceeded
+func IsTimeout(err error) bool {
+	return Classify(err) == CategoryTimeout
+}
+
+// IsCanceled reports whether err is the result of the call being canceled
+func IsCanceled(err error) bool {
+	return Classify(err) == CategoryCanceled
+}
+
+// IsBadConnection reports whether err is the result of an unusable connection
+func IsBadConnection(err error) bool {
+	return Classify(err) == CategoryBadConnection
+}
@@ -0,0 +1,106 @@
+package sqlerr
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+// timeoutError a network error reporting a timeout
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Category
+	}{
+		{"nil", nil, CategoryNone},
+		{"unwrapped", errors.New("boom"), CategoryUnknown},
+		{"no rows", sql.ErrNoRows, CategoryNotFound},
+		{"wrapped no rows", fmt.Errorf("read: %w", sql.ErrNoRows), CategoryNotFound},
+		{"canceled", context.Canceled, CategoryCanceled},
+		{"deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), CategoryTimeout},
+		{"bad conn", driver.ErrBadConn, CategoryBadConnection},
+		{"conn done", sql.ErrConnDone, CategoryBadConnection},
+		{"net timeout", timeoutError{}, CategoryTimeout},
+	}
+
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyPostgres(t *testing.T) {
+	tests := []struct {
+		code pq.ErrorCode
+		want Category
+	}{
+		{"23505", CategoryUniqueViolation},
+		{"23503", CategoryForeignKeyViolation},
+		{"23502", CategoryConstraintViolation},
+		{"23514", CategoryConstraintViolation},
+		{"40001", CategoryBusy},
+		{"40P01", CategoryBusy},
+		{"55P03", CategoryBusy},
+		{"25006", CategoryReadOnly},
+		{"42501", CategoryReadOnly},
+		{"57014", CategoryCanceled},
+		{"57P01", CategoryBadConnection},
+		{"08006", CategoryBadConnection},
+		{"XX001", CategoryCorrupt},
+		{"42P01", CategoryUnknown},
+	}
+
+	for _, tt := range tests {
+		err := fmt.Errorf("exec: %w", &pq.Error{Code: tt.code})
+
+		if got := Classify(err); got != tt.want {
+			t.Errorf("Classify(%s) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestCode(t *testing.T) {
+	var nilErr *pq.Error
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"unwrapped", errors.New("boom"), "unknown"},
+		{"typed nil", nilErr, "unknown"},
+		{"unique", &pq.Error{Code: "23505"}, "unique_violation"},
+	}
+
+	for _, tt := range tests {
+		if got := Code(tt.err); got != tt.want {
+			t.Errorf("Code(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsConstraintViolation(t *testing.T) {
+	for _, code := range []pq.ErrorCode{"23505", "23503", "23502"} {
+		if !IsConstraintViolation(&pq.Error{Code: code}) {
+			t.Errorf("IsConstraintViolation(%s) = false, want true", code)
+		}
+	}
+
+	if IsConstraintViolation(context.Canceled) {
+		t.Error("IsConstraintViolation(context.Canceled) = true, want false")
+	}
+}
@@ -0,0 +1,46 @@
+//go:build cgo
+
+package sqlerr
+
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	Register(classifySQLite)
+}
+
+// classifySQLite classifies go-sqlite3 errors by their extended code first,
+// falling back to the primary code
+func classifySQLite(err error) (Category, bool) {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return CategoryNone, false
+	}
+
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey, sqlite3.ErrConstraintRowID:
+		return CategoryUniqueViolation, true
+	case sqlite3.ErrConstraintForeignKey:
+		return CategoryForeignKeyViolation, true
+	}
+
+	switch sqliteErr.Code {
+	case sqlite3.ErrConstraint:
+		return CategoryConstraintViolation, true
+	case sqlite3.ErrBusy, sqlite3.ErrLocked:
+		return CategoryBusy, true
+	case sqlite3.ErrReadonly, sqlite3.ErrPerm:
+		return CategoryReadOnly, true
+	case sqlite3.ErrCorrupt, sqlite3.ErrNotADB:
+		return CategoryCorrupt, true
+	case sqlite3.ErrInterrupt:
+		return CategoryCanceled, true
+	case sqlite3.ErrCantOpen:
+		return CategoryBadConnection, true
+	}
+
+	return CategoryUnknown, true
+}
@@ -0,0 +1,42 @@
+//go:build cgo
+
+package sqlerr
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func TestClassifySQLite(t *testing.T) {
+	tests := []struct {
+		code     sqlite3.ErrNo
+		extended sqlite3.ErrNoExtended
+		want     Category
+	}{
+		{sqlite3.ErrConstraint, sqlite3.ErrConstraintUnique, CategoryUniqueViolation},
+		{sqlite3.ErrConstraint, sqlite3.ErrConstraintPrimaryKey, CategoryUniqueViolation},
+		{sqlite3.ErrConstraint, sqlite3.ErrConstraintRowID, CategoryUniqueViolation},
+		{sqlite3.ErrConstraint, sqlite3.ErrConstraintForeignKey, CategoryForeignKeyViolation},
+		{sqlite3.ErrConstraint, sqlite3.ErrConstraintNotNull, CategoryConstraintViolation},
+		{sqlite3.ErrConstraint, sqlite3.ErrConstraintCheck, CategoryConstraintViolation},
+		{sqlite3.ErrBusy, 0, CategoryBusy},
+		{sqlite3.ErrLocked, 0, CategoryBusy},
+		{sqlite3.ErrReadonly, 0, CategoryReadOnly},
+		{sqlite3.ErrPerm, 0, CategoryReadOnly},
+		{sqlite3.ErrCorrupt, 0, CategoryCorrupt},
+		{sqlite3.ErrNotADB, 0, CategoryCorrupt},
+		{sqlite3.ErrInterrupt, 0, CategoryCanceled},
+		{sqlite3.ErrCantOpen, 0, CategoryBadConnection},
+		{sqlite3.ErrError, 0, CategoryUnknown},
+	}
+
+	for _, tt := range tests {
+		err := fmt.Errorf("exec: %w", sqlite3.Error{Code: tt.code, ExtendedCode: tt.extended})
+
+		if got := Classify(err); got != tt.want {
+			t.Errorf("Classify(%v, %v) = %q, want %q", tt.code, tt.extended, got, tt.want)
+		}
+	}
+}
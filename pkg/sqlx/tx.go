@@ -0,0 +1,45 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Tx the statements of a transaction, implemented by *sqlx.Tx and by the
+// transactions begun by decorators, see BeginTransaction
+type Tx interface {
+	BindNamed(query string, arg interface{}) (string, []interface{}, error)
+	Commit() error
+	DriverName() string
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+	QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row
+	QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error)
+	Rebind(query string) string
+	Rollback() error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// TxBeginner implemented by the decorators of a DB, the transactions they
+// begin are decorated like the DB so their statements are seen too
+type TxBeginner interface {
+	BeginTransaction(ctx context.Context, opts *sql.TxOptions) (Tx, error)
+}
+
+// BeginTransaction begins a transaction of db, decorated by db when it
+// implements TxBeginner, the *sqlx.Tx of BeginTxx otherwise
+func BeginTransaction(ctx context.Context, db DB, opts *sql.TxOptions) (Tx, error) {
+	if b, ok := db.(TxBeginner); ok {
+		return b.BeginTransaction(ctx, opts)
+	}
+
+	tx, err := db.BeginTxx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
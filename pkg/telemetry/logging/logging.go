@@ -2,7 +2,14 @@ package logging
 
 const (
 	FieldComponent = "component"
-	FieldMethod    = "method"
+	FieldCount     = "count"
+	FieldCursor    = "cursor"
+	FieldDuration  = "duration"
 	FieldErrorCode = "error_code"
+	FieldID        = "id"
+	FieldLimit     = "limit"
+	FieldMethod    = "method"
+	FieldOffset    = "offset"
+	FieldTotal     = "total"
 	FieldTrace     = "trace"
 )
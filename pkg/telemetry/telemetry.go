@@ -1,7 +1,22 @@
 package telemetry
 
+import "context"
+
 type telemetryContextKey byte
 
 const (
-	ContextKeyTrace = iota
+	ContextKeyTrace telemetryContextKey = iota
 )
+
+// ContextWithTrace returns a copy of ctx carrying the trace ID of the request
+// it belongs to
+func ContextWithTrace(ctx context.Context, trace string) context.Context {
+	return context.WithValue(ctx, ContextKeyTrace, trace)
+}
+
+// TraceFromContext returns the trace ID carried by ctx, if any
+func TraceFromContext(ctx context.Context) (string, bool) {
+	trace, ok := ctx.Value(ContextKeyTrace).(string)
+
+	return trace, ok && trace != ""
+}